@@ -0,0 +1,94 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+)
+
+// configFlags registers the flags that override configuration options with
+// `flags`. The returned function gives the values of the flags that were set
+// keyed by the names of the options they override once the flags are parsed.
+func configFlags(flags *flag.FlagSet) func() map[string]string {
+	options := map[string]string{
+		"log-level":  "log.level",
+		"port":       "server.port",
+		"output-dir": "makemkv.output_directory",
+	}
+
+	flags.String("log-level", "", "minimum level of logged messages (debug, info, warn, or error)")
+	flags.String("port", "", "port the HTTP server listens on")
+	flags.String("output-dir", "", "directory the copied titles are written to")
+
+	return func() map[string]string {
+		overrides := make(map[string]string)
+		flags.Visit(func(f *flag.Flag) {
+			if option, ok := options[f.Name]; ok {
+				overrides[option] = f.Value.String()
+			}
+		})
+		return overrides
+	}
+}
+
+// runConfig runs the config command. The only action is print which prints
+// the effective value of each option and where it came from.
+func runConfig(args []string) {
+	if len(args) < 1 || args[0] != "print" {
+		printUsage()
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet("config print", flag.ExitOnError)
+	overrides := configFlags(flags)
+	flags.Parse(args[1:])
+
+	if flags.NArg() != 1 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	loadConfig(flags.Arg(0), overrides())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OPTION\tVALUE\tSOURCE")
+	for _, s := range cfg.Settings() {
+		source := string(s.Source)
+		if s.Source == cfg.SourceEnv {
+			source = fmt.Sprintf("env (%s)", cfg.EnvName(s.Name))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Value, source)
+	}
+	w.Flush()
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/db"
+)
+
+// runHeartbeat updates the last seen time of the drive with identifier `id`
+// and renews the lease on it held by `holder` for `leaseTimeout` every
+// `interval` until `ctx` is cancelled so that other services can tell that
+// this service is still alive. If another service takes the lease, `lost` is
+// called and the heartbeat stops since the drive is no longer this service's
+// to use.
+func runHeartbeat(ctx context.Context, repo db.DriveRepository, id int, holder string, interval, leaseTimeout time.Duration, lost func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := acquireDriveLease(ctx, repo, id, holder, leaseTimeout); errors.Is(err, db.ErrDriveLeaseHeld) {
+				slog.Error("Drive lease was taken by another service. Stopping use of the drive.", "error", err)
+				lost()
+				return
+			} else if err != nil {
+				slog.Warn("Failed to renew drive lease.", "error", err)
+			}
+			if err := repo.HeartbeatOpticalDrive(ctx, id, now); err != nil {
+				slog.Warn("Failed to update drive heartbeat.", "error", err)
+			}
+		}
+	}
+}
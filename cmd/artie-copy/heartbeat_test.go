@@ -0,0 +1,80 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+func TestRunHeartbeatLeaseLost(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+
+	od := models.OpticalDrive{SerialNumber: "4-8-15-16-23-42", State: models.DriveStateIdle}
+	if err := repo.UpsertOpticalDrive(ctx, &od); err != nil {
+		t.Fatal("UpsertOpticalDrive returned an error:", err)
+	}
+
+	now := time.Now()
+	if _, err := repo.AcquireDriveLease(ctx, od.Id, "other", now, now.Add(time.Hour)); err != nil {
+		t.Fatal("AcquireDriveLease returned an error:", err)
+	}
+
+	lost := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		runHeartbeat(ctx, repo, od.Id, "this", time.Millisecond, time.Minute, func() { close(lost) })
+		close(done)
+	}()
+
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lost lease not reported")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Heartbeat didn't stop after the lease was lost")
+	}
+
+	drives, err := repo.ListOpticalDrives(ctx)
+	if err != nil {
+		t.Fatal("ListOpticalDrives returned an error:", err)
+	}
+	if len(drives) != 1 || !drives[0].LastSeen.IsZero() {
+		t.Errorf("Drive = %+v, expected its last seen time to be left to the lease holder", drives)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/db"
+)
+
+// leaseHolder returns the name identifying this service as the holder of a
+// drive lease on host `hostname`.
+func leaseHolder(hostname string) string {
+	return fmt.Sprintf("%s (pid %d)", hostname, os.Getpid())
+}
+
+// acquireExistingDriveLease takes the lease on the drive with serial number
+// `serial` for `holder` if the drive is already in the database. This is done
+// before updating the drive so that a service that isn't allowed to use the
+// drive doesn't overwrite the information of the one that is.
+func acquireExistingDriveLease(ctx context.Context, repo db.DriveRepository, serial, holder string, timeout time.Duration) error {
+	drives, err := repo.ListOpticalDrives(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, od := range drives {
+		if od.SerialNumber == serial {
+			return acquireDriveLease(ctx, repo, od.Id, holder, timeout)
+		}
+	}
+
+	return nil
+}
+
+// acquireDriveLease takes or renews the lease on the drive with identifier
+// `id` for `holder` for `timeout`.
+func acquireDriveLease(ctx context.Context, repo db.DriveRepository, id int, holder string, timeout time.Duration) error {
+	now := time.Now()
+	_, err := repo.AcquireDriveLease(ctx, id, holder, now, now.Add(timeout))
+	return err
+}
@@ -30,82 +30,277 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/kfisher/artie-copy-service/internal/blk"
 	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/command"
 	"github.com/kfisher/artie-copy-service/internal/db"
 	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/preflight"
+	"github.com/kfisher/artie-copy-service/internal/retention"
 	"github.com/kfisher/artie-copy-service/internal/service"
 	"github.com/kfisher/artie-copy-service/internal/store"
+	"github.com/kfisher/artie-copy-service/internal/transcript"
+	"github.com/kfisher/artie-copy-service/internal/worker"
 )
 
 func main() {
-	// TODO: Add a command line flag to set the log level.
-	slog.SetLogLoggerLevel(slog.LevelInfo)
-
 	if len(os.Args) < 2 {
-		fmt.Println("usage: artie-copy CONFIG")
+		printUsage()
+		return
+	}
+
+	if os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	if os.Args[1] == "purge" {
+		runPurge(os.Args[2:])
+		return
+	}
+
+	if os.Args[1] == "config" {
+		runConfig(os.Args[2:])
 		return
 	}
 
-	cfgPath := os.Args[1]
-	slog.Info("Loading config", "path", cfgPath)
-	if err := cfg.LoadConfig(cfgPath); err != nil {
-		fmt.Printf("Failed to load configuration at %s\n", cfgPath)
+	flags := flag.NewFlagSet("artie-copy", flag.ExitOnError)
+	overrides := configFlags(flags)
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() != 1 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	configPath := flags.Arg(0)
+	configOverrides := overrides()
+	loadConfig(configPath, configOverrides)
+
+	slog.Info("Running preflight checks.")
+	if problems := preflight.Run(context.Background(), cfg.Current().MakeMkv, worker.NewRunner(cfg.Current().MakeMkv)); len(problems) > 0 {
+		fmt.Printf("Preflight checks failed.\n")
+		for _, problem := range problems {
+			fmt.Printf("error: %s\n", problem)
+		}
+		os.Exit(1)
+	}
+
+	// Staging on another filesystem works, but every copy is copied a second
+	// time when it's moved to the output directory.
+	conf := cfg.Current().MakeMkv
+	if same, err := preflight.SameFilesystem(conf.StagingDir, conf.OutDir); err == nil && !same {
+		slog.Warn("Staging directory is not on the output directory's filesystem, copies will be copied again when moved to it.",
+			"staging", conf.StagingDir, "output", conf.OutDir)
+	}
+
+	slog.Info("Opening database.")
+	openCtx := context.Background()
+	if cfg.Db.StartupTimeout > 0 {
+		var cancel context.CancelFunc
+		openCtx, cancel = context.WithTimeout(openCtx, time.Duration(cfg.Db.StartupTimeout)*time.Second)
+		defer cancel()
+	}
+	repo, err := db.OpenWithRetry(openCtx, cfg.Db.ConnStr)
+	if err != nil {
+		fmt.Printf("Failed to open the database.\n")
 		fmt.Printf("error: %s\n", err)
 		os.Exit(1)
 	}
+	defer repo.Close()
 
-	slog.Info("Initializing database pool.")
-	if err := db.InitPool(); err != nil {
-		fmt.Printf("Failed to initialize the database pool.\n")
+	slog.Info("Applying database migrations.")
+	if count, err := repo.MigrateUp(context.Background()); err != nil {
+		fmt.Printf("Failed to apply database migrations.\n")
 		fmt.Printf("error: %s\n", err)
 		os.Exit(1)
+	} else if count > 0 {
+		slog.Info("Applied database migrations.", "count", count)
 	}
-	defer db.Close()
 
-	slog.Info("Loading device information.")
-	device, err := blk.GetBlockDevice(cfg.Device.Serial)
+	hostname, err := os.Hostname()
 	if err != nil {
-		fmt.Printf("Failed to get device information.\n")
-		fmt.Printf("error: %s", err)
+		fmt.Printf("Failed to get hostname.\n")
+		fmt.Printf("error: %s\n", err)
 		os.Exit(1)
 	}
 
-	id, err := db.InitOpticalDriveInfo(context.Background(), cfg.Device.Serial)
+	// The lease prevents two services that were configured with the same
+	// drive from both using it.
+	holder := leaseHolder(hostname)
+
+	var drives []models.OpticalDrive
+	for _, dc := range cfg.Devices {
+		drives = append(drives, setupDrive(repo, dc, hostname, holder))
+	}
+
+	if count, err := transcript.Purge(time.Now()); err != nil {
+		slog.Warn("Failed to purge transcripts.", "error", err)
+	} else if count > 0 {
+		slog.Info("Purged old transcripts.", "count", count)
+	}
+
+	// Copy operation writes are spooled while the database is unreachable so
+	// that an outage doesn't interrupt a copy. The spool stays in the state
+	// directory used at startup even if the configuration is reloaded.
+	spoolPath := filepath.Join(cfg.Current().MakeMkv.StateDir, "db-spool.json")
+	if err := moveSpool(filepath.Join(cfg.Current().MakeMkv.OutDir, "db-spool.json"), spoolPath); err != nil {
+		fmt.Printf("Failed to move the database spool to the state directory.\n")
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
+	}
+	spool, err := db.NewSpoolRepository(repo, spoolPath)
 	if err != nil {
-		fmt.Printf("Failed to update drive info.\n")
+		fmt.Printf("Failed to load the database spool.\n")
 		fmt.Printf("error: %s\n", err)
 		os.Exit(1)
 	}
 
-	hostname, err := os.Hostname()
+	workers := make([]*worker.Worker, len(drives))
+	for i, od := range drives {
+		workers[i] = worker.New(spool, od.SerialNumber)
+	}
+
+	// A copy that was started while the database was unreachable may still
+	// be running when it's replayed so its worker is told the new identifier.
+	spool.OnReplay = func(provisional, id int) {
+		if err := transcript.Rename(provisional, id); err != nil {
+			slog.Warn("Failed to rename transcript.", "from", provisional, "to", id, "error", err)
+		}
+		for _, w := range workers {
+			w.Replayed(provisional, id)
+		}
+	}
+
+	// The license is shared by all drives so it only needs to be checked once.
+	slog.Info("Checking MakeMKV version and license.")
+	if err := workers[0].ProbeMakeMkv(context.Background()); err != nil {
+		slog.Warn("Failed to get MakeMKV version and license information.", "error", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go handleReloads(ctx, configPath, configOverrides)
+	go spool.Run(ctx, time.Duration(cfg.Db.HealthCheckInterval)*time.Second)
+
+	for i, od := range drives {
+		dc := cfg.Devices[i]
+		heartbeat := time.Duration(dc.HeartbeatInterval) * time.Second
+		leaseTimeout := time.Duration(dc.LeaseTimeout) * time.Second
+
+		go runHeartbeat(ctx, repo, od.Id, holder, heartbeat, leaseTimeout, workers[i].LeaseLost)
+		go retention.Run(ctx, repo, od.Id)
+
+		// Commands aren't spooled so the processor uses the database directly.
+		go command.New(repo, workers[i], od.Id).Run(ctx, time.Duration(cfg.Db.CommandPollInterval)*time.Second)
+
+		slog.Info("Starting drive.", "serial", od.SerialNumber, "device", od.DeviceName)
+	}
+
+	slog.Info("Starting service.", "drives", len(drives), "address", cfg.Server.Address, "port", cfg.Server.Port)
+
+	err = service.Run(ctx, spool, workers)
+
+	// Mark the drives offline even if the server failed so that other
+	// services don't think they're still available. A new context is used
+	// since `ctx` has been cancelled on a clean shutdown. Drives whose lease
+	// was taken belong to another service now so they're left alone.
+	slog.Info("Shutting down.")
+	for i, od := range drives {
+		if workers[i].Lost() {
+			continue
+		}
+		if err := repo.SetOpticalDriveOffline(context.Background(), od.Id); err != nil {
+			slog.Warn("Failed to mark drive offline.", "serial", od.SerialNumber, "error", err)
+		}
+		if err := repo.ReleaseDriveLease(context.Background(), od.Id, holder); err != nil {
+			slog.Warn("Failed to release drive lease.", "serial", od.SerialNumber, "error", err)
+		}
+	}
+
 	if err != nil {
-		fmt.Printf("Failed to get hostname.\n")
+		fmt.Printf("Failed to run server\n")
+		fmt.Printf("error: %s\n", err)
+		repo.Close()
+		os.Exit(1)
+	}
+}
+
+// setupDrive loads the device information of the drive configured by `dc`,
+// acquires its lease for `holder`, and records it in the database and store.
+// It exits if any of those fail.
+func setupDrive(repo db.Database, dc cfg.DeviceConfig, hostname, holder string) models.OpticalDrive {
+	slog.Info("Loading device information.", "serial", dc.Serial)
+	device, err := blk.GetBlockDevice(dc.Serial)
+	if err != nil {
+		fmt.Printf("Failed to get device information for %s.\n", dc.Serial)
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
+	}
+
+	leaseTimeout := time.Duration(dc.LeaseTimeout) * time.Second
+	if err := acquireExistingDriveLease(context.Background(), repo, dc.Serial, holder, leaseTimeout); err != nil {
+		fmt.Printf("Failed to acquire the drive lease for %s.\n", dc.Serial)
 		fmt.Printf("error: %s\n", err)
 		os.Exit(1)
 	}
 
 	od := models.OpticalDrive{
-		Id:           id,
-		Name:         cfg.Device.Name,
+		Name:         dc.Name,
 		Host:         hostname,
 		DeviceName:   device.Name,
-		SerialNumber: cfg.Device.Serial,
+		SerialNumber: dc.Serial,
 		State:        models.DriveStateIdle,
 		DiscLabel:    device.Label,
+		LastSeen:     time.Now(),
+		Online:       true,
+	}
+
+	if err := repo.UpsertOpticalDrive(context.Background(), &od); err != nil {
+		fmt.Printf("Failed to update drive info for %s.\n", dc.Serial)
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
+	}
+
+	// The lease is taken again in case the drive was just added.
+	if err := acquireDriveLease(context.Background(), repo, od.Id, holder, leaseTimeout); err != nil {
+		fmt.Printf("Failed to acquire the drive lease for %s.\n", dc.Serial)
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
 	}
 
 	store.Set(od)
 
-	slog.Info("Starting service.", "serial", cfg.Device.Serial, "device", device.Name, "address", cfg.Server.Address, "port", cfg.Server.Port)
+	return od
+}
 
-	if err = service.Run(); err != nil {
-		fmt.Printf("Failed to run server\n")
+func printUsage() {
+	fmt.Println("usage: artie-copy [--log-level LEVEL] [--port PORT] [--output-dir DIR] CONFIG")
+	fmt.Println("       artie-copy config print [--log-level LEVEL] [--port PORT] [--output-dir DIR] CONFIG")
+	fmt.Println("       artie-copy migrate up|down|status CONFIG")
+	fmt.Println("       artie-copy purge [--dry-run] CONFIG")
+}
+
+// loadConfig loads the configuration file at `path` with the options in
+// `overrides` taking precedence and sets the log level. It exits if loading
+// fails.
+func loadConfig(path string, overrides map[string]string) {
+	slog.Info("Loading config", "path", path)
+	if err := cfg.Load(path, overrides); err != nil {
+		fmt.Printf("Failed to load configuration at %s\n", path)
 		fmt.Printf("error: %s\n", err)
 		os.Exit(1)
 	}
+
+	slog.SetLogLoggerLevel(cfg.Current().Log.SlogLevel())
 }
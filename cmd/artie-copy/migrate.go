@@ -0,0 +1,104 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/db"
+)
+
+// runMigrate runs the migrate command which applies, reverts, or reports the
+// status of the database migrations.
+func runMigrate(args []string) {
+	if len(args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	action := args[0]
+	if action != "up" && action != "down" && action != "status" {
+		fmt.Printf("Unknown migrate action: %s\n", action)
+		printUsage()
+		os.Exit(1)
+	}
+
+	loadConfig(args[1], nil)
+
+	ctx := context.Background()
+
+	repo, err := db.Open(ctx, cfg.Db.ConnStr)
+	if err != nil {
+		fmt.Printf("Failed to open the database.\n")
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
+	}
+	defer repo.Close()
+
+	switch action {
+	case "up":
+		count, err := repo.MigrateUp(ctx)
+		if err != nil {
+			fmt.Printf("Failed to apply migrations.\n")
+			fmt.Printf("error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Applied %d migration(s).\n", count)
+	case "down":
+		reverted, err := repo.MigrateDown(ctx)
+		if err != nil {
+			fmt.Printf("Failed to revert migration.\n")
+			fmt.Printf("error: %s\n", err)
+			os.Exit(1)
+		}
+		if reverted {
+			fmt.Println("Reverted 1 migration.")
+		} else {
+			fmt.Println("No migrations to revert.")
+		}
+	case "status":
+		status, err := repo.GetMigrationStatus(ctx)
+		if err != nil {
+			fmt.Printf("Failed to get migration status.\n")
+			fmt.Printf("error: %s\n", err)
+			os.Exit(1)
+		}
+		for _, s := range status {
+			if s.Applied {
+				fmt.Printf("%04d_%-30s applied %s\n", s.Version, s.Name, s.AppliedAt.Format(time.RFC3339))
+			} else {
+				fmt.Printf("%04d_%-30s pending\n", s.Version, s.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/retention"
+	"github.com/kfisher/artie-copy-service/internal/transcript"
+)
+
+// runPurge runs the purge command which removes the copy operations of the
+// configured drives that are past their retention period along with their
+// transcripts, and the transcripts that are past their own retention period.
+// With --dry-run, what would be removed is only reported.
+func runPurge(args []string) {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would be removed without removing it")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	loadConfig(flags.Arg(0), nil)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	repo, err := db.Open(ctx, cfg.Db.ConnStr)
+	if err != nil {
+		fmt.Printf("Failed to open the database.\n")
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
+	}
+	defer repo.Close()
+
+	drives, err := repo.ListOpticalDrives(ctx)
+	if err != nil {
+		fmt.Printf("Failed to list drives.\n")
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
+	}
+
+	serials := make(map[string]bool)
+	for _, dc := range cfg.Devices {
+		serials[dc.Serial] = true
+	}
+
+	var ops []models.CopyOperation
+	for _, od := range drives {
+		if !serials[od.SerialNumber] {
+			continue
+		}
+
+		var driveOps []models.CopyOperation
+		if *dryRun {
+			driveOps, err = retention.Expired(ctx, repo, od.Id, now)
+		} else {
+			driveOps, err = retention.Purge(ctx, repo, od.Id, now)
+		}
+		ops = append(ops, driveOps...)
+		if err != nil {
+			break
+		}
+	}
+
+	for _, op := range ops {
+		fmt.Printf("%6d %-10s %-20s ended %s\n", op.Id, op.State, op.DiscLabel, op.EndedAt.Format(time.RFC3339))
+	}
+
+	if err != nil {
+		fmt.Printf("Failed to purge copy operations.\n")
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if !*dryRun {
+		count, err := transcript.Purge(now)
+		if err != nil {
+			fmt.Printf("Failed to purge transcripts.\n")
+			fmt.Printf("error: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Removed %d copy operation(s) and %d expired transcript(s).\n", len(ops), count)
+		return
+	}
+
+	transcripts, err := expiredTranscripts(ops, now)
+	if err != nil {
+		fmt.Printf("Failed to list expired transcripts.\n")
+		fmt.Printf("error: %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, id := range transcripts {
+		fmt.Printf("%6d transcript %s\n", id, transcript.Path(id))
+	}
+
+	fmt.Printf("Would remove %d copy operation(s) and %d transcript(s).\n", len(ops), len(transcripts))
+}
+
+// expiredTranscripts returns the identifiers, in order, of the copy operations
+// whose transcripts would be removed by a purge as of time `now` that removes
+// the copy operations `ops`.
+func expiredTranscripts(ops []models.CopyOperation, now time.Time) ([]int, error) {
+	expired, err := transcript.Expired(now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		if transcript.Exists(op.Id) && !slices.Contains(expired, op.Id) {
+			expired = append(expired, op.Id)
+		}
+	}
+
+	slices.Sort(expired)
+	return expired, nil
+}
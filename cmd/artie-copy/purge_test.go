@@ -0,0 +1,66 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/transcript"
+)
+
+func TestExpiredTranscripts(t *testing.T) {
+	cfg.Transcript = cfg.TranscriptConfig{Dir: t.TempDir(), RetentionDays: 30}
+
+	for _, id := range []int{4, 8, 15} {
+		w, err := transcript.Create(id)
+		if err != nil {
+			t.Fatal("Create returned an error:", err)
+		}
+		w.Close()
+	}
+
+	old := time.Now().AddDate(0, 0, -31)
+	os.Chtimes(transcript.Path(15), old, old)
+
+	// Operation 16 doesn't have a transcript so it isn't listed.
+	ops := []models.CopyOperation{{Id: 8}, {Id: 15}, {Id: 16}}
+	ids, err := expiredTranscripts(ops, time.Now())
+	if err != nil {
+		t.Fatal("expiredTranscripts returned an error:", err)
+	}
+
+	if expected := []int{8, 15}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expiredTranscripts = %v, expected %v", ids, expected)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/preflight"
+	"github.com/kfisher/artie-copy-service/internal/worker"
+)
+
+// handleReloads reloads the configuration file at `path` with the options in
+// `overrides` taking precedence each time the service receives SIGHUP. It runs
+// until `ctx` is cancelled.
+func handleReloads(ctx context.Context, path string, overrides map[string]string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reloadConfig(path, overrides)
+		}
+	}
+}
+
+// reloadConfig reloads the configuration file at `path` and logs the result.
+// The current configuration is kept if the file is invalid.
+func reloadConfig(path string, overrides map[string]string) {
+	slog.Info("Reloading config.", "path", path)
+
+	rejected, err := cfg.Reload(path, overrides, func(r *cfg.Reloadable) error {
+		return errors.Join(preflight.Run(context.Background(), r.MakeMkv, worker.NewRunner(r.MakeMkv))...)
+	})
+	if err != nil {
+		slog.Error("Failed to reload config. Keeping the current config.", "error", err)
+		return
+	}
+
+	for _, reason := range rejected {
+		slog.Warn("Ignored config change.", "reason", reason)
+	}
+
+	slog.SetLogLoggerLevel(cfg.Current().Log.SlogLevel())
+	slog.Info("Reloaded config.")
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveSpool(t *testing.T) {
+	out, state := t.TempDir(), t.TempDir()
+	old, path := filepath.Join(out, "db-spool.json"), filepath.Join(state, "db-spool.json")
+
+	if err := moveSpool(old, path); err != nil {
+		t.Fatalf("moveSpool without a spool returned %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("moveSpool without a spool created %s", path)
+	}
+
+	if err := os.WriteFile(old, []byte(`[{"action":"create"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveSpool(old, path); err != nil {
+		t.Fatalf("moveSpool returned %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != `[{"action":"create"}]` {
+		t.Errorf("spool = %q, %v, expected the old spool", data, err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("moveSpool left %s", old)
+	}
+}
+
+func TestMoveSpoolKeepsExisting(t *testing.T) {
+	out, state := t.TempDir(), t.TempDir()
+	old, path := filepath.Join(out, "db-spool.json"), filepath.Join(state, "db-spool.json")
+
+	if err := os.WriteFile(old, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveSpool(old, path); err != nil {
+		t.Fatalf("moveSpool returned %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("spool = %q, expected the existing spool to be kept", data)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Errorf("moveSpool removed %s: %v", old, err)
+	}
+}
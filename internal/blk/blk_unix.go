@@ -34,6 +34,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"strconv"
 )
 
 // TODO[LOW]: Implement testing for this package. It isn't straightforward to
@@ -78,3 +79,21 @@ func GetBlockDevice(sn string) (BlockDevice, error) {
 		return BlockDevice{}, ErrDeviceNotFound
 	}
 }
+
+// Eject ejects the disc in the device with name `name` (e.g. /dev/sr0).
+func Eject(name string) error {
+	out, err := exec.Command("eject", name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to eject %s: %w: %s", name, err, out)
+	}
+	return nil
+}
+
+// SetSpeed sets the read speed of the device with name `name` to `speed`.
+func SetSpeed(name string, speed int) error {
+	out, err := exec.Command("eject", "-x", strconv.Itoa(speed), name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to set speed of %s: %w: %s", name, err, out)
+	}
+	return nil
+}
@@ -35,3 +35,13 @@ package blk
 func GetBlockDevice(sn string) (BlockDevice, bool, error) {
 	panic("windows support not implemented yet")
 }
+
+// Eject ejects the disc in the device with name `name`.
+func Eject(name string) error {
+	panic("windows support not implemented yet")
+}
+
+// SetSpeed sets the read speed of the device with name `name` to `speed`.
+func SetSpeed(name string, speed int) error {
+	panic("windows support not implemented yet")
+}
@@ -26,74 +26,423 @@
 // ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
 // POSSIBILITY OF SUCH DAMAGE.
 
-// Package cfg handles managing configuration information. Configuration is
-// expected to be set during startup and not change afterwards. Therefore, they
-// should be safe to read in any routine without the need for a synchronization
-// method such as mutexes.
+// Package cfg handles managing configuration information. Most configuration
+// is set during startup and doesn't change afterwards. Therefore, it should be
+// safe to read in any routine without the need for a synchronization method
+// such as mutexes. The sections that can safely change while the service is
+// running are reloaded by Reload and are read through Current which returns
+// an immutable snapshot of them.
 package cfg
 
 import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
 
+	"github.com/kfisher/artie-copy-service/internal/naming"
 	"github.com/pelletier/go-toml/v2"
 )
 
+// The sections that require a restart for changes to take effect.
 var (
-	Device  DeviceConfig
-	Server  ServerConfig
-	MakeMkv MakeMkvConfig
-	Db      DatabaseConfig
+	Devices    []DeviceConfig
+	Server     ServerConfig
+	Db         DatabaseConfig
+	Transcript TranscriptConfig
 )
 
+// Reloadable is a snapshot of the sections that can change while the service
+// is running. A snapshot is never modified once it has been published so a
+// task that needs consistent configuration, such as a copy, should call
+// Current once and keep using the result.
+type Reloadable struct {
+	MakeMkv   MakeMkvConfig
+	Naming    NamingConfig
+	Catalog   CatalogConfig
+	Retention RetentionConfig
+	Log       LogConfig
+
+	// sources is the source of each of the service's options.
+	sources map[string]Source
+}
+
+// reloadableSections are the names of the sections in Reloadable.
+var reloadableSections = []string{"makemkv", "naming", "catalog", "retention", "log"}
+
+// restartOptions are the options in reloadable sections that require a
+// restart. The state directory holds files, such as the database spool and
+// the transcripts, whose locations are fixed at startup.
+var restartOptions = []string{"makemkv.state_directory"}
+
+var current atomic.Pointer[Reloadable]
+
+func init() {
+	current.Store(&Reloadable{})
+}
+
+// Current returns the snapshot of the reloadable configuration.
+func Current() *Reloadable {
+	return current.Load()
+}
+
+// Publish replaces the snapshot of the reloadable configuration with `r`.
+func Publish(r Reloadable) {
+	current.Store(&r)
+}
+
 // LoadConfig loads the configuration options provided by the TOML file `path`
-// and updates the service's global config.
+// and environment variables and updates the service's global config.
 func LoadConfig(path string) error {
+	return Load(path, nil)
+}
+
+// Load loads the configuration options provided by the TOML file `path`,
+// environment variables, and `flags` and updates the service's global config.
+// The flags are the values of options from the command line keyed by the
+// options' names such as "server.port" and take precedence over the others.
+func Load(path string, flags map[string]string) error {
+	l, err := read(path, flags)
+	if err != nil {
+		return err
+	}
+
+	Devices = l.devices
+	Server = l.config.Server
+	Db = l.config.Db
+	Transcript = l.config.Transcript
+	Publish(l.reloadable())
+
+	return nil
+}
+
+// Reload loads the configuration the same way as Load but only replaces the
+// reloadable sections. If `check` isn't nil, it is called with the new
+// sections to check them against the environment before they are used.
+// Nothing is changed if the configuration is invalid or the check fails.
+// Changes to options that require a restart are ignored and a description of
+// each is returned so that they can be reported. Reload shouldn't be called
+// concurrently with itself or Load.
+func Reload(path string, flags map[string]string, check func(r *Reloadable) error) ([]string, error) {
+	l, err := read(path, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	before := make(map[string]string)
+	for _, s := range published().settings() {
+		before[s.Name] = s.Value
+	}
+
+	// The sources of the options that require a restart stay the same since
+	// their values aren't changed.
+	r := l.reloadable()
+	r.sources = make(map[string]Source)
+	for name, source := range l.sources {
+		if isReloadable(name) {
+			r.sources[name] = source
+		}
+	}
+	for name, source := range Current().sources {
+		if !isReloadable(name) {
+			r.sources[name] = source
+		}
+	}
+
+	var rejected []string
+	for _, s := range l.settings() {
+		if isReloadable(s.Name) {
+			continue
+		}
+
+		old, ok := before[s.Name]
+		if !ok {
+			rejected = append(rejected, fmt.Sprintf("%s was added but requires a restart", s.Name))
+		} else if old != s.Value {
+			rejected = append(rejected, fmt.Sprintf("%s changed from %q to %q but requires a restart", s.Name, displayValue(s.Name, old), displayValue(s.Name, s.Value)))
+		}
+		delete(before, s.Name)
+	}
+	for name := range before {
+		if !isReloadable(name) {
+			rejected = append(rejected, fmt.Sprintf("%s was removed but requires a restart", name))
+		}
+	}
+	sort.Strings(rejected)
+
+	// The state directory keeps its value, and so do the directories within
+	// it that aren't configured, since it requires a restart.
+	if dir := Current().MakeMkv.StateDir; r.MakeMkv.StateDir != dir {
+		r.MakeMkv.setStateDir(dir, l.sources)
+		if err := r.MakeMkv.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid makemkv configuration: %w", err)
+		}
+	}
+
+	if check != nil {
+		if err := check(&r); err != nil {
+			return nil, err
+		}
+	}
+
+	Publish(r)
+
+	return rejected, nil
+}
+
+// isReloadable returns whether the option `name` is in a reloadable section
+// and doesn't require a restart.
+func isReloadable(name string) bool {
+	if slices.Contains(restartOptions, name) {
+		return false
+	}
+
+	section, _, _ := strings.Cut(name, ".")
+	return slices.Contains(reloadableSections, section)
+}
+
+// loaded is a configuration that has been read and validated but not yet
+// published.
+type loaded struct {
+	devices []DeviceConfig
+	config  serviceConfig
+	sources map[string]Source
+}
+
+// published returns the configuration that is currently in use.
+func published() loaded {
+	c := Current()
+	return loaded{
+		devices: Devices,
+		config: serviceConfig{
+			Server:     Server,
+			MakeMKV:    c.MakeMkv,
+			Naming:     c.Naming,
+			Db:         Db,
+			Transcript: Transcript,
+			Catalog:    c.Catalog,
+			Retention:  c.Retention,
+			Log:        c.Log,
+		},
+		sources: c.sources,
+	}
+}
+
+// reloadable returns the reloadable sections of the configuration.
+func (l *loaded) reloadable() Reloadable {
+	return Reloadable{
+		MakeMkv:   l.config.MakeMKV,
+		Naming:    l.config.Naming,
+		Catalog:   l.config.Catalog,
+		Retention: l.config.Retention,
+		Log:       l.config.Log,
+		sources:   l.sources,
+	}
+}
+
+// read reads the configuration options provided by the TOML file `path`,
+// environment variables, and `flags` and validates them.
+func read(path string, flags map[string]string) (loaded, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open config file: %w", err)
+		return loaded{}, fmt.Errorf("failed to open config file: %w", err)
 	}
 	defer file.Close()
 
 	bs, err := io.ReadAll(file)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return loaded{}, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config serviceConfig
+	// The defaults are set before the file is decoded and the overrides are
+	// applied so that an option explicitly set to zero keeps that value.
+	config := defaultConfig()
 	if err := toml.Unmarshal(bs, &config); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+		return loaded{}, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	if err = config.Device.Validate(); err != nil {
-		return fmt.Errorf("invalid device configuration: %w", err)
+	var tree map[string]any
+	if err := toml.Unmarshal(bs, &tree); err != nil {
+		return loaded{}, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	sources := make(map[string]Source)
+	if err := applyOverrides(&config, tree, flags, sources); err != nil {
+		return loaded{}, fmt.Errorf("invalid configuration override: %w", err)
+	}
+
+	devices, err := parseDevices(bs)
+	if err != nil {
+		return loaded{}, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	recordDeviceSources(devices, sources)
+
+	if err = validateDevices(devices); err != nil {
+		return loaded{}, fmt.Errorf("invalid device configuration: %w", err)
 	}
 
 	if err = config.Server.Validate(); err != nil {
-		return fmt.Errorf("invalid server configuration: %w", err)
+		return loaded{}, fmt.Errorf("invalid server configuration: %w", err)
 	}
 
+	config.MakeMKV.setStateDir(config.MakeMKV.StateDir, sources)
+
 	if err = config.MakeMKV.Validate(); err != nil {
-		return fmt.Errorf("invalid makemkv configuration: %w", err)
+		return loaded{}, fmt.Errorf("invalid makemkv configuration: %w", err)
+	}
+
+	if err = config.Naming.Validate(); err != nil {
+		return loaded{}, fmt.Errorf("invalid naming configuration: %w", err)
 	}
 
 	if err = config.Db.Validate(); err != nil {
-		return fmt.Errorf("invalid db configuration: %w", err)
+		return loaded{}, fmt.Errorf("invalid db configuration: %w", err)
 	}
 
-	Device = config.Device
-	Server = config.Server
-	MakeMkv = config.MakeMKV
-	Db = config.Db
+	if config.Transcript.Dir == "" {
+		config.Transcript.Dir = filepath.Join(config.MakeMKV.StateDir, "transcripts")
+	}
 
-	return nil
+	if err = config.Transcript.Validate(); err != nil {
+		return loaded{}, fmt.Errorf("invalid transcript configuration: %w", err)
+	}
+
+	if within(config.Transcript.Dir, config.MakeMKV.OutDir) {
+		return loaded{}, fmt.Errorf("invalid transcript configuration: directory %s cannot be within output_directory %s", config.Transcript.Dir, config.MakeMKV.OutDir)
+	}
+
+	if err = config.Catalog.Validate(); err != nil {
+		return loaded{}, fmt.Errorf("invalid catalog configuration: %w", err)
+	}
+
+	if err = config.Retention.Validate(); err != nil {
+		return loaded{}, fmt.Errorf("invalid retention configuration: %w", err)
+	}
+
+	if err = config.Log.Validate(); err != nil {
+		return loaded{}, fmt.Errorf("invalid log configuration: %w", err)
+	}
+
+	return loaded{devices: devices, config: config, sources: sources}, nil
 }
 
+// defaultConfig returns the configuration with the default value of every
+// option that has one. Options whose default depends on other options, such
+// as the directories within the state directory, are set once the
+// configuration has been read.
+func defaultConfig() serviceConfig {
+	return serviceConfig{
+		MakeMKV: MakeMkvConfig{
+			Mode:                   ModeMkv,
+			StateDir:               defaultStateDir,
+			ProgressSampleInterval: defaultProgressSampleInterval,
+			SpaceMargin:            defaultSpaceMargin,
+			SpaceCheckInterval:     defaultSpaceCheckInterval,
+			SizeTolerance:          defaultSizeTolerance,
+		},
+		Db: DatabaseConfig{
+			HealthCheckInterval: defaultHealthCheckInterval,
+			CommandPollInterval: defaultCommandPollInterval,
+		},
+		Catalog: CatalogConfig{
+			DuplicateAction: DuplicateWarn,
+		},
+		Retention: RetentionConfig{
+			PurgeInterval: defaultPurgeInterval,
+		},
+		Log: LogConfig{
+			Level: defaultLogLevel,
+		},
+	}
+}
+
+// defaultHeartbeatInterval is the number of seconds between heartbeats if
+// the interval isn't configured.
+const defaultHeartbeatInterval = 30
+
 type DeviceConfig struct {
 	Name   string `toml:"name"`
 	Serial string `toml:"serial_number"`
+
+	// HeartbeatInterval is the number of seconds between updates of the
+	// drive's last seen time in the database. Defaults to 30 seconds.
+	HeartbeatInterval int `toml:"heartbeat_interval"`
+
+	// LeaseTimeout is the number of seconds the service's lease on the drive
+	// lasts without being renewed by a heartbeat. Another service can't use
+	// the drive until the lease expires, so after a crash the service can't
+	// be restarted on another host until then. Defaults to three heartbeat
+	// intervals.
+	LeaseTimeout int `toml:"lease_timeout"`
+}
+
+// parseDevices parses the device configuration from the TOML document `bs`.
+// A single drive can be configured with a [device] table and multiple drives
+// with an array of [[device]] tables.
+func parseDevices(bs []byte) ([]DeviceConfig, error) {
+	var probe struct {
+		Device any `toml:"device"`
+	}
+	if err := toml.Unmarshal(bs, &probe); err != nil {
+		return nil, err
+	}
+
+	if _, ok := probe.Device.(map[string]any); ok {
+		var single struct {
+			Device DeviceConfig `toml:"device"`
+		}
+		err := toml.Unmarshal(bs, &single)
+		return []DeviceConfig{single.Device}, err
+	}
+
+	var multiple struct {
+		Devices []DeviceConfig `toml:"device"`
+	}
+	err := toml.Unmarshal(bs, &multiple)
+	return multiple.Devices, err
+}
+
+// validateDevices validates each of the drives in `devices`, sets the defaults
+// of any options that aren't configured, and makes sure that a drive isn't
+// configured more than once.
+func validateDevices(devices []DeviceConfig) error {
+	if len(devices) == 0 {
+		return errors.New("no devices configured")
+	}
+
+	serials := make(map[string]bool)
+	for i := range devices {
+		d := &devices[i]
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("device %d: %w", i+1, err)
+		}
+
+		if serials[d.Serial] {
+			return fmt.Errorf("device %d: serial_number %s is used by another device", i+1, d.Serial)
+		}
+		serials[d.Serial] = true
+
+		if d.HeartbeatInterval == 0 {
+			d.HeartbeatInterval = defaultHeartbeatInterval
+		}
+
+		if d.LeaseTimeout == 0 {
+			d.LeaseTimeout = 3 * d.HeartbeatInterval
+		}
+
+		if d.LeaseTimeout <= d.HeartbeatInterval {
+			return fmt.Errorf("device %d: lease_timeout must be greater than heartbeat_interval", i+1)
+		}
+	}
+
+	return nil
 }
 
 func (d *DeviceConfig) Validate() error {
@@ -105,6 +454,14 @@ func (d *DeviceConfig) Validate() error {
 		return errors.New("serial_number is missing or empty")
 	}
 
+	if d.HeartbeatInterval < 0 {
+		return errors.New("heartbeat_interval cannot be negative")
+	}
+
+	if d.LeaseTimeout < 0 {
+		return errors.New("lease_timeout cannot be negative")
+	}
+
 	return nil
 }
 
@@ -125,32 +482,290 @@ func (s *ServerConfig) Validate() error {
 	return nil
 }
 
+// defaultStateDir is the directory the service keeps its own files in if the
+// directory isn't configured.
+const defaultStateDir = "/var/lib/artie-copy"
+
+// defaultProgressSampleInterval is the number of seconds between samples of a
+// copy's progress if the interval isn't configured.
+const defaultProgressSampleInterval = 10
+
+// defaultSpaceMargin is the number of bytes that must be left free after a
+// copy if the margin isn't configured.
+const defaultSpaceMargin = 2 << 30
+
+// defaultSpaceCheckInterval is the number of seconds between checks of the
+// free space while a copy is running if the interval isn't configured.
+const defaultSpaceCheckInterval = 30
+
+// defaultSizeTolerance is the percentage by which the size of an MKV file may
+// differ from its title's size if the tolerance isn't configured.
+const defaultSizeTolerance = 10
+
 type MakeMkvConfig struct {
 	OutDir  string `toml:"output_directory"`
 	MakeMKV string `toml:"makemkv_exe"`
+
+	// MaxReadErrors is the number of read errors above which a copy is marked
+	// as failed. Zero means a copy never fails because of read errors.
+	MaxReadErrors int `toml:"max_read_errors"`
+
+	// OutputStallTimeout is the number of seconds MakeMKV can go without
+	// outputting anything before it is considered stalled and killed. Zero
+	// disables the check.
+	OutputStallTimeout int `toml:"output_stall_timeout"`
+
+	// ProgressStallTimeout is the number of seconds MakeMKV can go without its
+	// progress changing before it is considered stalled and killed. Zero
+	// disables the check.
+	ProgressStallTimeout int `toml:"progress_stall_timeout"`
+
+	// RetryOnStall specifies whether a copy is retried once if MakeMKV
+	// stalls.
+	RetryOnStall bool `toml:"retry_on_stall"`
+
+	// ProgressSampleInterval is the number of seconds between samples of a
+	// copy's progress. Defaults to 10 seconds. Zero samples every progress
+	// update.
+	ProgressSampleInterval int `toml:"progress_sample_interval"`
+
+	// MinFreeSpace is the number of bytes that must be free on the output
+	// directory's filesystem for the startup checks to pass. Zero means there
+	// is no minimum.
+	MinFreeSpace int64 `toml:"min_free_space"`
+
+	// SpaceMargin is the number of bytes that must be left free after a copy.
+	// A copy is refused if the size MakeMKV reports for the disc's titles plus
+	// the margin exceeds the free space of the staging or output directory,
+	// and is stopped if the free space needed to finish it runs out while it
+	// is running. The output directory is checked again before a copy that
+	// was staged on another filesystem is moved to it. Defaults to 2 GiB.
+	// Zero leaves no margin.
+	SpaceMargin int64 `toml:"space_margin"`
+
+	// SpaceCheckInterval is the number of seconds between checks of the free
+	// space while a copy is running. Defaults to 30 seconds. Zero disables
+	// the checks.
+	SpaceCheckInterval int `toml:"space_check_interval"`
+
+	// SizeTolerance is the percentage by which the size of an MKV file may
+	// differ from the size MakeMKV reported for its title before the file
+	// fails verification. Defaults to 10 percent. Zero requires the sizes to
+	// match exactly.
+	SizeTolerance int `toml:"size_tolerance"`
+
+	// StateDir is the directory the service keeps its own files in, such as
+	// the database spool. It can't be within the output directory so that
+	// programs watching the output directory only see copied files. Changes
+	// require a restart. Defaults to /var/lib/artie-copy.
+	StateDir string `toml:"state_directory"`
+
+	// StagingDir is the directory copies are written to while MakeMKV is
+	// running. Each copy has its own directory within it, and its files are
+	// only moved to the output directory once the copy succeeds. It can't be
+	// within the output directory. Defaults to the staging directory within
+	// the state directory. If it is on another filesystem than the output
+	// directory, such as the default with an output directory on a network
+	// share, every copy is copied a second time when it is moved, which needs
+	// as much time and free space in the output directory as the copy itself.
+	// Put it on the output directory's filesystem to have copies renamed
+	// instead.
+	StagingDir string `toml:"staging_directory"`
+
+	// HomeDir is the private home directory MakeMKV is run with. Its settings
+	// file is generated from the options below before every run so that
+	// MakeMKV's behavior doesn't depend on the settings of the user running
+	// the service. It can't be within the output directory. Defaults to the
+	// makemkv directory within the state directory.
+	HomeDir string `toml:"home_directory"`
+
+	// LicenseKey is the MakeMKV registration key. If empty, the key from the
+	// settings of the user running the service is used if there is one.
+	LicenseKey string `toml:"license_key"`
+
+	// Mode is how discs are copied. Either mkv to copy each title to an MKV
+	// file or backup to copy the whole disc. Defaults to mkv.
+	Mode CopyMode `toml:"mode"`
+
+	// Decrypt specifies whether backups are decrypted.
+	Decrypt bool `toml:"decrypt"`
+
+	// MinLength is the minimum length in seconds of the titles that are
+	// copied. Zero uses MakeMKV's default.
+	MinLength int `toml:"min_length"`
+
+	// CacheSize is the size of MakeMKV's read cache in megabytes. Zero uses
+	// MakeMKV's default.
+	CacheSize int `toml:"cache_size"`
+
+	// DirectIO is either true or false to enable or disable direct disc
+	// access. Empty uses MakeMKV's default.
+	DirectIO string `toml:"direct_io"`
+
+	// Profile is the path to the MakeMKV profile file used for conversion.
+	// Empty uses MakeMKV's default profile.
+	Profile string `toml:"profile"`
+
+	// DriveSpeed is the read speed the drive is set to before a copy. Zero
+	// leaves the drive's speed unchanged.
+	DriveSpeed int `toml:"drive_speed"`
+}
+
+// setStateDir sets the state directory to `dir` along with the staging and
+// MakeMKV home directories within it unless they were configured, according
+// to `sources`, to something else.
+func (m *MakeMkvConfig) setStateDir(dir string, sources map[string]Source) {
+	m.StateDir = dir
+
+	if _, ok := sources["makemkv.staging_directory"]; !ok || m.StagingDir == "" {
+		m.StagingDir = filepath.Join(dir, "staging")
+	}
+
+	if _, ok := sources["makemkv.home_directory"]; !ok || m.HomeDir == "" {
+		m.HomeDir = filepath.Join(dir, "makemkv")
+	}
 }
 
+// CopyMode specifies how MakeMKV copies discs.
+type CopyMode string
+
+const (
+	// ModeMkv copies each title on the disc to an MKV file.
+	ModeMkv CopyMode = "mkv"
+
+	// ModeBackup copies the whole disc.
+	ModeBackup CopyMode = "backup"
+)
+
 func (m *MakeMkvConfig) Validate() error {
 	if m.OutDir == "" {
 		return errors.New("output_directory is missing or empty")
 	}
 
-	if _, err := os.Stat(m.OutDir); os.IsNotExist(err) {
-		return errors.New("output_directory does not exist or is not a directory")
+	if m.MakeMKV == "" {
+		return errors.New("makemkv_exe is missing or empty")
 	}
 
-	// TODO[MED] Determine how to verify that the executable exists and is
-	// executable.
+	for _, dir := range []struct{ option, path string }{
+		{"state_directory", m.StateDir},
+		{"staging_directory", m.StagingDir},
+		{"home_directory", m.HomeDir},
+	} {
+		if within(dir.path, m.OutDir) {
+			return fmt.Errorf("%s %s cannot be within output_directory %s", dir.option, dir.path, m.OutDir)
+		}
+	}
 
-	if m.MakeMKV == "" {
-		return errors.New("makemkv_exe is missing or empty")
+	if m.MaxReadErrors < 0 {
+		return errors.New("max_read_errors cannot be negative")
+	}
+
+	if m.OutputStallTimeout < 0 {
+		return errors.New("output_stall_timeout cannot be negative")
+	}
+
+	if m.ProgressStallTimeout < 0 {
+		return errors.New("progress_stall_timeout cannot be negative")
+	}
+
+	if m.ProgressSampleInterval < 0 {
+		return errors.New("progress_sample_interval cannot be negative")
+	}
+
+	if m.MinFreeSpace < 0 {
+		return errors.New("min_free_space cannot be negative")
+	}
+
+	if m.SpaceMargin < 0 {
+		return errors.New("space_margin cannot be negative")
+	}
+
+	if m.SpaceCheckInterval < 0 {
+		return errors.New("space_check_interval cannot be negative")
+	}
+
+	if m.SizeTolerance < 0 {
+		return errors.New("size_tolerance cannot be negative")
+	}
+
+	if m.Mode != "" && m.Mode != ModeMkv && m.Mode != ModeBackup {
+		return fmt.Errorf("invalid mode %q, expected mkv or backup", m.Mode)
+	}
+
+	if m.MinLength < 0 {
+		return errors.New("min_length cannot be negative")
+	}
+
+	if m.CacheSize < 0 {
+		return errors.New("cache_size cannot be negative")
+	}
+
+	if m.DirectIO != "" && m.DirectIO != "true" && m.DirectIO != "false" {
+		return fmt.Errorf("invalid direct_io %q, expected true or false", m.DirectIO)
+	}
+
+	if m.DriveSpeed < 0 {
+		return errors.New("drive_speed cannot be negative")
 	}
 
 	return nil
 }
 
+// NamingConfig configures how the MKV files of a successful copy are named.
+// The templates are Go text templates executed with the fields of naming.Data,
+// for example "{{.DiscLabel}}" or "{{.DiscLabel}} - t{{printf \"%02d\" .Title}}".
+// Characters that aren't allowed in file names are replaced with underscores.
+type NamingConfig struct {
+	// Directory is the template of the directory within the output directory
+	// the files of a disc are moved to. Slashes separate subdirectories.
+	// Empty leaves the files in the copy operation's directory.
+	Directory string `toml:"directory"`
+
+	// File is the template of the name of each MKV file without its
+	// extension. If the name is already used, a number is added to it. Empty
+	// keeps the names MakeMKV used.
+	File string `toml:"file"`
+}
+
+func (n *NamingConfig) Validate() error {
+	if _, err := naming.Parse(n.Directory, n.File); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	return nil
+}
+
+// Templates returns the parsed templates. They are validated when the
+// configuration is loaded so an error is only returned for a configuration
+// that wasn't loaded.
+func (n *NamingConfig) Templates() (*naming.Templates, error) {
+	return naming.Parse(n.Directory, n.File)
+}
+
+// defaultHealthCheckInterval is the number of seconds between database health
+// checks if the interval isn't configured.
+const defaultHealthCheckInterval = 10
+
+// defaultCommandPollInterval is the number of seconds between checks for drive
+// commands if the interval isn't configured.
+const defaultCommandPollInterval = 5
+
 type DatabaseConfig struct {
 	ConnStr string `toml:"connection_string"`
+
+	// StartupTimeout is the number of seconds to keep retrying to reach the
+	// database at startup before giving up. Zero means retry forever.
+	StartupTimeout int `toml:"startup_timeout"`
+
+	// HealthCheckInterval is the number of seconds between checks of whether
+	// the database is reachable. Defaults to 10 seconds.
+	HealthCheckInterval int `toml:"health_check_interval"`
+
+	// CommandPollInterval is the number of seconds between checks for drive
+	// commands. PostgreSQL notifies the service when a command is added so
+	// polling is only a fallback in case a notification is missed. Defaults
+	// to 5 seconds.
+	CommandPollInterval int `toml:"command_poll_interval"`
 }
 
 func (d *DatabaseConfig) Validate() error {
@@ -158,12 +773,188 @@ func (d *DatabaseConfig) Validate() error {
 		return errors.New("db is missing or empty")
 	}
 
+	if d.StartupTimeout < 0 {
+		return errors.New("startup_timeout cannot be negative")
+	}
+
+	if d.HealthCheckInterval <= 0 {
+		return errors.New("health_check_interval must be positive")
+	}
+
+	if d.CommandPollInterval <= 0 {
+		return errors.New("command_poll_interval must be positive")
+	}
+
+	return nil
+}
+
+// TranscriptConfig configures how the raw output of MakeMKV is kept for each
+// copy operation.
+type TranscriptConfig struct {
+	// Dir is the directory the transcripts are written to. It can't be within
+	// the output directory. Defaults to the transcripts directory within the
+	// state directory.
+	Dir string `toml:"directory"`
+
+	// MaxSize is the maximum size in bytes of a transcript before it is
+	// compressed. Output beyond this is discarded. Zero means no limit.
+	MaxSize int64 `toml:"max_size"`
+
+	// RetentionDays is the number of days transcripts are kept. Zero means
+	// they are kept forever.
+	RetentionDays int `toml:"retention_days"`
+}
+
+// within returns true if the path `path` is the directory `dir` or is within
+// it. Relative paths are resolved against the working directory. An empty path
+// isn't within any directory.
+func within(path, dir string) bool {
+	if path == "" || dir == "" {
+		return false
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (t *TranscriptConfig) Validate() error {
+	if t.Dir == "" {
+		return errors.New("directory is missing or empty")
+	}
+
+	if t.MaxSize < 0 {
+		return errors.New("max_size cannot be negative")
+	}
+
+	if t.RetentionDays < 0 {
+		return errors.New("retention_days cannot be negative")
+	}
+
+	return nil
+}
+
+// DuplicateAction specifies what happens when a copy is started for a disc
+// that has already been copied successfully.
+type DuplicateAction string
+
+const (
+	// DuplicateOff disables checking whether a disc was already copied.
+	DuplicateOff DuplicateAction = "off"
+
+	// DuplicateWarn allows the copy but adds a warning to the operation.
+	DuplicateWarn DuplicateAction = "warn"
+
+	// DuplicateRefuse refuses to start the copy unless it is forced.
+	DuplicateRefuse DuplicateAction = "refuse"
+)
+
+// CatalogConfig configures how the disc catalog is used to detect discs that
+// were already copied.
+type CatalogConfig struct {
+	// DuplicateAction is what happens when the disc in the drive matches one
+	// that was already copied successfully. Defaults to warn.
+	DuplicateAction DuplicateAction `toml:"duplicate_action"`
+}
+
+func (c *CatalogConfig) Validate() error {
+	switch c.DuplicateAction {
+	case DuplicateOff, DuplicateWarn, DuplicateRefuse:
+		return nil
+	default:
+		return fmt.Errorf("invalid duplicate_action %q, expected off, warn, or refuse", c.DuplicateAction)
+	}
+}
+
+// defaultPurgeInterval is the number of seconds between purges of expired
+// copy operations if the interval isn't configured.
+const defaultPurgeInterval = 3600
+
+// RetentionConfig configures how long the history of copy operations is kept.
+// For each state, zero means the operations are kept forever.
+type RetentionConfig struct {
+	// SucceededDays is the number of days successful copy operations are
+	// kept after they end.
+	SucceededDays int `toml:"succeeded_days"`
+
+	// FailedDays is the number of days failed copy operations are kept after
+	// they end.
+	FailedDays int `toml:"failed_days"`
+
+	// CancelledDays is the number of days cancelled copy operations are kept
+	// after they end.
+	CancelledDays int `toml:"cancelled_days"`
+
+	// PurgeInterval is the number of seconds between purges of the copy
+	// operations that are past their retention period. Defaults to an hour.
+	PurgeInterval int `toml:"purge_interval"`
+}
+
+func (r *RetentionConfig) Validate() error {
+	if r.SucceededDays < 0 {
+		return errors.New("succeeded_days cannot be negative")
+	}
+
+	if r.FailedDays < 0 {
+		return errors.New("failed_days cannot be negative")
+	}
+
+	if r.CancelledDays < 0 {
+		return errors.New("cancelled_days cannot be negative")
+	}
+
+	if r.PurgeInterval <= 0 {
+		return errors.New("purge_interval must be positive")
+	}
+
 	return nil
 }
 
+// defaultLogLevel is the minimum level of the messages that are logged if the
+// level isn't configured.
+const defaultLogLevel = "info"
+
+// LogConfig configures the service's logging.
+type LogConfig struct {
+	// Level is the minimum level of the messages that are logged. Either
+	// debug, info, warn, or error. Defaults to info.
+	Level string `toml:"level"`
+}
+
+func (l *LogConfig) Validate() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return fmt.Errorf("invalid level %q, expected debug, info, warn, or error", l.Level)
+	}
+
+	return nil
+}
+
+// SlogLevel returns the configured level as a slog level.
+func (l *LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(l.Level))
+	return level
+}
+
+// serviceConfig is the configuration file's layout. The devices are parsed
+// separately since they can be a table or an array of tables.
 type serviceConfig struct {
-	Device  DeviceConfig
-	Server  ServerConfig
-	MakeMKV MakeMkvConfig
-	Db      DatabaseConfig
+	Server     ServerConfig     `toml:"server"`
+	MakeMKV    MakeMkvConfig    `toml:"makemkv"`
+	Naming     NamingConfig     `toml:"naming"`
+	Db         DatabaseConfig   `toml:"db"`
+	Transcript TranscriptConfig `toml:"transcript"`
+	Catalog    CatalogConfig    `toml:"catalog"`
+	Retention  RetentionConfig  `toml:"retention"`
+	Log        LogConfig        `toml:"log"`
 }
@@ -0,0 +1,254 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source is where the effective value of a configuration option came from.
+// Options are layered with each source overriding the ones before it: the
+// defaults, the configuration file, environment variables, and then command
+// line flags.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// envPrefix is the prefix of the environment variables that override options.
+// The rest of the variable's name is the option's name in upper case with the
+// dots replaced by underscores. For example, ARTIE_COPY_SERVER_PORT overrides
+// server.port.
+const envPrefix = "ARTIE_COPY_"
+
+// Setting is the effective value of a configuration option.
+type Setting struct {
+	Name   string
+	Value  string
+	Source Source
+}
+
+// EnvName returns the name of the environment variable that overrides the
+// option `name`.
+func EnvName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+}
+
+// Settings returns the effective value and source of every option ordered by
+// name. Passwords in the database connection string and the MakeMKV license
+// key are redacted.
+func Settings() []Setting {
+	settings := published().settings()
+	for i := range settings {
+		settings[i].Value = displayValue(settings[i].Name, settings[i].Value)
+	}
+	return settings
+}
+
+// settings returns the value and source of every option of the configuration
+// ordered by name.
+func (l loaded) settings() []Setting {
+	var settings []Setting
+	add := func(name string, v reflect.Value) {
+		source, ok := l.sources[name]
+		if !ok {
+			source = SourceDefault
+		}
+
+		settings = append(settings, Setting{Name: name, Value: fmt.Sprint(v.Interface()), Source: source})
+	}
+
+	for i := range l.devices {
+		walkOptions(fmt.Sprintf("device[%d]", i+1), reflect.ValueOf(&l.devices[i]).Elem(), add)
+	}
+	walkSections(&l.config, add)
+
+	sort.SliceStable(settings, func(i, j int) bool {
+		return settings[i].Name < settings[j].Name
+	})
+
+	return settings
+}
+
+// displayValue returns `value` of the option `name` with any secret redacted.
+func displayValue(name, value string) string {
+	switch {
+	case name == "db.connection_string":
+		return redactConnStr(value)
+	case name == "makemkv.license_key" && value != "":
+		return "xxxxx"
+	}
+	return value
+}
+
+// passwordPattern matches the password of a key/value or URL connection
+// string.
+var passwordPattern = regexp.MustCompile(`(password=)\S+|(://[^:/@]*:)[^@]*(@)`)
+
+// redactConnStr replaces the password in the connection string `s`.
+func redactConnStr(s string) string {
+	return passwordPattern.ReplaceAllString(s, "${1}${2}xxxxx${3}")
+}
+
+// applyOverrides sets the options of `config` from the environment and then
+// from `flags` which are keyed by the options' names. The source of each
+// option is recorded in `sources`, including the options in the configuration
+// file `tree`.
+func applyOverrides(config *serviceConfig, tree map[string]any, flags map[string]string, sources map[string]Source) error {
+	known := make(map[string]bool)
+
+	var err error
+	walkSections(config, func(name string, v reflect.Value) {
+		known[name] = true
+		if err != nil {
+			return
+		}
+
+		if inTree(tree, name) {
+			sources[name] = SourceFile
+		}
+
+		if s, ok := os.LookupEnv(EnvName(name)); ok {
+			if err = setOption(v, s); err != nil {
+				err = fmt.Errorf("invalid %s: %w", EnvName(name), err)
+				return
+			}
+			sources[name] = SourceEnv
+		}
+
+		if s, ok := flags[name]; ok {
+			if err = setOption(v, s); err != nil {
+				err = fmt.Errorf("invalid value for %s: %w", name, err)
+				return
+			}
+			sources[name] = SourceFlag
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for name := range flags {
+		if !known[name] {
+			return fmt.Errorf("unknown option %s", name)
+		}
+	}
+
+	return nil
+}
+
+// recordDeviceSources records the source of the options of `devices` which
+// can only be set in the configuration file. Any options left empty will be
+// set to their default.
+func recordDeviceSources(devices []DeviceConfig, sources map[string]Source) {
+	for i := range devices {
+		walkOptions(fmt.Sprintf("device[%d]", i+1), reflect.ValueOf(&devices[i]).Elem(), func(name string, v reflect.Value) {
+			if !v.IsZero() {
+				sources[name] = SourceFile
+			}
+		})
+	}
+}
+
+// walkSections calls `fn` with the name and value of each option in the
+// sections of `config`.
+func walkSections(config *serviceConfig, fn func(name string, v reflect.Value)) {
+	v := reflect.ValueOf(config).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		walkOptions(tomlName(v.Type().Field(i)), v.Field(i), fn)
+	}
+}
+
+// walkOptions calls `fn` with the name and value of each option of the
+// section `v` named `section`.
+func walkOptions(section string, v reflect.Value, fn func(name string, v reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		fn(section+"."+tomlName(v.Type().Field(i)), v.Field(i))
+	}
+}
+
+// tomlName returns the name of `field` in the configuration file.
+func tomlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// inTree returns whether the option `name` is set in the configuration file
+// `tree`.
+func inTree(tree map[string]any, name string) bool {
+	section, key, _ := strings.Cut(name, ".")
+	for k, v := range tree {
+		if !strings.EqualFold(k, section) {
+			continue
+		}
+		table, ok := v.(map[string]any)
+		if !ok {
+			return false
+		}
+		_, ok = table[key]
+		return ok
+	}
+	return false
+}
+
+// setOption parses `s` into the option `v` based on the option's type.
+func setOption(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported option type %s", v.Type())
+	}
+	return nil
+}
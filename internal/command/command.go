@@ -0,0 +1,233 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package command handles the commands sent to the drive through the database.
+// This allows the rest of the Artie suite to control the drive without knowing
+// the host and port of its copy service.
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/worker"
+)
+
+// listenRetryDelay is how long to wait before listening for commands again
+// after the connection used to listen fails.
+const listenRetryDelay = 5 * time.Second
+
+// Processor runs the commands sent to a drive.
+type Processor struct {
+	repo    db.CommandRepository
+	worker  *worker.Worker
+	driveId int
+
+	// starting is closed when the start command running in the background
+	// finishes. It is nil if one isn't running.
+	starting chan struct{}
+}
+
+// New creates a processor that runs the commands in `repo` sent to the drive
+// with identifier `driveId` using `w`.
+func New(repo db.CommandRepository, w *worker.Worker, driveId int) *Processor {
+	return &Processor{repo: repo, worker: w, driveId: driveId}
+}
+
+// Run handles commands until `ctx` is cancelled. If the repository can notify
+// the processor when commands are added they are handled immediately. The
+// repository is also polled every `pollInterval` in case a notification is
+// missed or the repository can't send them.
+func (p *Processor) Run(ctx context.Context, pollInterval time.Duration) {
+	wake := make(chan struct{}, 1)
+	if listener, ok := p.repo.(db.CommandListener); ok {
+		go p.listen(ctx, listener, wake)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		p.processPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			// The start command's result is recorded before returning.
+			if p.starting != nil {
+				<-p.starting
+			}
+			return
+		case <-wake:
+		case <-ticker.C:
+		case <-p.starting:
+		}
+	}
+}
+
+// listen signals `wake` whenever a command is added until `ctx` is cancelled.
+func (p *Processor) listen(ctx context.Context, listener db.CommandListener, wake chan<- struct{}) {
+	notify := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+
+	for {
+		err := listener.ListenDriveCommands(ctx, p.driveId, notify)
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.Warn("Stopped listening for drive commands. Retrying.", "delay", listenRetryDelay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+
+		// Commands may have been added while not listening.
+		notify()
+	}
+}
+
+// processPending runs all of the pending commands in the order they were
+// added. A start command runs in the background since scanning the disc can
+// take minutes, and only cancel commands are run until it finishes so that
+// the copy it starts can be cancelled. The other commands stay pending until
+// then.
+func (p *Processor) processPending(ctx context.Context) {
+	cmds, err := p.repo.ListPendingDriveCommands(ctx, p.driveId)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Failed to get pending drive commands.", "error", err)
+		}
+		return
+	}
+
+	for _, cmd := range cmds {
+		if p.starting != nil {
+			select {
+			case <-p.starting:
+				p.starting = nil
+			default:
+			}
+		}
+
+		if cmd.Command == models.DriveCommandCancel {
+			p.process(ctx, cmd)
+		} else if p.starting != nil {
+			continue
+		} else if cmd.Command == models.DriveCommandStart {
+			done := make(chan struct{})
+			p.starting = done
+			go func() {
+				defer close(done)
+				p.process(ctx, cmd)
+			}()
+		} else {
+			p.process(ctx, cmd)
+		}
+	}
+}
+
+// process runs `cmd` and records the result. The command is acknowledged
+// first so that it is only run once even if multiple processors see it.
+func (p *Processor) process(ctx context.Context, cmd models.DriveCommand) {
+	err := p.repo.AcknowledgeDriveCommand(ctx, cmd.Id, time.Now())
+	if errors.Is(err, db.ErrCommandNotPending) {
+		return
+	} else if err != nil {
+		slog.Warn("Failed to acknowledge drive command.", "id", cmd.Id, "error", err)
+		return
+	}
+
+	slog.Info("Running drive command.", "id", cmd.Id, "command", cmd.Command)
+
+	status := models.CommandStatusSucceeded
+	result, err := p.execute(ctx, cmd)
+	if err != nil {
+		slog.Warn("Drive command failed.", "id", cmd.Id, "command", cmd.Command, "error", err)
+		status = models.CommandStatusFailed
+		result = err.Error()
+	}
+
+	// A new context is used so that the result is recorded even if `ctx` was
+	// cancelled while the command was running.
+	err = p.repo.CompleteDriveCommand(context.Background(), cmd.Id, status, result, time.Now())
+	if err != nil {
+		slog.Warn("Failed to record drive command result.", "id", cmd.Id, "error", err)
+	}
+}
+
+// execute runs `cmd` and returns its result.
+func (p *Processor) execute(ctx context.Context, cmd models.DriveCommand) (string, error) {
+	switch cmd.Command {
+	case models.DriveCommandStart:
+		force, err := parseStartArgs(cmd.Args)
+		if err != nil {
+			return "", err
+		}
+		op, err := p.worker.StartCopy(ctx, force)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(op.Id), nil
+	case models.DriveCommandCancel:
+		return "", p.worker.CancelCopy()
+	case models.DriveCommandEject:
+		return "", p.worker.Eject(ctx)
+	case models.DriveCommandRescan:
+		return p.worker.Rescan(ctx)
+	default:
+		return "", fmt.Errorf("unknown command: %s", cmd.Command)
+	}
+}
+
+// parseStartArgs returns whether the start command with the arguments `args`
+// should copy a disc that was already copied, like the HTTP API's `force`
+// parameter.
+func parseStartArgs(args json.RawMessage) (bool, error) {
+	var parsed struct {
+		Force bool `json:"force"`
+	}
+	if args != nil {
+		if err := json.Unmarshal(args, &parsed); err != nil {
+			return false, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	return parsed.Force, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/worker"
+)
+
+func TestProcessPending(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+
+	drive := models.OpticalDrive{SerialNumber: "4-8-15-16-23-42"}
+	other := models.OpticalDrive{SerialNumber: "108"}
+	for _, od := range []*models.OpticalDrive{&drive, &other} {
+		if err := repo.UpsertOpticalDrive(ctx, od); err != nil {
+			t.Fatal("UpsertOpticalDrive returned an error:", err)
+		}
+	}
+
+	add := func(driveId int, command models.DriveCommandType) models.DriveCommand {
+		cmd := models.DriveCommand{DriveId: driveId, Command: command, CreatedAt: time.Now()}
+		if err := repo.CreateDriveCommand(ctx, &cmd); err != nil {
+			t.Fatal("CreateDriveCommand returned an error:", err)
+		}
+		return cmd
+	}
+
+	cancel := add(drive.Id, models.DriveCommandCancel)
+	unknown := add(drive.Id, "dance")
+	otherDrive := add(other.Id, models.DriveCommandCancel)
+
+	// A command acknowledged by another processor shouldn't be run again.
+	taken := add(drive.Id, models.DriveCommandCancel)
+	if err := repo.AcknowledgeDriveCommand(ctx, taken.Id, time.Now()); err != nil {
+		t.Fatal("AcknowledgeDriveCommand returned an error:", err)
+	}
+
+	p := New(repo, worker.New(repo, drive.SerialNumber), drive.Id)
+	p.processPending(ctx)
+
+	expected := []struct {
+		cmd    models.DriveCommand
+		status models.DriveCommandStatus
+		result string
+	}{
+		{cancel, models.CommandStatusFailed, worker.ErrNoCopyInProgress.Error()},
+		{unknown, models.CommandStatusFailed, "unknown command: dance"},
+		{otherDrive, models.CommandStatusPending, ""},
+		{taken, models.CommandStatusAcknowledged, ""},
+	}
+
+	for _, e := range expected {
+		got, err := repo.GetDriveCommand(ctx, e.cmd.Id)
+		if err != nil {
+			t.Error("GetDriveCommand returned an error:", err)
+			continue
+		}
+
+		if got.Status != e.status || got.Result != e.result {
+			t.Errorf("Command %s has status %s and result '%s', expected %s and '%s'",
+				e.cmd.Command, got.Status, got.Result, e.status, e.result)
+		}
+
+		if e.status == models.CommandStatusFailed && (got.AcknowledgedAt.IsZero() || got.CompletedAt.IsZero()) {
+			t.Errorf("Command %s is missing its acknowledged or completed time", e.cmd.Command)
+		}
+	}
+}
+
+func TestProcessPendingWhileStarting(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+
+	drive := models.OpticalDrive{SerialNumber: "4-8-15-16-23-42"}
+	if err := repo.UpsertOpticalDrive(ctx, &drive); err != nil {
+		t.Fatal("UpsertOpticalDrive returned an error:", err)
+	}
+
+	var cmds []models.DriveCommand
+	for _, command := range []models.DriveCommandType{models.DriveCommandEject, models.DriveCommandCancel} {
+		cmd := models.DriveCommand{DriveId: drive.Id, Command: command, CreatedAt: time.Now()}
+		if err := repo.CreateDriveCommand(ctx, &cmd); err != nil {
+			t.Fatal("CreateDriveCommand returned an error:", err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	eject, cancel := cmds[0], cmds[1]
+
+	// A start command is still scanning the disc.
+	p := New(repo, worker.New(repo, drive.SerialNumber), drive.Id)
+	p.starting = make(chan struct{})
+	p.processPending(ctx)
+
+	if got, _ := repo.GetDriveCommand(ctx, cancel.Id); got.Status != models.CommandStatusFailed || got.Result != worker.ErrNoCopyInProgress.Error() {
+		t.Errorf("Cancel command has status %s and result '%s', expected it to run", got.Status, got.Result)
+	}
+	if got, _ := repo.GetDriveCommand(ctx, eject.Id); got.Status != models.CommandStatusPending {
+		t.Errorf("Eject command has status %s, expected it to wait for the start command", got.Status)
+	}
+
+	close(p.starting)
+	p.processPending(ctx)
+
+	if got, _ := repo.GetDriveCommand(ctx, eject.Id); got.Status == models.CommandStatusPending {
+		t.Error("Eject command is still pending after the start command finished")
+	}
+	if p.starting != nil {
+		t.Error("Expected the finished start command to be cleared")
+	}
+}
+
+func TestParseStartArgs(t *testing.T) {
+	tests := []struct {
+		args  json.RawMessage
+		force bool
+		err   bool
+	}{
+		{nil, false, false},
+		{json.RawMessage(`{}`), false, false},
+		{json.RawMessage(`{"force": true}`), true, false},
+		{json.RawMessage(`{"force": false}`), false, false},
+		{json.RawMessage(`{"force": "yes"}`), false, true},
+		{json.RawMessage(`[true]`), false, true},
+	}
+
+	for _, test := range tests {
+		force, err := parseStartArgs(test.args)
+		if force != test.force || (err != nil) != test.err {
+			t.Errorf("parseStartArgs(%s) = %t, %v, expected %t with error %t", test.args, force, err, test.force, test.err)
+		}
+	}
+}
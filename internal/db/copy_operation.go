@@ -0,0 +1,240 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+const copyOperationColumns = "id, drive_id, disc_label, output_dir, state, started_at, ended_at, warnings, failure_reason, damaged, read_error_count, read_errors, disc_id, manifest"
+
+// CreateCopyOperation adds the copy operation `op` to the database and updates
+// its Id with the identifier assigned by the database.
+func (r *PgRepository) CreateCopyOperation(ctx context.Context, op *models.CopyOperation) error {
+	stmt := `INSERT INTO copy_operation
+		(drive_id, disc_label, output_dir, state, started_at, ended_at, warnings, failure_reason,
+		 damaged, read_error_count, read_errors, disc_id, manifest)
+		VALUES (@driveId, @discLabel, @outputDir, @state, @startedAt, @endedAt, @warnings, @failureReason,
+		 @damaged, @readErrorCount, @readErrors, @discId, @manifest)
+		RETURNING id`
+	err := r.pool.QueryRow(ctx, stmt, copyOperationArgs(*op)).Scan(&op.Id)
+	if err != nil {
+		return fmt.Errorf("insert failed: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateCopyOperation updates the database record for the copy operation `op`.
+func (r *PgRepository) UpdateCopyOperation(ctx context.Context, op models.CopyOperation) error {
+	stmt := `UPDATE copy_operation SET
+		disc_label=@discLabel, output_dir=@outputDir, state=@state, started_at=@startedAt,
+		ended_at=@endedAt, warnings=@warnings, failure_reason=@failureReason,
+		damaged=@damaged, read_error_count=@readErrorCount, read_errors=@readErrors,
+		disc_id=@discId, manifest=@manifest
+		WHERE id=@id`
+	tag, err := r.pool.Exec(ctx, stmt, copyOperationArgs(op))
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrCopyOperationNotFound
+	}
+
+	return nil
+}
+
+// GetCopyOperation gets the copy operation with identifier `id`. If the
+// operation doesn't exist, ErrCopyOperationNotFound is returned.
+func (r *PgRepository) GetCopyOperation(ctx context.Context, id int) (models.CopyOperation, error) {
+	stmt := "SELECT " + copyOperationColumns + " FROM copy_operation WHERE id=@id"
+	args := pgx.NamedArgs{"id": id}
+	op, err := scanCopyOperation(r.pool.QueryRow(ctx, stmt, args))
+	if err == pgx.ErrNoRows {
+		return op, ErrCopyOperationNotFound
+	} else if err != nil {
+		return op, fmt.Errorf("query row failed: %w", err)
+	}
+
+	return op, nil
+}
+
+// ListCopyOperations gets all of the copy operations for the drive with
+// identifier `driveId` ordered from newest to oldest.
+func (r *PgRepository) ListCopyOperations(ctx context.Context, driveId int) ([]models.CopyOperation, error) {
+	stmt := "SELECT " + copyOperationColumns + " FROM copy_operation WHERE drive_id=@driveId ORDER BY id DESC"
+	return r.listCopyOperations(ctx, stmt, pgx.NamedArgs{"driveId": driveId})
+}
+
+// listCopyOperations gets the copy operations selected by query `stmt`.
+func (r *PgRepository) listCopyOperations(ctx context.Context, stmt string, args pgx.NamedArgs) ([]models.CopyOperation, error) {
+	rows, err := r.pool.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	ops := make([]models.CopyOperation, 0)
+	for rows.Next() {
+		op, err := scanCopyOperation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		ops = append(ops, op)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return ops, nil
+}
+
+// ListCopyOperationsForDisc gets all of the copy operations of the disc with
+// identifier `discId` ordered from newest to oldest.
+func (r *PgRepository) ListCopyOperationsForDisc(ctx context.Context, discId int) ([]models.CopyOperation, error) {
+	stmt := "SELECT " + copyOperationColumns + " FROM copy_operation WHERE disc_id=@discId ORDER BY id DESC"
+	return r.listCopyOperations(ctx, stmt, pgx.NamedArgs{"discId": discId})
+}
+
+// ListCopyOperationsEndedBefore gets the copy operations for the drive with
+// identifier `driveId` in state `state` that ended before `before` ordered from
+// oldest to newest.
+func (r *PgRepository) ListCopyOperationsEndedBefore(ctx context.Context, driveId int, state models.CopyOperationState, before time.Time) ([]models.CopyOperation, error) {
+	stmt := "SELECT " + copyOperationColumns + ` FROM copy_operation
+		WHERE drive_id=@driveId AND state=@state AND ended_at<@before ORDER BY id`
+	args := pgx.NamedArgs{"driveId": driveId, "state": state, "before": before}
+	return r.listCopyOperations(ctx, stmt, args)
+}
+
+// DeleteCopyOperation deletes the copy operation with identifier `id`.
+func (r *PgRepository) DeleteCopyOperation(ctx context.Context, id int) error {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM copy_operation WHERE id=@id", pgx.NamedArgs{"id": id})
+	if err != nil {
+		return fmt.Errorf("delete failed: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrCopyOperationNotFound
+	}
+
+	return nil
+}
+
+func copyOperationArgs(op models.CopyOperation) pgx.NamedArgs {
+	var endedAt *time.Time
+	if !op.EndedAt.IsZero() {
+		endedAt = &op.EndedAt
+	}
+
+	warnings := op.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	readErrors := op.ReadErrors
+	if readErrors == nil {
+		readErrors = []models.ReadError{}
+	}
+
+	var discId *int
+	if op.DiscId != 0 {
+		discId = &op.DiscId
+	}
+
+	var manifest *string
+	if op.Manifest != nil {
+		s := string(op.Manifest)
+		manifest = &s
+	}
+
+	return pgx.NamedArgs{
+		"id":             op.Id,
+		"driveId":        op.DriveId,
+		"discLabel":      op.DiscLabel,
+		"outputDir":      op.OutputDir,
+		"state":          string(op.State),
+		"startedAt":      op.StartedAt,
+		"endedAt":        endedAt,
+		"warnings":       warnings,
+		"failureReason":  op.FailureReason,
+		"damaged":        op.Damaged,
+		"readErrorCount": op.ReadErrorCount,
+		"readErrors":     readErrors,
+		"discId":         discId,
+		"manifest":       manifest,
+	}
+}
+
+func scanCopyOperation(row pgx.Row) (models.CopyOperation, error) {
+	var op models.CopyOperation
+	var state string
+	var endedAt *time.Time
+	var discId *int
+	var manifest *string
+	err := row.Scan(
+		&op.Id,
+		&op.DriveId,
+		&op.DiscLabel,
+		&op.OutputDir,
+		&state,
+		&op.StartedAt,
+		&endedAt,
+		&op.Warnings,
+		&op.FailureReason,
+		&op.Damaged,
+		&op.ReadErrorCount,
+		&op.ReadErrors,
+		&discId,
+		&manifest,
+	)
+	if err != nil {
+		return op, err
+	}
+
+	op.State = models.CopyOperationState(state)
+	if endedAt != nil {
+		op.EndedAt = *endedAt
+	}
+	if discId != nil {
+		op.DiscId = *discId
+	}
+	if manifest != nil {
+		op.Manifest = json.RawMessage(*manifest)
+	}
+
+	return op, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+const copyProgressSampleColumns = "copy_operation_id, sampled_at, title, bytes, percent"
+
+const insertCopyProgressSampleStmt = `INSERT INTO copy_progress_sample (` + copyProgressSampleColumns + `)
+	VALUES (@id, @sampledAt, @title, @bytes, @percent)`
+
+// AddCopyProgressSample adds progress sample `sample` to its copy operation.
+func (r *PgRepository) AddCopyProgressSample(ctx context.Context, sample models.CopyProgressSample) error {
+	if _, err := r.pool.Exec(ctx, insertCopyProgressSampleStmt, copyProgressSampleArgs(sample)); err != nil {
+		return fmt.Errorf("insert failed: %w", err)
+	}
+
+	return nil
+}
+
+// ListCopyProgressSamples gets the progress samples of the copy operation with
+// identifier `id` ordered from oldest to newest.
+func (r *PgRepository) ListCopyProgressSamples(ctx context.Context, id int) ([]models.CopyProgressSample, error) {
+	stmt := "SELECT " + copyProgressSampleColumns + ` FROM copy_progress_sample
+		WHERE copy_operation_id=@id ORDER BY sampled_at`
+	rows, err := r.pool.Query(ctx, stmt, pgx.NamedArgs{"id": id})
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	samples := make([]models.CopyProgressSample, 0)
+	for rows.Next() {
+		var s models.CopyProgressSample
+		if err := rows.Scan(&s.CopyOperationId, &s.SampledAt, &s.Title, &s.Bytes, &s.Percent); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		samples = append(samples, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return samples, nil
+}
+
+// ReplaceCopyProgressSamples replaces the progress samples of the copy
+// operation with identifier `id` with `samples`.
+func (r *PgRepository) ReplaceCopyProgressSamples(ctx context.Context, id int, samples []models.CopyProgressSample) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		stmt := "DELETE FROM copy_progress_sample WHERE copy_operation_id=@id"
+		if _, err := tx.Exec(ctx, stmt, pgx.NamedArgs{"id": id}); err != nil {
+			return fmt.Errorf("delete failed: %w", err)
+		}
+
+		for _, sample := range samples {
+			sample.CopyOperationId = id
+			if _, err := tx.Exec(ctx, insertCopyProgressSampleStmt, copyProgressSampleArgs(sample)); err != nil {
+				return fmt.Errorf("insert failed: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func copyProgressSampleArgs(sample models.CopyProgressSample) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"id":        sample.CopyOperationId,
+		"sampledAt": sample.SampledAt,
+		"title":     sample.Title,
+		"bytes":     sample.Bytes,
+		"percent":   sample.Percent,
+	}
+}
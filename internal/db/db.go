@@ -31,53 +31,33 @@ package db
 
 import (
 	"context"
-	"fmt"
-	"log/slog"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/kfisher/artie-copy-service/internal/cfg"
 )
 
-var Pool *pgxpool.Pool = nil
+// PgRepository is the Repository implementation backed by a PostgreSQL
+// database.
+type PgRepository struct {
+	pool *pgxpool.Pool
+}
 
-// InitPool initializes the connection pool.
-func InitPool() error {
-	pool, err := pgxpool.New(context.Background(), cfg.Db.ConnStr)
+// NewPgRepository creates a repository connected to the PostgreSQL database
+// specified by connection string `connStr`.
+func NewPgRepository(ctx context.Context, connStr string) (*PgRepository, error) {
+	pool, err := pgxpool.New(ctx, connStr)
 	if err != nil {
-		return err
-	} else {
-		Pool = pool
-		return nil
+		return nil, err
 	}
-}
 
-// Close
-func Close() {
-	Pool.Close()
+	return &PgRepository{pool: pool}, nil
 }
 
-// InitOpticalDriveInfo checks the database to see if there is an entry for `sn`
-// and adds if not.
-func InitOpticalDriveInfo(ctx context.Context, sn string) (int, error) {
-	stmt := "SELECT id FROM optical_drive WHERE serial_number=@sn"
-	args := pgx.NamedArgs{"sn": sn}
-	var id int
-	err := Pool.QueryRow(ctx, stmt, args).Scan(&id)
-	if err == nil {
-		slog.Debug("Optical drive information already in database.", "id", id)
-		return id, nil
-	} else if err != pgx.ErrNoRows {
-		return 0, fmt.Errorf("query row failed: %w", err)
-	}
-
-	slog.Debug("Adding optical drive info to database.")
-
-	stmt = "INSERT INTO optical_drive (serial_number) VALUES (@sn) RETURNING id"
-	err = Pool.QueryRow(ctx, stmt, args).Scan(&id)
-	if err != nil {
-		return 0, fmt.Errorf("insert failed: %w", err)
-	}
+// Close closes the connection pool.
+func (r *PgRepository) Close() {
+	r.pool.Close()
+}
 
-	return id, nil
+// Ping checks that the database is reachable.
+func (r *PgRepository) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
 }
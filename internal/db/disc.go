@@ -0,0 +1,211 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+const (
+	discColumns       = "id, label, name, volume_name, fingerprint, created_at"
+	discTitleColumns  = "id, title_index, name, duration, size_bytes, chapter_count, segments_map, source_file"
+	discStreamColumns = "title_id, stream_index, type, codec, lang_code, name"
+)
+
+// CreateDisc adds the disc `disc` along with its titles and streams to the
+// database and updates its Id with the identifier assigned by the database.
+func (r *PgRepository) CreateDisc(ctx context.Context, disc *models.Disc) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		stmt := `INSERT INTO disc (label, name, volume_name, fingerprint, created_at)
+			VALUES (@label, @name, @volumeName, @fingerprint, @createdAt)
+			RETURNING id`
+		args := pgx.NamedArgs{
+			"label":       disc.Label,
+			"name":        disc.Name,
+			"volumeName":  disc.VolumeName,
+			"fingerprint": disc.Fingerprint,
+			"createdAt":   disc.CreatedAt,
+		}
+		if err := tx.QueryRow(ctx, stmt, args).Scan(&disc.Id); err != nil {
+			return fmt.Errorf("insert failed: %w", err)
+		}
+
+		for _, title := range disc.Titles {
+			stmt := `INSERT INTO disc_title
+				(disc_id, title_index, name, duration, size_bytes, chapter_count, segments_map, source_file)
+				VALUES (@discId, @index, @name, @duration, @size, @chapterCount, @segmentsMap, @sourceFile)
+				RETURNING id`
+			args := pgx.NamedArgs{
+				"discId":       disc.Id,
+				"index":        title.Index,
+				"name":         title.Name,
+				"duration":     title.Duration,
+				"size":         title.Size,
+				"chapterCount": title.ChapterCount,
+				"segmentsMap":  title.SegmentsMap,
+				"sourceFile":   title.SourceFile,
+			}
+			var titleId int
+			if err := tx.QueryRow(ctx, stmt, args).Scan(&titleId); err != nil {
+				return fmt.Errorf("title insert failed: %w", err)
+			}
+
+			for _, stream := range title.Streams {
+				stmt := `INSERT INTO disc_stream (title_id, stream_index, type, codec, lang_code, name)
+					VALUES (@titleId, @index, @type, @codec, @langCode, @name)`
+				args := pgx.NamedArgs{
+					"titleId":  titleId,
+					"index":    stream.Index,
+					"type":     stream.Type,
+					"codec":    stream.Codec,
+					"langCode": stream.LangCode,
+					"name":     stream.Name,
+				}
+				if _, err := tx.Exec(ctx, stmt, args); err != nil {
+					return fmt.Errorf("stream insert failed: %w", err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetDisc gets the disc with identifier `id` including its titles and streams.
+// If the disc doesn't exist, ErrDiscNotFound is returned.
+func (r *PgRepository) GetDisc(ctx context.Context, id int) (models.Disc, error) {
+	stmt := "SELECT " + discColumns + " FROM disc WHERE id=@id"
+	return r.getDisc(ctx, stmt, pgx.NamedArgs{"id": id})
+}
+
+// GetDiscByFingerprint gets the oldest disc with fingerprint `fingerprint`
+// including its titles and streams. If the disc doesn't exist,
+// ErrDiscNotFound is returned.
+func (r *PgRepository) GetDiscByFingerprint(ctx context.Context, fingerprint string) (models.Disc, error) {
+	stmt := "SELECT " + discColumns + " FROM disc WHERE fingerprint=@fingerprint ORDER BY id LIMIT 1"
+	return r.getDisc(ctx, stmt, pgx.NamedArgs{"fingerprint": fingerprint})
+}
+
+// ListDiscs gets all of the discs ordered from newest to oldest.
+func (r *PgRepository) ListDiscs(ctx context.Context) ([]models.Disc, error) {
+	stmt := "SELECT " + discColumns + " FROM disc ORDER BY id DESC"
+	rows, err := r.pool.Query(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	discs := make([]models.Disc, 0)
+	for rows.Next() {
+		disc, err := scanDisc(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		discs = append(discs, disc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return discs, nil
+}
+
+// getDisc gets the disc selected by query `stmt` along with its titles and
+// streams.
+func (r *PgRepository) getDisc(ctx context.Context, stmt string, args pgx.NamedArgs) (models.Disc, error) {
+	disc, err := scanDisc(r.pool.QueryRow(ctx, stmt, args))
+	if err == pgx.ErrNoRows {
+		return disc, ErrDiscNotFound
+	} else if err != nil {
+		return disc, fmt.Errorf("query row failed: %w", err)
+	}
+
+	stmt = "SELECT " + discTitleColumns + " FROM disc_title WHERE disc_id=@discId ORDER BY title_index"
+	rows, err := r.pool.Query(ctx, stmt, pgx.NamedArgs{"discId": disc.Id})
+	if err != nil {
+		return disc, fmt.Errorf("title query failed: %w", err)
+	}
+
+	titleIds := make(map[int]int)
+	for rows.Next() {
+		var title models.DiscTitle
+		var titleId int
+		err := rows.Scan(&titleId, &title.Index, &title.Name, &title.Duration, &title.Size,
+			&title.ChapterCount, &title.SegmentsMap, &title.SourceFile)
+		if err != nil {
+			rows.Close()
+			return disc, fmt.Errorf("title scan failed: %w", err)
+		}
+		titleIds[titleId] = len(disc.Titles)
+		disc.Titles = append(disc.Titles, title)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return disc, fmt.Errorf("title query failed: %w", err)
+	}
+
+	stmt = `SELECT ` + discStreamColumns + ` FROM disc_stream
+		WHERE title_id IN (SELECT id FROM disc_title WHERE disc_id=@discId)
+		ORDER BY title_id, stream_index`
+	rows, err = r.pool.Query(ctx, stmt, pgx.NamedArgs{"discId": disc.Id})
+	if err != nil {
+		return disc, fmt.Errorf("stream query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stream models.DiscStream
+		var titleId int
+		err := rows.Scan(&titleId, &stream.Index, &stream.Type, &stream.Codec, &stream.LangCode, &stream.Name)
+		if err != nil {
+			return disc, fmt.Errorf("stream scan failed: %w", err)
+		}
+		if i, ok := titleIds[titleId]; ok {
+			disc.Titles[i].Streams = append(disc.Titles[i].Streams, stream)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return disc, fmt.Errorf("stream query failed: %w", err)
+	}
+
+	return disc, nil
+}
+
+func scanDisc(row pgx.Row) (models.Disc, error) {
+	var disc models.Disc
+	err := row.Scan(&disc.Id, &disc.Label, &disc.Name, &disc.VolumeName, &disc.Fingerprint, &disc.CreatedAt)
+	return disc, err
+}
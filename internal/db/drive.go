@@ -0,0 +1,132 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+const opticalDriveColumns = "id, name, host, device_name, serial_number, last_seen, online"
+
+// UpsertOpticalDrive adds or updates the entry for the drive with the serial
+// number of `od` and updates its Id with the identifier assigned by the
+// database.
+func (r *PgRepository) UpsertOpticalDrive(ctx context.Context, od *models.OpticalDrive) error {
+	var lastSeen *time.Time
+	if !od.LastSeen.IsZero() {
+		lastSeen = &od.LastSeen
+	}
+
+	stmt := `INSERT INTO optical_drive (name, host, device_name, serial_number, last_seen, online)
+		VALUES (@name, @host, @deviceName, @sn, @lastSeen, @online)
+		ON CONFLICT (serial_number) DO UPDATE SET
+		name=excluded.name, host=excluded.host, device_name=excluded.device_name,
+		last_seen=excluded.last_seen, online=excluded.online
+		RETURNING id`
+	args := pgx.NamedArgs{
+		"name":       od.Name,
+		"host":       od.Host,
+		"deviceName": od.DeviceName,
+		"sn":         od.SerialNumber,
+		"lastSeen":   lastSeen,
+		"online":     od.Online,
+	}
+	if err := r.pool.QueryRow(ctx, stmt, args).Scan(&od.Id); err != nil {
+		return fmt.Errorf("upsert failed: %w", err)
+	}
+
+	return nil
+}
+
+// HeartbeatOpticalDrive marks the drive with identifier `id` as online and sets
+// its last seen time to `seen`.
+func (r *PgRepository) HeartbeatOpticalDrive(ctx context.Context, id int, seen time.Time) error {
+	stmt := "UPDATE optical_drive SET last_seen=@seen, online=TRUE WHERE id=@id"
+	args := pgx.NamedArgs{"id": id, "seen": seen}
+	tag, err := r.pool.Exec(ctx, stmt, args)
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrDriveNotFound
+	}
+
+	return nil
+}
+
+// SetOpticalDriveOffline marks the drive with identifier `id` as offline.
+func (r *PgRepository) SetOpticalDriveOffline(ctx context.Context, id int) error {
+	stmt := "UPDATE optical_drive SET online=FALSE WHERE id=@id"
+	tag, err := r.pool.Exec(ctx, stmt, pgx.NamedArgs{"id": id})
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrDriveNotFound
+	}
+
+	return nil
+}
+
+// ListOpticalDrives gets all of the drives ordered by identifier.
+func (r *PgRepository) ListOpticalDrives(ctx context.Context) ([]models.OpticalDrive, error) {
+	stmt := "SELECT " + opticalDriveColumns + " FROM optical_drive ORDER BY id"
+	rows, err := r.pool.Query(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	drives := make([]models.OpticalDrive, 0)
+	for rows.Next() {
+		var od models.OpticalDrive
+		var lastSeen *time.Time
+		err := rows.Scan(&od.Id, &od.Name, &od.Host, &od.DeviceName, &od.SerialNumber, &lastSeen, &od.Online)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		if lastSeen != nil {
+			od.LastSeen = *lastSeen
+		}
+		drives = append(drives, od)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return drives, nil
+}
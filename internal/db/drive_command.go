@@ -0,0 +1,199 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+const driveCommandColumns = "id, drive_id, command, args, status, result, created_at, acknowledged_at, completed_at"
+
+// CreateDriveCommand adds the pending command `cmd` to the database and updates
+// its Id with the identifier assigned by the database. Adding the command
+// notifies the service managing the drive.
+func (r *PgRepository) CreateDriveCommand(ctx context.Context, cmd *models.DriveCommand) error {
+	stmt := `INSERT INTO drive_command (drive_id, command, args, status, created_at)
+		VALUES (@driveId, @command, @args, @status, @createdAt)
+		RETURNING id`
+	var cmdArgs *string
+	if cmd.Args != nil {
+		s := string(cmd.Args)
+		cmdArgs = &s
+	}
+	args := pgx.NamedArgs{
+		"driveId":   cmd.DriveId,
+		"command":   string(cmd.Command),
+		"args":      cmdArgs,
+		"status":    string(models.CommandStatusPending),
+		"createdAt": cmd.CreatedAt,
+	}
+	if err := r.pool.QueryRow(ctx, stmt, args).Scan(&cmd.Id); err != nil {
+		return fmt.Errorf("insert failed: %w", err)
+	}
+
+	cmd.Status = models.CommandStatusPending
+	return nil
+}
+
+// GetDriveCommand gets the command with identifier `id`. If the command doesn't
+// exist, ErrCommandNotFound is returned.
+func (r *PgRepository) GetDriveCommand(ctx context.Context, id int) (models.DriveCommand, error) {
+	stmt := "SELECT " + driveCommandColumns + " FROM drive_command WHERE id=@id"
+	cmd, err := scanDriveCommand(r.pool.QueryRow(ctx, stmt, pgx.NamedArgs{"id": id}))
+	if err == pgx.ErrNoRows {
+		return cmd, ErrCommandNotFound
+	} else if err != nil {
+		return cmd, fmt.Errorf("query row failed: %w", err)
+	}
+
+	return cmd, nil
+}
+
+// ListPendingDriveCommands gets the pending commands for the drive with
+// identifier `driveId` ordered from oldest to newest.
+func (r *PgRepository) ListPendingDriveCommands(ctx context.Context, driveId int) ([]models.DriveCommand, error) {
+	stmt := "SELECT " + driveCommandColumns + " FROM drive_command WHERE drive_id=@driveId AND status=@status ORDER BY id"
+	args := pgx.NamedArgs{"driveId": driveId, "status": string(models.CommandStatusPending)}
+	rows, err := r.pool.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	cmds := make([]models.DriveCommand, 0)
+	for rows.Next() {
+		cmd, err := scanDriveCommand(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return cmds, nil
+}
+
+// AcknowledgeDriveCommand marks the pending command with identifier `id` as
+// acknowledged at time `at`.
+func (r *PgRepository) AcknowledgeDriveCommand(ctx context.Context, id int, at time.Time) error {
+	stmt := "UPDATE drive_command SET status=@acknowledged, acknowledged_at=@at WHERE id=@id AND status=@pending"
+	args := pgx.NamedArgs{
+		"id":           id,
+		"at":           at,
+		"acknowledged": string(models.CommandStatusAcknowledged),
+		"pending":      string(models.CommandStatusPending),
+	}
+	tag, err := r.pool.Exec(ctx, stmt, args)
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrCommandNotPending
+	}
+
+	return nil
+}
+
+// CompleteDriveCommand sets the status and result of the command with
+// identifier `id` which completed at time `at`.
+func (r *PgRepository) CompleteDriveCommand(ctx context.Context, id int, status models.DriveCommandStatus, result string, at time.Time) error {
+	stmt := "UPDATE drive_command SET status=@status, result=@result, completed_at=@at WHERE id=@id"
+	args := pgx.NamedArgs{"id": id, "status": string(status), "result": result, "at": at}
+	tag, err := r.pool.Exec(ctx, stmt, args)
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrCommandNotFound
+	}
+
+	return nil
+}
+
+// ListenDriveCommands calls `notify` whenever a command is added for the drive
+// with identifier `driveId`. A connection is held for as long as it listens.
+func (r *PgRepository) ListenDriveCommands(ctx context.Context, driveId int, notify func()) error {
+	pooled, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	// The connection is taken out of the pool so that it isn't reused while
+	// still listening on the channel.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	channel := pgx.Identifier{DriveCommandChannel(driveId)}.Sanitize()
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		notify()
+	}
+}
+
+func scanDriveCommand(row pgx.Row) (models.DriveCommand, error) {
+	var cmd models.DriveCommand
+	var command, status string
+	var args *string
+	var acknowledgedAt, completedAt *time.Time
+	err := row.Scan(&cmd.Id, &cmd.DriveId, &command, &args, &status, &cmd.Result, &cmd.CreatedAt, &acknowledgedAt, &completedAt)
+	if err != nil {
+		return cmd, err
+	}
+
+	cmd.Command = models.DriveCommandType(command)
+	cmd.Status = models.DriveCommandStatus(status)
+	if args != nil {
+		cmd.Args = json.RawMessage(*args)
+	}
+	if acknowledgedAt != nil {
+		cmd.AcknowledgedAt = *acknowledgedAt
+	}
+	if completedAt != nil {
+		cmd.CompletedAt = *completedAt
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,511 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// MemoryRepository is a Repository implementation that keeps everything in
+// memory. It is intended for testing and nothing is persisted.
+type MemoryRepository struct {
+	mu             sync.RWMutex
+	drives         map[int]models.OpticalDrive
+	copyOperations map[int]models.CopyOperation
+	discs          map[int]models.Disc
+	commands       map[int]models.DriveCommand
+	leases         map[int]models.DriveLease
+	samples        map[int][]models.CopyProgressSample
+
+	// The last identifier assigned to each type.
+	lastDriveId         int
+	lastCopyOperationId int
+	lastDiscId          int
+	lastCommandId       int
+}
+
+// NewMemoryRepository creates an empty in-memory repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		drives:         make(map[int]models.OpticalDrive),
+		copyOperations: make(map[int]models.CopyOperation),
+		discs:          make(map[int]models.Disc),
+		commands:       make(map[int]models.DriveCommand),
+		leases:         make(map[int]models.DriveLease),
+		samples:        make(map[int][]models.CopyProgressSample),
+	}
+}
+
+// Close does nothing since there aren't any resources to release.
+func (r *MemoryRepository) Close() {}
+
+// Ping always succeeds since the data is in memory.
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// UpsertOpticalDrive adds or updates the entry for the drive with the serial
+// number of `od` and updates its Id with the identifier assigned to it.
+func (r *MemoryRepository) UpsertOpticalDrive(ctx context.Context, od *models.OpticalDrive) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	od.Id = 0
+	for id, drive := range r.drives {
+		if drive.SerialNumber == od.SerialNumber {
+			od.Id = id
+			break
+		}
+	}
+
+	if od.Id == 0 {
+		r.lastDriveId++
+		od.Id = r.lastDriveId
+	}
+
+	r.drives[od.Id] = models.OpticalDrive{
+		Id:           od.Id,
+		Name:         od.Name,
+		Host:         od.Host,
+		DeviceName:   od.DeviceName,
+		SerialNumber: od.SerialNumber,
+		LastSeen:     od.LastSeen,
+		Online:       od.Online,
+	}
+	return nil
+}
+
+// HeartbeatOpticalDrive marks the drive with identifier `id` as online and sets
+// its last seen time to `seen`.
+func (r *MemoryRepository) HeartbeatOpticalDrive(ctx context.Context, id int, seen time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	drive, ok := r.drives[id]
+	if !ok {
+		return ErrDriveNotFound
+	}
+
+	drive.LastSeen = seen
+	drive.Online = true
+	r.drives[id] = drive
+	return nil
+}
+
+// SetOpticalDriveOffline marks the drive with identifier `id` as offline.
+func (r *MemoryRepository) SetOpticalDriveOffline(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	drive, ok := r.drives[id]
+	if !ok {
+		return ErrDriveNotFound
+	}
+
+	drive.Online = false
+	r.drives[id] = drive
+	return nil
+}
+
+// ListOpticalDrives gets all of the drives ordered by identifier.
+func (r *MemoryRepository) ListOpticalDrives(ctx context.Context) ([]models.OpticalDrive, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	drives := make([]models.OpticalDrive, 0, len(r.drives))
+	for _, drive := range r.drives {
+		drives = append(drives, drive)
+	}
+
+	slices.SortFunc(drives, func(a, b models.OpticalDrive) int {
+		return a.Id - b.Id
+	})
+
+	return drives, nil
+}
+
+// AcquireDriveLease takes or renews the lease on the drive with identifier
+// `driveId` for `holder` until `expiresAt`.
+func (r *MemoryRepository) AcquireDriveLease(ctx context.Context, driveId int, holder string, now, expiresAt time.Time) (models.DriveLease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lease, ok := r.leases[driveId]
+	switch {
+	case ok && lease.Holder == holder:
+		lease.ExpiresAt = expiresAt
+	case !ok || lease.ExpiresAt.Before(now):
+		lease = models.DriveLease{DriveId: driveId, Holder: holder, AcquiredAt: now, ExpiresAt: expiresAt}
+	default:
+		return lease, driveLeaseHeldError(lease)
+	}
+
+	r.leases[driveId] = lease
+	return lease, nil
+}
+
+// ReleaseDriveLease releases the lease on the drive with identifier `driveId`
+// if it is held by `holder`.
+func (r *MemoryRepository) ReleaseDriveLease(ctx context.Context, driveId int, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[driveId]; ok && lease.Holder == holder {
+		delete(r.leases, driveId)
+	}
+
+	return nil
+}
+
+// CreateCopyOperation adds the copy operation `op` and updates its Id with the
+// identifier assigned to it.
+func (r *MemoryRepository) CreateCopyOperation(ctx context.Context, op *models.CopyOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastCopyOperationId++
+	op.Id = r.lastCopyOperationId
+	r.copyOperations[op.Id] = cloneCopyOperation(*op)
+	return nil
+}
+
+// UpdateCopyOperation updates the stored copy operation `op`.
+func (r *MemoryRepository) UpdateCopyOperation(ctx context.Context, op models.CopyOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.copyOperations[op.Id]; !ok {
+		return ErrCopyOperationNotFound
+	}
+
+	r.copyOperations[op.Id] = cloneCopyOperation(op)
+	return nil
+}
+
+// GetCopyOperation gets the copy operation with identifier `id`.
+func (r *MemoryRepository) GetCopyOperation(ctx context.Context, id int) (models.CopyOperation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	op, ok := r.copyOperations[id]
+	if !ok {
+		return models.CopyOperation{}, ErrCopyOperationNotFound
+	}
+
+	return cloneCopyOperation(op), nil
+}
+
+// ListCopyOperations gets all of the copy operations for the drive with
+// identifier `driveId` ordered from newest to oldest.
+func (r *MemoryRepository) ListCopyOperations(ctx context.Context, driveId int) ([]models.CopyOperation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.listCopyOperations(func(op models.CopyOperation) bool {
+		return op.DriveId == driveId
+	}), nil
+}
+
+// ListCopyOperationsForDisc gets all of the copy operations of the disc with
+// identifier `discId` ordered from newest to oldest.
+func (r *MemoryRepository) ListCopyOperationsForDisc(ctx context.Context, discId int) ([]models.CopyOperation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.listCopyOperations(func(op models.CopyOperation) bool {
+		return op.DiscId == discId
+	}), nil
+}
+
+// ListCopyOperationsEndedBefore gets the copy operations for the drive with
+// identifier `driveId` in state `state` that ended before `before` ordered from
+// oldest to newest.
+func (r *MemoryRepository) ListCopyOperationsEndedBefore(ctx context.Context, driveId int, state models.CopyOperationState, before time.Time) ([]models.CopyOperation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ops := r.listCopyOperations(func(op models.CopyOperation) bool {
+		return op.DriveId == driveId && op.State == state && !op.EndedAt.IsZero() && op.EndedAt.Before(before)
+	})
+	slices.Reverse(ops)
+	return ops, nil
+}
+
+// DeleteCopyOperation deletes the copy operation with identifier `id`.
+func (r *MemoryRepository) DeleteCopyOperation(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.copyOperations[id]; !ok {
+		return ErrCopyOperationNotFound
+	}
+
+	delete(r.copyOperations, id)
+	delete(r.samples, id)
+	return nil
+}
+
+// AddCopyProgressSample adds progress sample `sample` to its copy operation.
+func (r *MemoryRepository) AddCopyProgressSample(ctx context.Context, sample models.CopyProgressSample) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.copyOperations[sample.CopyOperationId]; !ok {
+		return ErrCopyOperationNotFound
+	}
+
+	r.samples[sample.CopyOperationId] = append(r.samples[sample.CopyOperationId], sample)
+	return nil
+}
+
+// ListCopyProgressSamples gets the progress samples of the copy operation with
+// identifier `id` ordered from oldest to newest.
+func (r *MemoryRepository) ListCopyProgressSamples(ctx context.Context, id int) ([]models.CopyProgressSample, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	samples := slices.Clone(r.samples[id])
+	if samples == nil {
+		samples = make([]models.CopyProgressSample, 0)
+	}
+
+	slices.SortStableFunc(samples, func(a, b models.CopyProgressSample) int {
+		return a.SampledAt.Compare(b.SampledAt)
+	})
+
+	return samples, nil
+}
+
+// ReplaceCopyProgressSamples replaces the progress samples of the copy
+// operation with identifier `id` with `samples`.
+func (r *MemoryRepository) ReplaceCopyProgressSamples(ctx context.Context, id int, samples []models.CopyProgressSample) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.copyOperations[id]; !ok {
+		return ErrCopyOperationNotFound
+	}
+
+	replaced := make([]models.CopyProgressSample, len(samples))
+	for i, sample := range samples {
+		sample.CopyOperationId = id
+		replaced[i] = sample
+	}
+
+	r.samples[id] = replaced
+	return nil
+}
+
+// listCopyOperations gets the copy operations matching `match` ordered from
+// newest to oldest. The caller must hold the lock.
+func (r *MemoryRepository) listCopyOperations(match func(models.CopyOperation) bool) []models.CopyOperation {
+	ops := make([]models.CopyOperation, 0)
+	for _, op := range r.copyOperations {
+		if match(op) {
+			ops = append(ops, cloneCopyOperation(op))
+		}
+	}
+
+	slices.SortFunc(ops, func(a, b models.CopyOperation) int {
+		return b.Id - a.Id
+	})
+
+	return ops
+}
+
+// CreateDisc adds the disc `disc` along with its titles and streams and
+// updates its Id with the identifier assigned to it.
+func (r *MemoryRepository) CreateDisc(ctx context.Context, disc *models.Disc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastDiscId++
+	disc.Id = r.lastDiscId
+	r.discs[disc.Id] = cloneDisc(*disc)
+	return nil
+}
+
+// GetDisc gets the disc with identifier `id` including its titles and streams.
+func (r *MemoryRepository) GetDisc(ctx context.Context, id int) (models.Disc, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	disc, ok := r.discs[id]
+	if !ok {
+		return models.Disc{}, ErrDiscNotFound
+	}
+
+	return cloneDisc(disc), nil
+}
+
+// GetDiscByFingerprint gets the oldest disc with fingerprint `fingerprint`
+// including its titles and streams.
+func (r *MemoryRepository) GetDiscByFingerprint(ctx context.Context, fingerprint string) (models.Disc, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *models.Disc
+	for _, disc := range r.discs {
+		if disc.Fingerprint == fingerprint && (found == nil || disc.Id < found.Id) {
+			found = &disc
+		}
+	}
+
+	if found == nil {
+		return models.Disc{}, ErrDiscNotFound
+	}
+
+	return cloneDisc(*found), nil
+}
+
+// ListDiscs gets all of the discs ordered from newest to oldest.
+func (r *MemoryRepository) ListDiscs(ctx context.Context) ([]models.Disc, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	discs := make([]models.Disc, 0, len(r.discs))
+	for _, disc := range r.discs {
+		disc.Titles = nil
+		discs = append(discs, disc)
+	}
+
+	slices.SortFunc(discs, func(a, b models.Disc) int {
+		return b.Id - a.Id
+	})
+
+	return discs, nil
+}
+
+// CreateDriveCommand adds the pending command `cmd` and updates its Id with
+// the identifier assigned to it.
+func (r *MemoryRepository) CreateDriveCommand(ctx context.Context, cmd *models.DriveCommand) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastCommandId++
+	cmd.Id = r.lastCommandId
+	cmd.Status = models.CommandStatusPending
+	stored := *cmd
+	stored.Args = slices.Clone(cmd.Args)
+	r.commands[cmd.Id] = stored
+	return nil
+}
+
+// GetDriveCommand gets the command with identifier `id`. Returns
+// ErrCommandNotFound if it doesn't exist.
+func (r *MemoryRepository) GetDriveCommand(ctx context.Context, id int) (models.DriveCommand, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cmd, ok := r.commands[id]
+	if !ok {
+		return cmd, ErrCommandNotFound
+	}
+
+	return cmd, nil
+}
+
+// ListPendingDriveCommands gets the pending commands for the drive with
+// identifier `driveId` ordered from oldest to newest.
+func (r *MemoryRepository) ListPendingDriveCommands(ctx context.Context, driveId int) ([]models.DriveCommand, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cmds := make([]models.DriveCommand, 0)
+	for _, cmd := range r.commands {
+		if cmd.DriveId == driveId && cmd.Status == models.CommandStatusPending {
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	slices.SortFunc(cmds, func(a, b models.DriveCommand) int {
+		return a.Id - b.Id
+	})
+
+	return cmds, nil
+}
+
+// AcknowledgeDriveCommand marks the pending command with identifier `id` as
+// acknowledged at time `at`.
+func (r *MemoryRepository) AcknowledgeDriveCommand(ctx context.Context, id int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cmd, ok := r.commands[id]
+	if !ok || cmd.Status != models.CommandStatusPending {
+		return ErrCommandNotPending
+	}
+
+	cmd.Status = models.CommandStatusAcknowledged
+	cmd.AcknowledgedAt = at
+	r.commands[id] = cmd
+	return nil
+}
+
+// CompleteDriveCommand sets the status and result of the command with
+// identifier `id` which completed at time `at`.
+func (r *MemoryRepository) CompleteDriveCommand(ctx context.Context, id int, status models.DriveCommandStatus, result string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cmd, ok := r.commands[id]
+	if !ok {
+		return ErrCommandNotFound
+	}
+
+	cmd.Status = status
+	cmd.Result = result
+	cmd.CompletedAt = at
+	r.commands[id] = cmd
+	return nil
+}
+
+// cloneCopyOperation returns a copy of `op` that doesn't share any slices with
+// it so that callers can't modify the stored operation.
+func cloneCopyOperation(op models.CopyOperation) models.CopyOperation {
+	op.Warnings = slices.Clone(op.Warnings)
+	op.ReadErrors = slices.Clone(op.ReadErrors)
+	op.Manifest = slices.Clone(op.Manifest)
+	return op
+}
+
+// cloneDisc returns a copy of `disc` that doesn't share any titles or streams
+// with it so that callers can't modify the stored disc.
+func cloneDisc(disc models.Disc) models.Disc {
+	disc.Titles = slices.Clone(disc.Titles)
+	for i := range disc.Titles {
+		disc.Titles[i].Streams = slices.Clone(disc.Titles[i].Streams)
+	}
+	return disc
+}
@@ -0,0 +1,366 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The migrations for each supported database are stored in a directory named
+// after the database's dialect. Each dialect must contain the same set of
+// migrations so that the schemas are kept in sync.
+//
+//go:embed migrations
+var migrationFS embed.FS
+
+// Dialects of SQL supported by the migrations.
+const (
+	DialectPostgres = "postgres"
+	DialectSqlite   = "sqlite"
+)
+
+// migrationLockKey is the key of the advisory lock held while migrations are
+// applied so that multiple services starting at the same time don't try to
+// apply the same migrations.
+const migrationLockKey int64 = 0x61727469652d6d67 // "artie-mg"
+
+// migrationFileRegex matches the file names of the migrations which take the
+// form VERSION_NAME.(up|down).sql (e.g. 0001_optical_drive.up.sql).
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change to the database schema.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is the status of a migration in the database.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// migrationSession applies and reverts migrations on a database. The session
+// is expected to hold whatever lock the database uses to prevent migrations
+// from being applied concurrently.
+type migrationSession interface {
+	// appliedMigrations returns the time each migration in the database was
+	// applied keyed by version.
+	appliedMigrations(ctx context.Context) (map[int]time.Time, error)
+
+	// applyMigration runs the up migration and records it as applied.
+	applyMigration(ctx context.Context, migration Migration) error
+
+	// revertMigration runs the down migration and removes its record.
+	revertMigration(ctx context.Context, migration Migration) error
+}
+
+// Migrations returns the migrations for SQL dialect `dialect` embedded in the
+// application ordered by version.
+func Migrations(dialect string) ([]Migration, error) {
+	dir := "migrations/" + dialect
+	entries, err := fs.ReadDir(migrationFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s migrations: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(m[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration version %d used by %s and %s", version, migration.Name, m[2])
+		}
+
+		bs, err := migrationFS.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		if m[3] == "up" {
+			migration.Up = string(bs)
+		} else {
+			migration.Down = string(bs)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s is missing its up or down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// migrateUp applies all of the migrations in `migrations` that haven't been
+// applied. Returns the number of migrations applied.
+func migrateUp(ctx context.Context, migrations []Migration, s migrationSession) (int, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		slog.Info("Applying migration.", "version", migration.Version, "name", migration.Name)
+		if err := s.applyMigration(ctx, migration); err != nil {
+			return count, fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// migrateDown reverts the most recently applied migration in `migrations`.
+// Returns false if there weren't any migrations to revert.
+func migrateDown(ctx context.Context, migrations []Migration, s migrationSession) (bool, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		slog.Info("Reverting migration.", "version", migration.Version, "name", migration.Name)
+		if err := s.revertMigration(ctx, migration); err != nil {
+			return false, fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// migrationStatus returns the status of every migration in `migrations`.
+func migrationStatus(ctx context.Context, migrations []Migration, s migrationSession) ([]MigrationStatus, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		status = append(status, MigrationStatus{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return status, nil
+}
+
+// MigrateUp applies all migrations that haven't been applied to the database.
+// Returns the number of migrations applied.
+func (r *PgRepository) MigrateUp(ctx context.Context) (int, error) {
+	migrations, err := Migrations(DialectPostgres)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = r.withMigrationLock(ctx, func(s *pgMigrationSession) error {
+		count, err = migrateUp(ctx, migrations, s)
+		return err
+	})
+
+	return count, err
+}
+
+// MigrateDown reverts the most recently applied migration. Returns false if
+// there weren't any migrations to revert.
+func (r *PgRepository) MigrateDown(ctx context.Context) (bool, error) {
+	migrations, err := Migrations(DialectPostgres)
+	if err != nil {
+		return false, err
+	}
+
+	reverted := false
+	err = r.withMigrationLock(ctx, func(s *pgMigrationSession) error {
+		reverted, err = migrateDown(ctx, migrations, s)
+		return err
+	})
+
+	return reverted, err
+}
+
+// GetMigrationStatus returns the status of every migration embedded in the
+// application ordered by version.
+func (r *PgRepository) GetMigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := Migrations(DialectPostgres)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	s := &pgMigrationSession{conn: conn.Conn()}
+	if err := s.createMigrationTable(ctx); err != nil {
+		return nil, err
+	}
+
+	return migrationStatus(ctx, migrations, s)
+}
+
+// withMigrationLock calls `fn` with a session whose connection holds the
+// migration advisory lock. The lock is held for the session, rather than a
+// transaction, so that each migration can be applied in its own transaction.
+func (r *PgRepository) withMigrationLock(ctx context.Context, fn func(s *pgMigrationSession) error) error {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// A new context is used so that the lock is released even if `ctx`
+		// was cancelled.
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			slog.Error("Failed to release migration lock.", "error", err)
+		}
+	}()
+
+	s := &pgMigrationSession{conn: conn.Conn()}
+	if err := s.createMigrationTable(ctx); err != nil {
+		return err
+	}
+
+	return fn(s)
+}
+
+// pgMigrationSession is the migrationSession for PostgreSQL databases.
+type pgMigrationSession struct {
+	conn *pgx.Conn
+}
+
+func (s *pgMigrationSession) createMigrationTable(ctx context.Context) error {
+	stmt := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+	if _, err := s.conn.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return nil
+}
+
+func (s *pgMigrationSession) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := s.conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return applied, nil
+}
+
+func (s *pgMigrationSession) applyMigration(ctx context.Context, migration Migration) error {
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migration.Up); err != nil {
+			return err
+		}
+		stmt := "INSERT INTO schema_migrations (version, name) VALUES (@version, @name)"
+		args := pgx.NamedArgs{"version": migration.Version, "name": migration.Name}
+		_, err := tx.Exec(ctx, stmt, args)
+		return err
+	})
+}
+
+func (s *pgMigrationSession) revertMigration(ctx context.Context, migration Migration) error {
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migration.Down); err != nil {
+			return err
+		}
+		stmt := "DELETE FROM schema_migrations WHERE version=@version"
+		args := pgx.NamedArgs{"version": migration.Version}
+		_, err := tx.Exec(ctx, stmt, args)
+		return err
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	for _, dialect := range []string{DialectPostgres, DialectSqlite} {
+		migrations, err := Migrations(dialect)
+		if err != nil {
+			t.Errorf("Migrations(%s) returned an error: %s", dialect, err)
+			continue
+		}
+
+		if len(migrations) == 0 {
+			t.Errorf("Expected at least one %s migration", dialect)
+		}
+
+		for i, migration := range migrations {
+			if migration.Version != i+1 {
+				t.Errorf("%s migration %s has version %d, expected %d", dialect, migration.Name, migration.Version, i+1)
+			}
+
+			if migration.Up == "" {
+				t.Errorf("%s migration %d_%s has an empty up migration", dialect, migration.Version, migration.Name)
+			}
+
+			if migration.Down == "" {
+				t.Errorf("%s migration %d_%s has an empty down migration", dialect, migration.Version, migration.Name)
+			}
+		}
+	}
+}
+
+// TestMigrationsInSync checks that every dialect has the same migrations so
+// that the schemas don't drift apart.
+func TestMigrationsInSync(t *testing.T) {
+	pg, err := Migrations(DialectPostgres)
+	if err != nil {
+		t.Fatal("Migrations returned an error:", err)
+	}
+
+	sqlite, err := Migrations(DialectSqlite)
+	if err != nil {
+		t.Fatal("Migrations returned an error:", err)
+	}
+
+	if len(pg) != len(sqlite) {
+		t.Fatalf("Have %d postgres migrations and %d sqlite migrations", len(pg), len(sqlite))
+	}
+
+	for i := range pg {
+		if pg[i].Version != sqlite[i].Version || pg[i].Name != sqlite[i].Name {
+			t.Errorf("Migration mismatch: postgres %d_%s, sqlite %d_%s",
+				pg[i].Version, pg[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE optical_drive;
@@ -0,0 +1,6 @@
+-- Databases used before migrations were added already have this table, which
+-- was created by hand, so it's only created if it doesn't exist.
+CREATE TABLE IF NOT EXISTS optical_drive (
+    id            SERIAL PRIMARY KEY,
+    serial_number TEXT NOT NULL UNIQUE
+);
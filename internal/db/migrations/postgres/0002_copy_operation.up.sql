@@ -0,0 +1,16 @@
+CREATE TABLE copy_operation (
+    id               SERIAL PRIMARY KEY,
+    drive_id         INTEGER NOT NULL REFERENCES optical_drive (id),
+    disc_label       TEXT NOT NULL DEFAULT '',
+    output_dir       TEXT NOT NULL DEFAULT '',
+    state            TEXT NOT NULL,
+    started_at       TIMESTAMPTZ NOT NULL,
+    ended_at         TIMESTAMPTZ,
+    warnings         TEXT[] NOT NULL DEFAULT '{}',
+    failure_reason   TEXT NOT NULL DEFAULT '',
+    damaged          BOOLEAN NOT NULL DEFAULT FALSE,
+    read_error_count INTEGER NOT NULL DEFAULT 0,
+    read_errors      JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX copy_operation_drive_id_idx ON copy_operation (drive_id);
@@ -0,0 +1,7 @@
+CREATE TABLE disc (
+    id          SERIAL PRIMARY KEY,
+    label       TEXT NOT NULL DEFAULT '',
+    name        TEXT NOT NULL DEFAULT '',
+    volume_name TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
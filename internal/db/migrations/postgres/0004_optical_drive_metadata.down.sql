@@ -0,0 +1,6 @@
+ALTER TABLE optical_drive
+    DROP COLUMN name,
+    DROP COLUMN host,
+    DROP COLUMN device_name,
+    DROP COLUMN last_seen,
+    DROP COLUMN online;
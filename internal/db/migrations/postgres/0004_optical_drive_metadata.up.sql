@@ -0,0 +1,6 @@
+ALTER TABLE optical_drive
+    ADD COLUMN name        TEXT NOT NULL DEFAULT '',
+    ADD COLUMN host        TEXT NOT NULL DEFAULT '',
+    ADD COLUMN device_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_seen   TIMESTAMPTZ,
+    ADD COLUMN online      BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+DROP TRIGGER drive_command_notify ON drive_command;
+DROP FUNCTION notify_drive_command();
+DROP TABLE drive_command;
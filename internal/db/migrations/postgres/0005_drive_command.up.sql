@@ -0,0 +1,25 @@
+CREATE TABLE drive_command (
+    id              SERIAL PRIMARY KEY,
+    drive_id        INTEGER NOT NULL REFERENCES optical_drive (id),
+    command         TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    result          TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    acknowledged_at TIMESTAMPTZ,
+    completed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX drive_command_pending_idx ON drive_command (drive_id) WHERE status = 'pending';
+
+-- Notify the copy service managing the drive whenever a command is added so
+-- that clients only need to insert the row.
+CREATE FUNCTION notify_drive_command() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('artie_drive_' || NEW.drive_id, NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER drive_command_notify
+    AFTER INSERT ON drive_command
+    FOR EACH ROW EXECUTE FUNCTION notify_drive_command();
@@ -0,0 +1,4 @@
+ALTER TABLE copy_operation DROP COLUMN disc_id;
+DROP TABLE disc_stream;
+DROP TABLE disc_title;
+ALTER TABLE disc DROP COLUMN fingerprint;
@@ -0,0 +1 @@
+DROP TABLE drive_lease;
@@ -0,0 +1,9 @@
+-- A drive can only be managed by one copy service at a time. The service
+-- holding the lease renews it with its heartbeat so that the lease expires if
+-- the service dies without releasing it.
+CREATE TABLE drive_lease (
+    drive_id    INTEGER PRIMARY KEY REFERENCES optical_drive (id),
+    holder      TEXT NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
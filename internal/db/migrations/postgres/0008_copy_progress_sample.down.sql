@@ -0,0 +1 @@
+DROP TABLE copy_progress_sample;
@@ -0,0 +1,9 @@
+CREATE TABLE copy_progress_sample (
+    copy_operation_id INTEGER NOT NULL REFERENCES copy_operation (id) ON DELETE CASCADE,
+    sampled_at        TIMESTAMPTZ NOT NULL,
+    title             INTEGER NOT NULL,
+    bytes             BIGINT NOT NULL,
+    percent           DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX copy_progress_sample_operation_idx ON copy_progress_sample (copy_operation_id, sampled_at);
@@ -0,0 +1 @@
+ALTER TABLE copy_operation DROP COLUMN manifest;
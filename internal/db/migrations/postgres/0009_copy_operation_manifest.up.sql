@@ -0,0 +1 @@
+ALTER TABLE copy_operation ADD COLUMN manifest JSONB;
@@ -0,0 +1 @@
+ALTER TABLE drive_command ADD COLUMN args JSONB;
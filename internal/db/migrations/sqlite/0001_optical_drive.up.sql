@@ -0,0 +1,4 @@
+CREATE TABLE optical_drive (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    serial_number TEXT NOT NULL UNIQUE
+);
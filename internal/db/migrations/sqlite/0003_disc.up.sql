@@ -0,0 +1,7 @@
+CREATE TABLE disc (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    label       TEXT NOT NULL DEFAULT '',
+    name        TEXT NOT NULL DEFAULT '',
+    volume_name TEXT NOT NULL DEFAULT '',
+    created_at  TEXT NOT NULL
+);
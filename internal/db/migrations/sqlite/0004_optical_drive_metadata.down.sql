@@ -0,0 +1,5 @@
+ALTER TABLE optical_drive DROP COLUMN name;
+ALTER TABLE optical_drive DROP COLUMN host;
+ALTER TABLE optical_drive DROP COLUMN device_name;
+ALTER TABLE optical_drive DROP COLUMN last_seen;
+ALTER TABLE optical_drive DROP COLUMN online;
@@ -0,0 +1,5 @@
+ALTER TABLE optical_drive ADD COLUMN name TEXT NOT NULL DEFAULT '';
+ALTER TABLE optical_drive ADD COLUMN host TEXT NOT NULL DEFAULT '';
+ALTER TABLE optical_drive ADD COLUMN device_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE optical_drive ADD COLUMN last_seen TEXT;
+ALTER TABLE optical_drive ADD COLUMN online INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+CREATE TABLE drive_command (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    drive_id        INTEGER NOT NULL REFERENCES optical_drive (id),
+    command         TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    result          TEXT NOT NULL DEFAULT '',
+    created_at      TEXT NOT NULL,
+    acknowledged_at TEXT,
+    completed_at    TEXT
+);
+
+CREATE INDEX drive_command_pending_idx ON drive_command (drive_id) WHERE status = 'pending';
@@ -0,0 +1,6 @@
+DROP INDEX copy_operation_disc_id_idx;
+ALTER TABLE copy_operation DROP COLUMN disc_id;
+DROP TABLE disc_stream;
+DROP TABLE disc_title;
+DROP INDEX disc_fingerprint_idx;
+ALTER TABLE disc DROP COLUMN fingerprint;
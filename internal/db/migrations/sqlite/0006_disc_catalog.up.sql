@@ -0,0 +1,33 @@
+ALTER TABLE disc ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX disc_fingerprint_idx ON disc (fingerprint);
+
+CREATE TABLE disc_title (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    disc_id       INTEGER NOT NULL REFERENCES disc (id) ON DELETE CASCADE,
+    title_index   INTEGER NOT NULL,
+    name          TEXT NOT NULL DEFAULT '',
+    duration      INTEGER NOT NULL DEFAULT 0,
+    size_bytes    INTEGER NOT NULL DEFAULT 0,
+    chapter_count INTEGER NOT NULL DEFAULT 0,
+    segments_map  TEXT NOT NULL DEFAULT '',
+    source_file   TEXT NOT NULL DEFAULT '',
+    UNIQUE (disc_id, title_index)
+);
+
+CREATE TABLE disc_stream (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    title_id     INTEGER NOT NULL REFERENCES disc_title (id) ON DELETE CASCADE,
+    stream_index INTEGER NOT NULL,
+    type         TEXT NOT NULL DEFAULT '',
+    codec        TEXT NOT NULL DEFAULT '',
+    lang_code    TEXT NOT NULL DEFAULT '',
+    name         TEXT NOT NULL DEFAULT '',
+    UNIQUE (title_id, stream_index)
+);
+
+-- SQLite can't drop a column that references another table so the reference
+-- to disc isn't enforced.
+ALTER TABLE copy_operation ADD COLUMN disc_id INTEGER;
+
+CREATE INDEX copy_operation_disc_id_idx ON copy_operation (disc_id);
@@ -0,0 +1 @@
+ALTER TABLE drive_command DROP COLUMN args;
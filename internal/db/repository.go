@@ -0,0 +1,280 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+var (
+	ErrDriveNotFound         = errors.New("optical drive not found")
+	ErrCopyOperationNotFound = errors.New("copy operation not found")
+	ErrDiscNotFound          = errors.New("disc not found")
+	ErrCommandNotFound       = errors.New("drive command not found")
+	ErrCommandNotPending     = errors.New("drive command not pending")
+	ErrDriveLeaseHeld        = errors.New("optical drive is held by another service")
+)
+
+// DriveRepository stores optical drive information.
+type DriveRepository interface {
+	// UpsertOpticalDrive adds or updates the entry for the drive with the
+	// serial number of `od` and updates its Id with the identifier assigned
+	// to it. The drive's state and disc label aren't stored since they are
+	// only meaningful to the running service.
+	UpsertOpticalDrive(ctx context.Context, od *models.OpticalDrive) error
+
+	// HeartbeatOpticalDrive marks the drive with identifier `id` as online
+	// and sets its last seen time to `seen`. Returns ErrDriveNotFound if it
+	// doesn't exist.
+	HeartbeatOpticalDrive(ctx context.Context, id int, seen time.Time) error
+
+	// SetOpticalDriveOffline marks the drive with identifier `id` as offline.
+	// Returns ErrDriveNotFound if it doesn't exist.
+	SetOpticalDriveOffline(ctx context.Context, id int) error
+
+	// ListOpticalDrives gets all of the drives ordered by identifier.
+	ListOpticalDrives(ctx context.Context) ([]models.OpticalDrive, error)
+
+	// AcquireDriveLease takes or renews the lease on the drive with
+	// identifier `driveId` for `holder` until `expiresAt`. The lease can only
+	// be taken if it isn't held or has expired as of `now`. If another holder
+	// has the lease, their lease is returned along with an error wrapping
+	// ErrDriveLeaseHeld that names them.
+	AcquireDriveLease(ctx context.Context, driveId int, holder string, now, expiresAt time.Time) (models.DriveLease, error)
+
+	// ReleaseDriveLease releases the lease on the drive with identifier
+	// `driveId` if it is held by `holder`.
+	ReleaseDriveLease(ctx context.Context, driveId int, holder string) error
+}
+
+// driveLeaseHeldError returns the error reported when `lease` is held by
+// another service.
+func driveLeaseHeldError(lease models.DriveLease) error {
+	return fmt.Errorf("%w: %s until %s", ErrDriveLeaseHeld, lease.Holder, lease.ExpiresAt.Format(time.RFC3339))
+}
+
+// CopyOperationRepository stores copy operations.
+type CopyOperationRepository interface {
+	// CreateCopyOperation adds the copy operation `op` and updates its Id
+	// with the identifier assigned to it.
+	CreateCopyOperation(ctx context.Context, op *models.CopyOperation) error
+
+	// UpdateCopyOperation updates the stored copy operation `op`. Returns
+	// ErrCopyOperationNotFound if it doesn't exist.
+	UpdateCopyOperation(ctx context.Context, op models.CopyOperation) error
+
+	// GetCopyOperation gets the copy operation with identifier `id`. Returns
+	// ErrCopyOperationNotFound if it doesn't exist.
+	GetCopyOperation(ctx context.Context, id int) (models.CopyOperation, error)
+
+	// ListCopyOperations gets all of the copy operations for the drive with
+	// identifier `driveId` ordered from newest to oldest.
+	ListCopyOperations(ctx context.Context, driveId int) ([]models.CopyOperation, error)
+
+	// ListCopyOperationsForDisc gets all of the copy operations of the disc
+	// with identifier `discId` ordered from newest to oldest.
+	ListCopyOperationsForDisc(ctx context.Context, discId int) ([]models.CopyOperation, error)
+
+	// ListCopyOperationsEndedBefore gets the copy operations for the drive
+	// with identifier `driveId` in state `state` that ended before `before`
+	// ordered from oldest to newest.
+	ListCopyOperationsEndedBefore(ctx context.Context, driveId int, state models.CopyOperationState, before time.Time) ([]models.CopyOperation, error)
+
+	// DeleteCopyOperation deletes the copy operation with identifier `id`.
+	// Returns ErrCopyOperationNotFound if it doesn't exist.
+	DeleteCopyOperation(ctx context.Context, id int) error
+
+	// AddCopyProgressSample adds progress sample `sample` to its copy
+	// operation.
+	AddCopyProgressSample(ctx context.Context, sample models.CopyProgressSample) error
+
+	// ListCopyProgressSamples gets the progress samples of the copy operation
+	// with identifier `id` ordered from oldest to newest.
+	ListCopyProgressSamples(ctx context.Context, id int) ([]models.CopyProgressSample, error)
+
+	// ReplaceCopyProgressSamples replaces the progress samples of the copy
+	// operation with identifier `id` with `samples`.
+	ReplaceCopyProgressSamples(ctx context.Context, id int, samples []models.CopyProgressSample) error
+}
+
+// DiscRepository stores information about the discs that have been inserted
+// into the optical drives.
+type DiscRepository interface {
+	// CreateDisc adds the disc `disc` along with its titles and streams and
+	// updates its Id with the identifier assigned to it.
+	CreateDisc(ctx context.Context, disc *models.Disc) error
+
+	// GetDisc gets the disc with identifier `id` including its titles and
+	// streams. Returns ErrDiscNotFound if it doesn't exist.
+	GetDisc(ctx context.Context, id int) (models.Disc, error)
+
+	// GetDiscByFingerprint gets the oldest disc with fingerprint
+	// `fingerprint` including its titles and streams. Returns
+	// ErrDiscNotFound if it doesn't exist.
+	GetDiscByFingerprint(ctx context.Context, fingerprint string) (models.Disc, error)
+
+	// ListDiscs gets all of the discs ordered from newest to oldest. The
+	// titles aren't included.
+	ListDiscs(ctx context.Context) ([]models.Disc, error)
+}
+
+// CommandRepository stores the commands sent to the drives.
+type CommandRepository interface {
+	// CreateDriveCommand adds the pending command `cmd` and updates its Id
+	// with the identifier assigned to it.
+	CreateDriveCommand(ctx context.Context, cmd *models.DriveCommand) error
+
+	// GetDriveCommand gets the command with identifier `id`. Returns
+	// ErrCommandNotFound if it doesn't exist.
+	GetDriveCommand(ctx context.Context, id int) (models.DriveCommand, error)
+
+	// ListPendingDriveCommands gets the pending commands for the drive with
+	// identifier `driveId` ordered from oldest to newest.
+	ListPendingDriveCommands(ctx context.Context, driveId int) ([]models.DriveCommand, error)
+
+	// AcknowledgeDriveCommand marks the pending command with identifier `id`
+	// as acknowledged at time `at`. Returns ErrCommandNotPending if it isn't
+	// pending so that a command is only ever handled once.
+	AcknowledgeDriveCommand(ctx context.Context, id int, at time.Time) error
+
+	// CompleteDriveCommand sets the status and result of the command with
+	// identifier `id` which completed at time `at`. Returns
+	// ErrCommandNotFound if it doesn't exist.
+	CompleteDriveCommand(ctx context.Context, id int, status models.DriveCommandStatus, result string, at time.Time) error
+}
+
+// CommandListener is implemented by repositories that can notify the service
+// when a command is added instead of it having to poll for them.
+type CommandListener interface {
+	// ListenDriveCommands calls `notify` whenever a command is added for the
+	// drive with identifier `driveId`. It blocks until `ctx` is done or the
+	// connection used to listen fails.
+	ListenDriveCommands(ctx context.Context, driveId int, notify func()) error
+}
+
+// DriveCommandChannel returns the name of the notification channel used to
+// signal that a command was added for the drive with identifier `driveId`.
+func DriveCommandChannel(driveId int) string {
+	return fmt.Sprintf("artie_drive_%d", driveId)
+}
+
+// Repository provides access to all of the stored data.
+type Repository interface {
+	DriveRepository
+	CopyOperationRepository
+	DiscRepository
+	CommandRepository
+
+	// Ping checks that the repository's storage is reachable.
+	Ping(ctx context.Context) error
+
+	// Close releases the resources held by the repository.
+	Close()
+}
+
+// Migrator manages the schema of a database.
+type Migrator interface {
+	// MigrateUp applies all migrations that haven't been applied. Returns
+	// the number of migrations applied.
+	MigrateUp(ctx context.Context) (int, error)
+
+	// MigrateDown reverts the most recently applied migration. Returns false
+	// if there weren't any migrations to revert.
+	MigrateDown(ctx context.Context) (bool, error)
+
+	// GetMigrationStatus returns the status of every migration ordered by
+	// version.
+	GetMigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+}
+
+// Database is a Repository backed by a database whose schema is managed using
+// migrations.
+type Database interface {
+	Repository
+	Migrator
+}
+
+// Open opens the database specified by connection string `connStr`. The
+// backend is selected using the connection string's scheme: "sqlite:" selects
+// SQLite where the rest of the string is the path to the database file (e.g.
+// sqlite:/var/lib/artie/copy.db) and anything else is passed to PostgreSQL.
+func Open(ctx context.Context, connStr string) (Database, error) {
+	if path, ok := strings.CutPrefix(connStr, "sqlite:"); ok {
+		// Accept URL style connection strings (e.g. sqlite:///path/to/db).
+		path = strings.TrimPrefix(path, "//")
+		if path == "" {
+			return nil, errors.New("sqlite connection string missing database path")
+		}
+		return NewSqliteRepository(ctx, path)
+	}
+
+	return NewPgRepository(ctx, connStr)
+}
+
+// Backoff between attempts to reach the database at startup.
+const (
+	initialRetryDelay = time.Second
+	maxRetryDelay     = 30 * time.Second
+)
+
+// OpenWithRetry opens the database specified by connection string `connStr`
+// and waits for it to be reachable, retrying with exponential backoff until
+// `ctx` is done. An invalid connection string isn't retried.
+func OpenWithRetry(ctx context.Context, connStr string) (Database, error) {
+	repo, err := Open(ctx, connStr)
+	if err != nil {
+		return nil, err
+	}
+
+	delay := initialRetryDelay
+	for {
+		err := repo.Ping(ctx)
+		if err == nil {
+			return repo, nil
+		}
+
+		slog.Warn("Database unreachable. Retrying.", "delay", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			repo.Close()
+			return nil, fmt.Errorf("database unreachable: %w", err)
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
@@ -0,0 +1,703 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// testRepository runs the tests shared by all of the Repository
+// implementations against `repo`.
+func testRepository(t *testing.T, repo Repository) {
+	t.Run("OpticalDrive", func(t *testing.T) { testOpticalDrive(t, repo) })
+	t.Run("DriveLease", func(t *testing.T) { testDriveLease(t, repo) })
+	t.Run("CopyOperation", func(t *testing.T) { testCopyOperation(t, repo) })
+	t.Run("CopyProgressSample", func(t *testing.T) { testCopyProgressSample(t, repo) })
+	t.Run("Disc", func(t *testing.T) { testDisc(t, repo) })
+	t.Run("DriveCommand", func(t *testing.T) { testDriveCommand(t, repo) })
+}
+
+func testOpticalDrive(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	seen := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	od := models.OpticalDrive{
+		Name:         "Drive A",
+		Host:         "artie-01",
+		DeviceName:   "/dev/sr0",
+		SerialNumber: "4-8-15-16-23-42",
+		LastSeen:     seen,
+		Online:       true,
+	}
+	if err := repo.UpsertOpticalDrive(ctx, &od); err != nil {
+		t.Error("UpsertOpticalDrive returned an error:", err)
+		return
+	}
+
+	if od.Id == 0 {
+		t.Error("UpsertOpticalDrive didn't set the drive's id")
+	}
+
+	again := od
+	again.Id = 0
+	again.Name = "Drive B"
+	again.DeviceName = "/dev/sr1"
+	if err := repo.UpsertOpticalDrive(ctx, &again); err != nil {
+		t.Error("UpsertOpticalDrive returned an error:", err)
+		return
+	}
+
+	if again.Id != od.Id {
+		t.Errorf("UpsertOpticalDrive assigned %d to an existing drive, expected %d", again.Id, od.Id)
+	}
+
+	other := models.OpticalDrive{SerialNumber: "108"}
+	if err := repo.UpsertOpticalDrive(ctx, &other); err != nil {
+		t.Error("UpsertOpticalDrive returned an error:", err)
+		return
+	}
+
+	if other.Id == od.Id {
+		t.Error("UpsertOpticalDrive assigned the same id to different drives")
+	}
+
+	heartbeat := seen.Add(time.Minute)
+	if err := repo.HeartbeatOpticalDrive(ctx, other.Id, heartbeat); err != nil {
+		t.Error("HeartbeatOpticalDrive returned an error:", err)
+	}
+
+	if err := repo.SetOpticalDriveOffline(ctx, od.Id); err != nil {
+		t.Error("SetOpticalDriveOffline returned an error:", err)
+	}
+
+	if err := repo.HeartbeatOpticalDrive(ctx, -1, heartbeat); err != ErrDriveNotFound {
+		t.Errorf("HeartbeatOpticalDrive returned %v for a missing drive, expected ErrDriveNotFound", err)
+	}
+
+	if err := repo.SetOpticalDriveOffline(ctx, -1); err != ErrDriveNotFound {
+		t.Errorf("SetOpticalDriveOffline returned %v for a missing drive, expected ErrDriveNotFound", err)
+	}
+
+	drives, err := repo.ListOpticalDrives(ctx)
+	if err != nil {
+		t.Error("ListOpticalDrives returned an error:", err)
+		return
+	}
+
+	found := 0
+	for _, drive := range drives {
+		switch drive.Id {
+		case od.Id:
+			found++
+			if drive.Name != "Drive B" || drive.Host != "artie-01" || drive.DeviceName != "/dev/sr1" {
+				t.Errorf("Drive fields weren't updated: %+v", drive)
+			}
+			if !drive.LastSeen.Equal(seen) {
+				t.Errorf("Drive LastSeen = %s, expected %s", drive.LastSeen, seen)
+			}
+			if drive.Online {
+				t.Error("Drive is online after SetOpticalDriveOffline")
+			}
+		case other.Id:
+			found++
+			if !drive.LastSeen.Equal(heartbeat) {
+				t.Errorf("Drive LastSeen = %s, expected %s", drive.LastSeen, heartbeat)
+			}
+			if !drive.Online {
+				t.Error("Drive is offline after HeartbeatOpticalDrive")
+			}
+		}
+	}
+
+	if found != 2 {
+		t.Errorf("ListOpticalDrives returned %d of the 2 drives", found)
+	}
+}
+
+func testDriveLease(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	drive := models.OpticalDrive{SerialNumber: "drive-lease-drive"}
+	if err := repo.UpsertOpticalDrive(ctx, &drive); err != nil {
+		t.Error("UpsertOpticalDrive returned an error:", err)
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := fmt.Sprintf("host-a (pid %d)", now.UnixNano())
+	second := fmt.Sprintf("host-b (pid %d)", now.UnixNano())
+
+	lease, err := repo.AcquireDriveLease(ctx, drive.Id, first, now, now.Add(time.Minute))
+	if err != nil {
+		t.Error("AcquireDriveLease returned an error:", err)
+		return
+	}
+
+	if lease.Holder != first || !lease.AcquiredAt.Equal(now) || !lease.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("AcquireDriveLease returned %+v", lease)
+	}
+
+	lease, err = repo.AcquireDriveLease(ctx, drive.Id, second, now.Add(time.Second), now.Add(time.Minute))
+	if !errors.Is(err, ErrDriveLeaseHeld) {
+		t.Errorf("AcquireDriveLease returned %v for a held lease, expected ErrDriveLeaseHeld", err)
+	} else if lease.Holder != first || !strings.Contains(err.Error(), first) {
+		t.Errorf("AcquireDriveLease did not report the holder: %+v, %v", lease, err)
+	}
+
+	renewed, err := repo.AcquireDriveLease(ctx, drive.Id, first, now.Add(30*time.Second), now.Add(2*time.Minute))
+	if err != nil {
+		t.Error("AcquireDriveLease returned an error when renewing:", err)
+	} else if !renewed.AcquiredAt.Equal(now) || !renewed.ExpiresAt.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("AcquireDriveLease renewed the lease as %+v", renewed)
+	}
+
+	later := now.Add(3 * time.Minute)
+	taken, err := repo.AcquireDriveLease(ctx, drive.Id, second, later, later.Add(time.Minute))
+	if err != nil {
+		t.Error("AcquireDriveLease returned an error for an expired lease:", err)
+	} else if taken.Holder != second || !taken.AcquiredAt.Equal(later) {
+		t.Errorf("AcquireDriveLease took the expired lease as %+v", taken)
+	}
+
+	// Releasing a lease held by someone else must not release it.
+	if err := repo.ReleaseDriveLease(ctx, drive.Id, first); err != nil {
+		t.Error("ReleaseDriveLease returned an error:", err)
+	}
+
+	if _, err := repo.AcquireDriveLease(ctx, drive.Id, first, later, later.Add(time.Minute)); !errors.Is(err, ErrDriveLeaseHeld) {
+		t.Errorf("AcquireDriveLease returned %v after another holder's release, expected ErrDriveLeaseHeld", err)
+	}
+
+	if err := repo.ReleaseDriveLease(ctx, drive.Id, second); err != nil {
+		t.Error("ReleaseDriveLease returned an error:", err)
+	}
+
+	if _, err := repo.AcquireDriveLease(ctx, drive.Id, first, later, later.Add(time.Minute)); err != nil {
+		t.Error("AcquireDriveLease returned an error for a released lease:", err)
+	}
+
+	if err := repo.ReleaseDriveLease(ctx, drive.Id, first); err != nil {
+		t.Error("ReleaseDriveLease returned an error:", err)
+	}
+}
+
+func testCopyOperation(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	drive := models.OpticalDrive{SerialNumber: "copy-operation-drive"}
+	if err := repo.UpsertOpticalDrive(ctx, &drive); err != nil {
+		t.Error("UpsertOpticalDrive returned an error:", err)
+		return
+	}
+	driveId := drive.Id
+
+	op := models.CopyOperation{
+		DriveId:   driveId,
+		DiscLabel: "LOST_S1",
+		State:     models.CopyStateRunning,
+		StartedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	if err := repo.CreateCopyOperation(ctx, &op); err != nil {
+		t.Error("CreateCopyOperation returned an error:", err)
+		return
+	}
+
+	if op.Id == 0 {
+		t.Error("CreateCopyOperation did not assign an id")
+	}
+
+	op.State = models.CopyStateFailed
+	op.EndedAt = op.StartedAt.Add(time.Hour)
+	op.Warnings = []string{"warning"}
+	op.FailureReason = "failure"
+	op.Damaged = true
+	op.ReadErrorCount = 1
+	op.ReadErrors = []models.ReadError{{Kind: "medium", Source: "00800.m2ts", Title: 2, Offset: 1234, Progress: 25}}
+	op.Manifest = json.RawMessage(`{"version": 1, "operation_id": 1}`)
+
+	if err := repo.UpdateCopyOperation(ctx, op); err != nil {
+		t.Error("UpdateCopyOperation returned an error:", err)
+		return
+	}
+
+	// Modifying the operation after saving it must not change what was
+	// stored.
+	op.Warnings[0] = "changed"
+
+	stored, err := repo.GetCopyOperation(ctx, op.Id)
+	if err != nil {
+		t.Error("GetCopyOperation returned an error:", err)
+		return
+	}
+
+	if stored.State != models.CopyStateFailed {
+		t.Errorf("State = %s, expected %s", stored.State, models.CopyStateFailed)
+	}
+	if !stored.EndedAt.Equal(op.EndedAt) {
+		t.Errorf("EndedAt = %s, expected %s", stored.EndedAt, op.EndedAt)
+	}
+	if len(stored.Warnings) != 1 || stored.Warnings[0] != "warning" {
+		t.Errorf("Warnings = %v, expected [warning]", stored.Warnings)
+	}
+	if stored.FailureReason != "failure" {
+		t.Errorf("FailureReason = %s, expected failure", stored.FailureReason)
+	}
+	if !stored.Damaged || stored.ReadErrorCount != 1 || len(stored.ReadErrors) != 1 || stored.ReadErrors[0] != op.ReadErrors[0] {
+		t.Errorf("Read errors not stored: %+v", stored)
+	}
+
+	// PostgreSQL normalizes JSON so the manifest is compared decoded.
+	var manifest map[string]any
+	if err := json.Unmarshal(stored.Manifest, &manifest); err != nil || manifest["version"] != 1.0 {
+		t.Errorf("Manifest = %s, expected %s (%v)", stored.Manifest, op.Manifest, err)
+	}
+
+	if _, err := repo.GetCopyOperation(ctx, op.Id+1000); err != ErrCopyOperationNotFound {
+		t.Error("GetCopyOperation did not return ErrCopyOperationNotFound")
+	}
+
+	if err := repo.UpdateCopyOperation(ctx, models.CopyOperation{Id: op.Id + 1000}); err != ErrCopyOperationNotFound {
+		t.Error("UpdateCopyOperation did not return ErrCopyOperationNotFound")
+	}
+
+	newer := models.CopyOperation{DriveId: driveId, State: models.CopyStateRunning, StartedAt: time.Now()}
+	if err := repo.CreateCopyOperation(ctx, &newer); err != nil {
+		t.Error("CreateCopyOperation returned an error:", err)
+		return
+	}
+
+	ops, err := repo.ListCopyOperations(ctx, driveId)
+	if err != nil {
+		t.Error("ListCopyOperations returned an error:", err)
+		return
+	}
+
+	if len(ops) != 2 {
+		t.Errorf("ListCopyOperations returned %d operations, expected 2", len(ops))
+		return
+	}
+
+	if ops[0].Id != newer.Id || ops[1].Id != op.Id {
+		t.Errorf("ListCopyOperations returned [%d, %d], expected [%d, %d]", ops[0].Id, ops[1].Id, newer.Id, op.Id)
+	}
+
+	if ops[0].Manifest != nil {
+		t.Errorf("Manifest = %s, expected none for an operation without one", ops[0].Manifest)
+	}
+
+	ended, err := repo.ListCopyOperationsEndedBefore(ctx, driveId, models.CopyStateFailed, op.EndedAt.Add(time.Second))
+	if err != nil {
+		t.Error("ListCopyOperationsEndedBefore returned an error:", err)
+	} else if len(ended) != 1 || ended[0].Id != op.Id {
+		t.Errorf("ListCopyOperationsEndedBefore returned %+v, expected operation %d", ended, op.Id)
+	}
+
+	ended, err = repo.ListCopyOperationsEndedBefore(ctx, driveId, models.CopyStateFailed, op.EndedAt)
+	if err != nil {
+		t.Error("ListCopyOperationsEndedBefore returned an error:", err)
+	} else if len(ended) != 0 {
+		t.Errorf("ListCopyOperationsEndedBefore returned %d operations that ended too late", len(ended))
+	}
+
+	if err := repo.DeleteCopyOperation(ctx, op.Id); err != nil {
+		t.Error("DeleteCopyOperation returned an error:", err)
+	}
+
+	if _, err := repo.GetCopyOperation(ctx, op.Id); err != ErrCopyOperationNotFound {
+		t.Error("GetCopyOperation did not return ErrCopyOperationNotFound for a deleted operation")
+	}
+
+	if err := repo.DeleteCopyOperation(ctx, op.Id); err != ErrCopyOperationNotFound {
+		t.Error("DeleteCopyOperation did not return ErrCopyOperationNotFound")
+	}
+}
+
+func testCopyProgressSample(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	drive := models.OpticalDrive{SerialNumber: "progress-sample-drive"}
+	if err := repo.UpsertOpticalDrive(ctx, &drive); err != nil {
+		t.Error("UpsertOpticalDrive returned an error:", err)
+		return
+	}
+
+	op := models.CopyOperation{DriveId: drive.Id, State: models.CopyStateRunning, StartedAt: time.Now()}
+	if err := repo.CreateCopyOperation(ctx, &op); err != nil {
+		t.Error("CreateCopyOperation returned an error:", err)
+		return
+	}
+
+	start := time.Now().UTC().Truncate(time.Second)
+	for i := range 3 {
+		sample := models.CopyProgressSample{
+			CopyOperationId: op.Id,
+			SampledAt:       start.Add(time.Duration(i) * 10 * time.Second),
+			Title:           i / 2,
+			Bytes:           int64(i) * 1 << 30,
+			Percent:         float64(i) * 12.5,
+		}
+		if err := repo.AddCopyProgressSample(ctx, sample); err != nil {
+			t.Error("AddCopyProgressSample returned an error:", err)
+			return
+		}
+	}
+
+	samples, err := repo.ListCopyProgressSamples(ctx, op.Id)
+	if err != nil {
+		t.Error("ListCopyProgressSamples returned an error:", err)
+		return
+	}
+
+	if len(samples) != 3 {
+		t.Errorf("ListCopyProgressSamples returned %d samples, expected 3", len(samples))
+		return
+	}
+
+	last := samples[2]
+	if !last.SampledAt.Equal(start.Add(20*time.Second)) || last.Title != 1 || last.Bytes != 2<<30 || last.Percent != 25 {
+		t.Errorf("ListCopyProgressSamples returned %+v", last)
+	}
+
+	if err := repo.ReplaceCopyProgressSamples(ctx, op.Id, []models.CopyProgressSample{samples[0], samples[2]}); err != nil {
+		t.Error("ReplaceCopyProgressSamples returned an error:", err)
+	}
+
+	samples, err = repo.ListCopyProgressSamples(ctx, op.Id)
+	if err != nil {
+		t.Error("ListCopyProgressSamples returned an error:", err)
+	} else if len(samples) != 2 || samples[1].Percent != 25 {
+		t.Errorf("ListCopyProgressSamples returned %+v after replacing the samples", samples)
+	}
+
+	// Deleting the operation deletes its samples.
+	op.State = models.CopyStateSucceeded
+	if err := repo.UpdateCopyOperation(ctx, op); err != nil {
+		t.Error("UpdateCopyOperation returned an error:", err)
+	}
+
+	if err := repo.DeleteCopyOperation(ctx, op.Id); err != nil {
+		t.Error("DeleteCopyOperation returned an error:", err)
+	}
+
+	samples, err = repo.ListCopyProgressSamples(ctx, op.Id)
+	if err != nil {
+		t.Error("ListCopyProgressSamples returned an error:", err)
+	} else if len(samples) != 0 {
+		t.Errorf("ListCopyProgressSamples returned %d samples for a deleted operation", len(samples))
+	}
+}
+
+func testDisc(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	fingerprint := fmt.Sprintf("fingerprint-%d", time.Now().UnixNano())
+	disc := models.Disc{
+		Label:       "LOST_S1",
+		Name:        "Lost: Season 1",
+		VolumeName:  "LOST_S1_D1",
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		Titles: []models.DiscTitle{
+			{
+				Index:        0,
+				Name:         "Lost",
+				Duration:     2580,
+				Size:         7516192768,
+				ChapterCount: 6,
+				SegmentsMap:  "800",
+				SourceFile:   "00800.mpls",
+				Streams: []models.DiscStream{
+					{Index: 0, Type: "Video", Codec: "Mpeg4"},
+					{Index: 1, Type: "Audio", Codec: "DTS", LangCode: "eng", Name: "Surround 5.1"},
+				},
+			},
+			{Index: 1, Duration: 2610, Size: 7600000000, SegmentsMap: "801", SourceFile: "00801.mpls"},
+		},
+	}
+
+	if err := repo.CreateDisc(ctx, &disc); err != nil {
+		t.Error("CreateDisc returned an error:", err)
+		return
+	}
+
+	stored, err := repo.GetDisc(ctx, disc.Id)
+	if err != nil {
+		t.Error("GetDisc returned an error:", err)
+		return
+	}
+
+	if stored.Name != disc.Name || stored.VolumeName != disc.VolumeName || stored.Label != disc.Label ||
+		stored.Fingerprint != disc.Fingerprint {
+		t.Errorf("GetDisc returned %+v, expected %+v", stored, disc)
+	}
+
+	if !reflect.DeepEqual(stored.Titles, disc.Titles) {
+		t.Errorf("GetDisc returned titles %+v, expected %+v", stored.Titles, disc.Titles)
+	}
+
+	if _, err := repo.GetDisc(ctx, disc.Id+1000); err != ErrDiscNotFound {
+		t.Error("GetDisc did not return ErrDiscNotFound")
+	}
+
+	duplicate := models.Disc{Label: "LOST_S1", Fingerprint: fingerprint, CreatedAt: time.Now()}
+	if err := repo.CreateDisc(ctx, &duplicate); err != nil {
+		t.Error("CreateDisc returned an error:", err)
+		return
+	}
+
+	found, err := repo.GetDiscByFingerprint(ctx, fingerprint)
+	if err != nil {
+		t.Error("GetDiscByFingerprint returned an error:", err)
+	} else if found.Id != disc.Id || len(found.Titles) != 2 {
+		t.Errorf("GetDiscByFingerprint returned disc %d with %d titles, expected disc %d with 2 titles",
+			found.Id, len(found.Titles), disc.Id)
+	}
+
+	if _, err := repo.GetDiscByFingerprint(ctx, fingerprint+"-missing"); err != ErrDiscNotFound {
+		t.Error("GetDiscByFingerprint did not return ErrDiscNotFound")
+	}
+
+	discs, err := repo.ListDiscs(ctx)
+	if err != nil {
+		t.Error("ListDiscs returned an error:", err)
+		return
+	}
+
+	if len(discs) == 0 || discs[0].Id != duplicate.Id {
+		t.Errorf("ListDiscs did not return the newest disc first: %+v", discs)
+	}
+
+	drive := models.OpticalDrive{SerialNumber: "disc-drive"}
+	if err := repo.UpsertOpticalDrive(ctx, &drive); err != nil {
+		t.Error("UpsertOpticalDrive returned an error:", err)
+		return
+	}
+
+	other := models.CopyOperation{DriveId: drive.Id, State: models.CopyStateRunning, StartedAt: time.Now()}
+	if err := repo.CreateCopyOperation(ctx, &other); err != nil {
+		t.Error("CreateCopyOperation returned an error:", err)
+		return
+	}
+
+	op := models.CopyOperation{DriveId: drive.Id, DiscId: disc.Id, State: models.CopyStateRunning, StartedAt: time.Now()}
+	if err := repo.CreateCopyOperation(ctx, &op); err != nil {
+		t.Error("CreateCopyOperation returned an error:", err)
+		return
+	}
+
+	ops, err := repo.ListCopyOperationsForDisc(ctx, disc.Id)
+	if err != nil {
+		t.Error("ListCopyOperationsForDisc returned an error:", err)
+		return
+	}
+
+	if len(ops) != 1 || ops[0].Id != op.Id || ops[0].DiscId != disc.Id {
+		t.Errorf("ListCopyOperationsForDisc returned %+v, expected operation %d", ops, op.Id)
+	}
+}
+
+func testDriveCommand(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	drive := models.OpticalDrive{SerialNumber: "drive-command-drive"}
+	if err := repo.UpsertOpticalDrive(ctx, &drive); err != nil {
+		t.Error("UpsertOpticalDrive returned an error:", err)
+		return
+	}
+
+	start := models.DriveCommand{DriveId: drive.Id, Command: models.DriveCommandStart, Args: json.RawMessage(`{"force": true}`), CreatedAt: time.Now()}
+	if err := repo.CreateDriveCommand(ctx, &start); err != nil {
+		t.Error("CreateDriveCommand returned an error:", err)
+		return
+	}
+
+	eject := models.DriveCommand{DriveId: drive.Id, Command: models.DriveCommandEject, CreatedAt: time.Now()}
+	if err := repo.CreateDriveCommand(ctx, &eject); err != nil {
+		t.Error("CreateDriveCommand returned an error:", err)
+		return
+	}
+
+	pending, err := repo.ListPendingDriveCommands(ctx, drive.Id)
+	if err != nil {
+		t.Error("ListPendingDriveCommands returned an error:", err)
+		return
+	}
+
+	if len(pending) != 2 || pending[0].Id != start.Id || pending[1].Id != eject.Id {
+		t.Errorf("ListPendingDriveCommands returned %+v, expected the commands oldest first", pending)
+	} else if string(pending[0].Args) != `{"force": true}` || pending[1].Args != nil {
+		t.Errorf("Command args = %s, %s, expected the start command's args only", pending[0].Args, pending[1].Args)
+	}
+
+	acked := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := repo.AcknowledgeDriveCommand(ctx, start.Id, acked); err != nil {
+		t.Error("AcknowledgeDriveCommand returned an error:", err)
+	}
+
+	if err := repo.AcknowledgeDriveCommand(ctx, start.Id, acked); err != ErrCommandNotPending {
+		t.Errorf("AcknowledgeDriveCommand returned %v for an acknowledged command, expected ErrCommandNotPending", err)
+	}
+
+	completed := acked.Add(time.Second)
+	if err := repo.CompleteDriveCommand(ctx, start.Id, models.CommandStatusSucceeded, "copy operation 1", completed); err != nil {
+		t.Error("CompleteDriveCommand returned an error:", err)
+	}
+
+	if err := repo.CompleteDriveCommand(ctx, -1, models.CommandStatusFailed, "", completed); err != ErrCommandNotFound {
+		t.Errorf("CompleteDriveCommand returned %v for a missing command, expected ErrCommandNotFound", err)
+	}
+
+	got, err := repo.GetDriveCommand(ctx, start.Id)
+	if err != nil {
+		t.Error("GetDriveCommand returned an error:", err)
+		return
+	}
+
+	if got.Status != models.CommandStatusSucceeded || got.Result != "copy operation 1" {
+		t.Errorf("GetDriveCommand returned %+v, expected a succeeded command", got)
+	}
+
+	if !got.AcknowledgedAt.Equal(acked) || !got.CompletedAt.Equal(completed) {
+		t.Errorf("Command times = %s, %s, expected %s, %s", got.AcknowledgedAt, got.CompletedAt, acked, completed)
+	}
+
+	if _, err := repo.GetDriveCommand(ctx, -1); err != ErrCommandNotFound {
+		t.Errorf("GetDriveCommand returned %v for a missing command, expected ErrCommandNotFound", err)
+	}
+
+	pending, err = repo.ListPendingDriveCommands(ctx, drive.Id)
+	if err != nil {
+		t.Error("ListPendingDriveCommands returned an error:", err)
+		return
+	}
+
+	if len(pending) != 1 || pending[0].Id != eject.Id {
+		t.Errorf("ListPendingDriveCommands returned %+v, expected only the eject command", pending)
+	}
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, NewMemoryRepository())
+}
+
+// TestPgRepository runs the repository tests against the PostgreSQL database
+// specified by the ARTIE_TEST_DB environment variable. The database should be
+// empty since the migrations are applied and then reverted.
+func TestPgRepository(t *testing.T) {
+	connStr := os.Getenv("ARTIE_TEST_DB")
+	if connStr == "" {
+		t.Skip("ARTIE_TEST_DB not set")
+	}
+
+	ctx := context.Background()
+
+	repo, err := NewPgRepository(ctx, connStr)
+	if err != nil {
+		t.Fatal("NewPgRepository returned an error:", err)
+	}
+	defer repo.Close()
+
+	if _, err := repo.MigrateUp(ctx); err != nil {
+		t.Fatal("MigrateUp returned an error:", err)
+	}
+	defer func() {
+		for {
+			reverted, err := repo.MigrateDown(ctx)
+			if err != nil {
+				t.Error("MigrateDown returned an error:", err)
+				return
+			}
+			if !reverted {
+				return
+			}
+		}
+	}()
+
+	testRepository(t, repo)
+}
+
+// TestSqliteRepository runs the repository tests against a SQLite database in a
+// temporary directory.
+func TestSqliteRepository(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := Open(ctx, "sqlite:"+filepath.Join(t.TempDir(), "artie.db"))
+	if err != nil {
+		t.Fatal("Open returned an error:", err)
+	}
+	defer repo.Close()
+
+	if _, ok := repo.(*SqliteRepository); !ok {
+		t.Fatalf("Open returned %T, expected *SqliteRepository", repo)
+	}
+
+	count, err := repo.MigrateUp(ctx)
+	if err != nil {
+		t.Fatal("MigrateUp returned an error:", err)
+	}
+
+	if count == 0 {
+		t.Error("Expected MigrateUp to apply migrations")
+	}
+
+	status, err := repo.GetMigrationStatus(ctx)
+	if err != nil {
+		t.Fatal("GetMigrationStatus returned an error:", err)
+	}
+
+	for _, s := range status {
+		if !s.Applied || s.AppliedAt.IsZero() {
+			t.Errorf("Migration %d_%s not marked as applied", s.Version, s.Name)
+		}
+	}
+
+	testRepository(t, repo)
+
+	for {
+		reverted, err := repo.MigrateDown(ctx)
+		if err != nil {
+			t.Fatal("MigrateDown returned an error:", err)
+		}
+		if !reverted {
+			break
+		}
+	}
+}
@@ -0,0 +1,425 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// Actions recorded in the spool.
+const (
+	spoolCreate = "create"
+	spoolUpdate = "update"
+)
+
+// spoolRecord is a copy operation write that couldn't be made to the database.
+type spoolRecord struct {
+	Action string               `json:"action"`
+	Op     models.CopyOperation `json:"op"`
+}
+
+// HealthReporter reports the health of a repository's connection to its
+// database.
+type HealthReporter interface {
+	// Healthy returns true if the database was reachable the last time it
+	// was checked.
+	Healthy() bool
+
+	// Spooled returns the number of writes waiting to be replayed.
+	Spooled() int
+}
+
+// SpoolRepository wraps a Repository so that copy operation writes are
+// buffered in an on-disk spool while the database is unreachable instead of
+// failing. The spooled writes are replayed in order once the database is
+// reachable again. Everything else is passed straight through.
+//
+// Copy operations created while the database is unreachable are assigned a
+// negative provisional identifier until they are replayed. Later writes using
+// the provisional identifier are mapped to the identifier assigned by the
+// database.
+type SpoolRepository struct {
+	Repository
+
+	// OnReplay is called when a copy operation created while the database was
+	// unreachable is added to the database. `provisional` is the identifier
+	// it was assigned while spooled and `id` is the one assigned by the
+	// database. It must be set before Run is called.
+	OnReplay func(provisional, id int)
+
+	path string
+
+	mu              sync.Mutex
+	records         []spoolRecord
+	ids             map[int]int
+	lastProvisional int
+	healthy         bool
+}
+
+// NewSpoolRepository creates a repository that spools copy operation writes
+// to the file at `path` when they can't be made to `repo`. Any writes already
+// in the spool are loaded so they can be replayed.
+func NewSpoolRepository(repo Repository, path string) (*SpoolRepository, error) {
+	r := &SpoolRepository{
+		Repository: repo,
+		path:       path,
+		ids:        make(map[int]int),
+		healthy:    true,
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read spool: %w", err)
+	} else if err == nil {
+		if err := json.Unmarshal(bs, &r.records); err != nil {
+			return nil, fmt.Errorf("failed to parse spool: %w", err)
+		}
+	}
+
+	for _, record := range r.records {
+		r.lastProvisional = min(r.lastProvisional, record.Op.Id)
+	}
+
+	return r, nil
+}
+
+// Healthy returns true if the database was reachable the last time it was
+// checked or written to.
+func (r *SpoolRepository) Healthy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.healthy
+}
+
+// Spooled returns the number of writes waiting to be replayed.
+func (r *SpoolRepository) Spooled() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.records)
+}
+
+// CreateCopyOperation adds the copy operation `op` to the database. If the
+// database can't be reached, the write is spooled and `op` is assigned a
+// provisional identifier. Errors for writes the database rejected are
+// returned.
+func (r *SpoolRepository) CreateCopyOperation(ctx context.Context, op *models.CopyOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Writes can't skip ahead of the spool otherwise they could be replayed
+	// out of order.
+	if len(r.records) == 0 {
+		err := r.Repository.CreateCopyOperation(ctx, op)
+		if err == nil || !r.unavailable(ctx, err) {
+			return err
+		}
+		r.setUnhealthy(err)
+	}
+
+	// Provisional identifiers are based on the time so that they aren't
+	// reused across restarts. The identifier is used to name the operation's
+	// output directory which may still exist after it was replayed.
+	r.lastProvisional = min(r.lastProvisional-1, int(-time.Now().Unix()))
+	op.Id = r.lastProvisional
+
+	slog.Warn("Spooling copy operation.", "id", op.Id)
+	return r.spool(spoolRecord{Action: spoolCreate, Op: *op})
+}
+
+// UpdateCopyOperation updates the database record for the copy operation `op`.
+// If the database can't be reached, the write is spooled. Errors for writes
+// the database rejected are returned.
+func (r *SpoolRepository) UpdateCopyOperation(ctx context.Context, op models.CopyOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.ids[op.Id]; ok {
+		op.Id = id
+	}
+
+	if len(r.records) == 0 {
+		err := r.Repository.UpdateCopyOperation(ctx, op)
+		if err == nil || !r.unavailable(ctx, err) {
+			return err
+		}
+		r.setUnhealthy(err)
+	}
+
+	return r.spool(spoolRecord{Action: spoolUpdate, Op: op})
+}
+
+// GetCopyOperation gets the copy operation with identifier `id`. Operations
+// with spooled writes are returned from the spool so that the latest state is
+// visible while the database is unreachable.
+func (r *SpoolRepository) GetCopyOperation(ctx context.Context, id int) (models.CopyOperation, error) {
+	r.mu.Lock()
+	if mapped, ok := r.ids[id]; ok {
+		id = mapped
+	}
+	for i := len(r.records) - 1; i >= 0; i-- {
+		if r.records[i].Op.Id == id {
+			op := cloneCopyOperation(r.records[i].Op)
+			r.mu.Unlock()
+			return op, nil
+		}
+	}
+	r.mu.Unlock()
+
+	return r.Repository.GetCopyOperation(ctx, id)
+}
+
+// AddCopyProgressSample adds progress sample `sample` to its copy operation.
+// Samples of operations that were replayed use the identifier assigned by the
+// database.
+func (r *SpoolRepository) AddCopyProgressSample(ctx context.Context, sample models.CopyProgressSample) error {
+	sample.CopyOperationId = r.replayedId(sample.CopyOperationId)
+	return r.Repository.AddCopyProgressSample(ctx, sample)
+}
+
+// ListCopyProgressSamples gets the progress samples of the copy operation with
+// identifier `id` which may be the provisional identifier of an operation that
+// was replayed.
+func (r *SpoolRepository) ListCopyProgressSamples(ctx context.Context, id int) ([]models.CopyProgressSample, error) {
+	return r.Repository.ListCopyProgressSamples(ctx, r.replayedId(id))
+}
+
+// ReplaceCopyProgressSamples replaces the progress samples of the copy
+// operation with identifier `id`, which may be the provisional identifier of
+// an operation that was replayed, with `samples`.
+func (r *SpoolRepository) ReplaceCopyProgressSamples(ctx context.Context, id int, samples []models.CopyProgressSample) error {
+	id = r.replayedId(id)
+	for i := range samples {
+		samples[i].CopyOperationId = id
+	}
+	return r.Repository.ReplaceCopyProgressSamples(ctx, id, samples)
+}
+
+// replayedId returns the identifier assigned by the database to the copy
+// operation with provisional identifier `id` if it was replayed. Otherwise,
+// `id` is returned.
+func (r *SpoolRepository) replayedId(id int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if mapped, ok := r.ids[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+// Run checks the health of the database every `interval` and replays the
+// spool when the database is reachable until `ctx` is cancelled.
+func (r *SpoolRepository) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check pings the database and replays the spool if it's reachable.
+func (r *SpoolRepository) check(ctx context.Context) {
+	err := r.Repository.Ping(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.setUnhealthy(err)
+		return
+	}
+
+	if !r.healthy {
+		slog.Info("Database reachable again.")
+		r.healthy = true
+	}
+
+	if len(r.records) == 0 {
+		return
+	}
+
+	count := len(r.records)
+	if err := r.replay(ctx); err != nil {
+		r.setUnhealthy(err)
+		return
+	}
+
+	slog.Info("Replayed spooled copy operation writes.", "count", count)
+}
+
+// replay writes the spooled records to the database in order. Each record is
+// removed from the spool once written so that it isn't replayed again if
+// replaying fails part way through. Records the database rejects are dropped
+// so that they don't block the rest. Requires the lock to be held.
+func (r *SpoolRepository) replay(ctx context.Context) error {
+	for len(r.records) > 0 {
+		record := r.records[0]
+		op := record.Op
+
+		switch record.Action {
+		case spoolCreate:
+			provisional := op.Id
+			if err := r.Repository.CreateCopyOperation(ctx, &op); err != nil && r.unavailable(ctx, err) {
+				return fmt.Errorf("failed to replay create of %d: %w", provisional, err)
+			} else if err != nil {
+				slog.Warn("Dropping rejected spooled create of copy operation.", "id", provisional, "error", err)
+				break
+			}
+
+			// Update the rest of the spool so that it remains valid on its
+			// own if the service restarts.
+			r.ids[provisional] = op.Id
+			for i := range r.records {
+				if r.records[i].Op.Id == provisional {
+					r.records[i].Op.Id = op.Id
+				}
+			}
+
+			if r.OnReplay != nil {
+				r.OnReplay(provisional, op.Id)
+			}
+		case spoolUpdate:
+			err := r.Repository.UpdateCopyOperation(ctx, op)
+			if err != nil && r.unavailable(ctx, err) {
+				return fmt.Errorf("failed to replay update of %d: %w", op.Id, err)
+			} else if err != nil {
+				slog.Warn("Dropping rejected spooled update of copy operation.", "id", op.Id, "error", err)
+			}
+		default:
+			slog.Warn("Dropping unknown spool record.", "action", record.Action)
+		}
+
+		r.records = r.records[1:]
+		if err := r.save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// spool adds `record` to the spool. Requires the lock to be held.
+func (r *SpoolRepository) spool(record spoolRecord) error {
+	record.Op = cloneCopyOperation(record.Op)
+	r.records = append(r.records, record)
+	return r.save()
+}
+
+// save writes the spool to disk, removing the file if the spool is empty. The
+// spool is written to a temporary file first so that a crash doesn't leave it
+// partially written. Requires the lock to be held.
+func (r *SpoolRepository) save() error {
+	if len(r.records) == 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove spool: %w", err)
+		}
+		return nil
+	}
+
+	bs, err := json.Marshal(r.records)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+
+	return nil
+}
+
+// unavailable returns true if a write failed with `err` because the database
+// couldn't be reached rather than because it rejected the write. Rejected
+// writes aren't spooled since replaying them would fail the same way and
+// block the rest of the spool. Errors reported by PostgreSQL are rejections
+// unless they are about the connection or the server shutting down. For any
+// other error, the database is pinged to tell. Requires the lock to be held.
+func (r *SpoolRepository) unavailable(ctx context.Context, err error) bool {
+	// The write was abandoned by the caller so it shouldn't be made later.
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P")
+	}
+
+	return r.Repository.Ping(ctx) != nil
+}
+
+// setUnhealthy marks the database as unreachable because of `err`. Requires
+// the lock to be held.
+func (r *SpoolRepository) setUnhealthy(err error) {
+	if r.healthy {
+		slog.Warn("Database unreachable.", "error", err)
+	}
+	r.healthy = false
+}
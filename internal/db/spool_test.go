@@ -0,0 +1,225 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+var errUnreachable = errors.New("database unreachable")
+
+// flakyRepository is a MemoryRepository whose copy operation writes and pings
+// fail while `down` is set. Its copy operation writes fail with `rejected`, if
+// set, while it's up.
+type flakyRepository struct {
+	*MemoryRepository
+	down     bool
+	rejected error
+}
+
+func (r *flakyRepository) Ping(ctx context.Context) error {
+	if r.down {
+		return errUnreachable
+	}
+	return nil
+}
+
+func (r *flakyRepository) CreateCopyOperation(ctx context.Context, op *models.CopyOperation) error {
+	if r.down {
+		return errUnreachable
+	} else if r.rejected != nil {
+		return r.rejected
+	}
+	return r.MemoryRepository.CreateCopyOperation(ctx, op)
+}
+
+func (r *flakyRepository) UpdateCopyOperation(ctx context.Context, op models.CopyOperation) error {
+	if r.down {
+		return errUnreachable
+	} else if r.rejected != nil {
+		return r.rejected
+	}
+	return r.MemoryRepository.UpdateCopyOperation(ctx, op)
+}
+
+func TestSpoolRepository(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "spool.json")
+
+	flaky := &flakyRepository{MemoryRepository: NewMemoryRepository()}
+	repo, err := NewSpoolRepository(flaky, path)
+	if err != nil {
+		t.Fatal("NewSpoolRepository returned an error:", err)
+	}
+
+	healthy := models.CopyOperation{DriveId: 1, State: models.CopyStateSucceeded, StartedAt: time.Now()}
+	if err := repo.CreateCopyOperation(ctx, &healthy); err != nil {
+		t.Fatal("CreateCopyOperation returned an error:", err)
+	}
+
+	if healthy.Id <= 0 {
+		t.Errorf("Operation created while healthy has id %d", healthy.Id)
+	}
+
+	flaky.down = true
+
+	op := models.CopyOperation{DriveId: 1, State: models.CopyStateRunning, StartedAt: time.Now()}
+	if err := repo.CreateCopyOperation(ctx, &op); err != nil {
+		t.Fatal("CreateCopyOperation returned an error while unreachable:", err)
+	}
+
+	if op.Id >= 0 {
+		t.Errorf("Spooled operation has id %d, expected a provisional id", op.Id)
+	}
+
+	provisional := op.Id
+	op.State = models.CopyStateSucceeded
+	op.Warnings = []string{"warning"}
+	if err := repo.UpdateCopyOperation(ctx, op); err != nil {
+		t.Fatal("UpdateCopyOperation returned an error while unreachable:", err)
+	}
+
+	if repo.Healthy() {
+		t.Error("Repository healthy while the database is unreachable")
+	}
+
+	if repo.Spooled() != 2 {
+		t.Errorf("Spooled = %d, expected 2", repo.Spooled())
+	}
+
+	got, err := repo.GetCopyOperation(ctx, provisional)
+	if err != nil {
+		t.Error("GetCopyOperation returned an error for a spooled operation:", err)
+	} else if got.State != models.CopyStateSucceeded {
+		t.Errorf("Spooled operation state = %s, expected %s", got.State, models.CopyStateSucceeded)
+	}
+
+	// Reload the spool as if the service restarted to check that it was
+	// persisted.
+	repo, err = NewSpoolRepository(flaky, path)
+	if err != nil {
+		t.Fatal("NewSpoolRepository returned an error:", err)
+	}
+
+	if repo.Spooled() != 2 {
+		t.Fatalf("Spooled = %d after reload, expected 2", repo.Spooled())
+	}
+
+	replayed := make(map[int]int)
+	repo.OnReplay = func(provisional, id int) {
+		replayed[provisional] = id
+	}
+
+	repo.check(ctx)
+	if repo.Spooled() != 2 {
+		t.Error("Spool replayed while the database is unreachable")
+	}
+
+	flaky.down = false
+	repo.check(ctx)
+
+	if !repo.Healthy() {
+		t.Error("Repository unhealthy after the database became reachable")
+	}
+
+	if repo.Spooled() != 0 {
+		t.Errorf("Spooled = %d after replay, expected 0", repo.Spooled())
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Spool file not removed after replay")
+	}
+
+	id, ok := replayed[provisional]
+	if !ok {
+		t.Fatal("OnReplay not called for the spooled operation")
+	}
+
+	stored, err := flaky.GetCopyOperation(ctx, id)
+	if err != nil {
+		t.Fatal("Replayed operation not in the database:", err)
+	}
+
+	if stored.State != models.CopyStateSucceeded || len(stored.Warnings) != 1 {
+		t.Errorf("Replayed operation doesn't include the spooled update: %+v", stored)
+	}
+
+	// Writes using the provisional id after the replay should be mapped to
+	// the id assigned by the database.
+	op.FailureReason = "late update"
+	if err := repo.UpdateCopyOperation(ctx, op); err != nil {
+		t.Fatal("UpdateCopyOperation returned an error:", err)
+	}
+
+	stored, _ = flaky.GetCopyOperation(ctx, id)
+	if stored.FailureReason != "late update" {
+		t.Error("Update using the provisional id wasn't mapped to the replayed operation")
+	}
+
+	sample := models.CopyProgressSample{CopyOperationId: provisional, SampledAt: time.Now(), Percent: 50}
+	if err := repo.AddCopyProgressSample(ctx, sample); err != nil {
+		t.Fatal("AddCopyProgressSample returned an error:", err)
+	}
+
+	if samples, err := flaky.ListCopyProgressSamples(ctx, id); err != nil || len(samples) != 1 {
+		t.Errorf("Samples of the replayed operation = %v, %v, expected the sample added using the provisional id", samples, err)
+	}
+}
+
+func TestSpoolRepositoryRejectedWrite(t *testing.T) {
+	ctx := context.Background()
+
+	rejected := errors.New("constraint violated")
+	flaky := &flakyRepository{MemoryRepository: NewMemoryRepository(), rejected: rejected}
+	repo, err := NewSpoolRepository(flaky, filepath.Join(t.TempDir(), "spool.json"))
+	if err != nil {
+		t.Fatal("NewSpoolRepository returned an error:", err)
+	}
+
+	op := models.CopyOperation{DriveId: 1, State: models.CopyStateRunning, StartedAt: time.Now()}
+	if err := repo.CreateCopyOperation(ctx, &op); !errors.Is(err, rejected) {
+		t.Errorf("CreateCopyOperation error = %v, expected %v", err, rejected)
+	}
+
+	op.Id = 1
+	if err := repo.UpdateCopyOperation(ctx, op); !errors.Is(err, rejected) {
+		t.Errorf("UpdateCopyOperation error = %v, expected %v", err, rejected)
+	}
+
+	if repo.Spooled() != 0 || !repo.Healthy() {
+		t.Errorf("Spooled = %d, healthy = %v, expected rejected writes not to be spooled", repo.Spooled(), repo.Healthy())
+	}
+}
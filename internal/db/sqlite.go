@@ -0,0 +1,289 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimeFormat is the format timestamps are stored in. SQLite doesn't have
+// a timestamp type so they are stored as text. The format is fixed width and
+// always UTC so that timestamps can be compared and ordered as strings.
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// SqliteRepository is the Repository implementation backed by a SQLite
+// database. It is intended for single-host installs where running a
+// PostgreSQL server isn't worth the trouble.
+type SqliteRepository struct {
+	db *sql.DB
+}
+
+// NewSqliteRepository creates a repository using the SQLite database stored
+// in the file at `path`. The file is created if it doesn't exist.
+func NewSqliteRepository(ctx context.Context, path string) (*SqliteRepository, error) {
+	// The busy timeout makes writers wait for each other rather than failing
+	// immediately and WAL mode allows reads while a write is in progress.
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(1)")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	return &SqliteRepository{db: db}, nil
+}
+
+// Close closes the database.
+func (r *SqliteRepository) Close() {
+	r.db.Close()
+}
+
+// Ping checks that the database is reachable.
+func (r *SqliteRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// MigrateUp applies all migrations that haven't been applied to the database.
+// Returns the number of migrations applied.
+func (r *SqliteRepository) MigrateUp(ctx context.Context) (int, error) {
+	migrations, err := Migrations(DialectSqlite)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = r.withMigrationLock(ctx, func(s *sqliteMigrationSession) error {
+		count, err = migrateUp(ctx, migrations, s)
+		return err
+	})
+
+	return count, err
+}
+
+// MigrateDown reverts the most recently applied migration. Returns false if
+// there weren't any migrations to revert.
+func (r *SqliteRepository) MigrateDown(ctx context.Context) (bool, error) {
+	migrations, err := Migrations(DialectSqlite)
+	if err != nil {
+		return false, err
+	}
+
+	reverted := false
+	err = r.withMigrationLock(ctx, func(s *sqliteMigrationSession) error {
+		reverted, err = migrateDown(ctx, migrations, s)
+		return err
+	})
+
+	return reverted, err
+}
+
+// GetMigrationStatus returns the status of every migration embedded in the
+// application ordered by version.
+func (r *SqliteRepository) GetMigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := Migrations(DialectSqlite)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []MigrationStatus
+	err = r.withMigrationLock(ctx, func(s *sqliteMigrationSession) error {
+		status, err = migrationStatus(ctx, migrations, s)
+		return err
+	})
+
+	return status, err
+}
+
+// withMigrationLock calls `fn` with a session whose connection holds the
+// database's write lock. SQLite doesn't have advisory locks so instead all of
+// the migrations are applied in a single immediate transaction which is
+// committed if `fn` succeeds.
+func (r *SqliteRepository) withMigrationLock(ctx context.Context, fn func(s *sqliteMigrationSession) error) (err error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// A new context is used so that the transaction is finished even if
+		// `ctx` was cancelled.
+		stmt := "COMMIT"
+		if err != nil {
+			stmt = "ROLLBACK"
+		}
+		if _, cerr := conn.ExecContext(context.Background(), stmt); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to commit migrations: %w", cerr)
+		}
+	}()
+
+	s := &sqliteMigrationSession{conn: conn}
+	if err := s.createMigrationTable(ctx); err != nil {
+		return err
+	}
+
+	return fn(s)
+}
+
+// sqliteMigrationSession is the migrationSession for SQLite databases. All of
+// the statements are run in the transaction started by withMigrationLock.
+type sqliteMigrationSession struct {
+	conn *sql.Conn
+}
+
+func (s *sqliteMigrationSession) createMigrationTable(ctx context.Context) error {
+	stmt := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`
+	if _, err := s.conn.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteMigrationSession) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := s.conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt sqliteTime
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		applied[version] = time.Time(appliedAt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return applied, nil
+}
+
+func (s *sqliteMigrationSession) applyMigration(ctx context.Context, migration Migration) error {
+	if _, err := s.conn.ExecContext(ctx, migration.Up); err != nil {
+		return err
+	}
+	stmt := "INSERT INTO schema_migrations (version, name, applied_at) VALUES (@version, @name, @appliedAt)"
+	_, err := s.conn.ExecContext(ctx, stmt,
+		sql.Named("version", migration.Version),
+		sql.Named("name", migration.Name),
+		sql.Named("appliedAt", formatSqliteTime(time.Now())))
+	return err
+}
+
+func (s *sqliteMigrationSession) revertMigration(ctx context.Context, migration Migration) error {
+	if _, err := s.conn.ExecContext(ctx, migration.Down); err != nil {
+		return err
+	}
+	stmt := "DELETE FROM schema_migrations WHERE version=@version"
+	_, err := s.conn.ExecContext(ctx, stmt, sql.Named("version", migration.Version))
+	return err
+}
+
+// formatSqliteTime formats `t` for storage.
+func formatSqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// formatSqliteNullTime formats `t` for storage in a nullable column. A zero
+// time is stored as NULL.
+func formatSqliteNullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return formatSqliteTime(t)
+}
+
+// sqliteCheckAffected returns `notFound` if the statement that produced
+// `result` didn't affect any rows.
+func sqliteCheckAffected(result sql.Result, notFound error) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// sqliteTime scans a timestamp stored by formatSqliteTime. NULL is scanned as
+// the zero time.
+type sqliteTime time.Time
+
+func (t *sqliteTime) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*t = sqliteTime{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into timestamp", src)
+	}
+
+	parsed, err := time.Parse(sqliteTimeFormat, s)
+	if err != nil {
+		return err
+	}
+
+	*t = sqliteTime(parsed.Local())
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// CreateCopyOperation adds the copy operation `op` to the database and updates
+// its Id with the identifier assigned by the database.
+func (r *SqliteRepository) CreateCopyOperation(ctx context.Context, op *models.CopyOperation) error {
+	args, err := sqliteCopyOperationArgs(*op)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO copy_operation
+		(drive_id, disc_label, output_dir, state, started_at, ended_at, warnings, failure_reason,
+		 damaged, read_error_count, read_errors, disc_id, manifest)
+		VALUES (@driveId, @discLabel, @outputDir, @state, @startedAt, @endedAt, @warnings, @failureReason,
+		 @damaged, @readErrorCount, @readErrors, @discId, @manifest)
+		RETURNING id`
+	if err := r.db.QueryRowContext(ctx, stmt, args...).Scan(&op.Id); err != nil {
+		return fmt.Errorf("insert failed: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateCopyOperation updates the database record for the copy operation `op`.
+func (r *SqliteRepository) UpdateCopyOperation(ctx context.Context, op models.CopyOperation) error {
+	args, err := sqliteCopyOperationArgs(op)
+	if err != nil {
+		return err
+	}
+
+	stmt := `UPDATE copy_operation SET
+		disc_label=@discLabel, output_dir=@outputDir, state=@state, started_at=@startedAt,
+		ended_at=@endedAt, warnings=@warnings, failure_reason=@failureReason,
+		damaged=@damaged, read_error_count=@readErrorCount, read_errors=@readErrors,
+		disc_id=@discId, manifest=@manifest
+		WHERE id=@id`
+	result, err := r.db.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	return sqliteCheckAffected(result, ErrCopyOperationNotFound)
+}
+
+// GetCopyOperation gets the copy operation with identifier `id`. If the
+// operation doesn't exist, ErrCopyOperationNotFound is returned.
+func (r *SqliteRepository) GetCopyOperation(ctx context.Context, id int) (models.CopyOperation, error) {
+	stmt := "SELECT " + copyOperationColumns + " FROM copy_operation WHERE id=@id"
+	op, err := scanSqliteCopyOperation(r.db.QueryRowContext(ctx, stmt, sql.Named("id", id)))
+	if err == sql.ErrNoRows {
+		return op, ErrCopyOperationNotFound
+	} else if err != nil {
+		return op, fmt.Errorf("query row failed: %w", err)
+	}
+
+	return op, nil
+}
+
+// ListCopyOperations gets all of the copy operations for the drive with
+// identifier `driveId` ordered from newest to oldest.
+func (r *SqliteRepository) ListCopyOperations(ctx context.Context, driveId int) ([]models.CopyOperation, error) {
+	stmt := "SELECT " + copyOperationColumns + " FROM copy_operation WHERE drive_id=@driveId ORDER BY id DESC"
+	return r.listCopyOperations(ctx, stmt, sql.Named("driveId", driveId))
+}
+
+// ListCopyOperationsForDisc gets all of the copy operations of the disc with
+// identifier `discId` ordered from newest to oldest.
+func (r *SqliteRepository) ListCopyOperationsForDisc(ctx context.Context, discId int) ([]models.CopyOperation, error) {
+	stmt := "SELECT " + copyOperationColumns + " FROM copy_operation WHERE disc_id=@discId ORDER BY id DESC"
+	return r.listCopyOperations(ctx, stmt, sql.Named("discId", discId))
+}
+
+// ListCopyOperationsEndedBefore gets the copy operations for the drive with
+// identifier `driveId` in state `state` that ended before `before` ordered from
+// oldest to newest.
+func (r *SqliteRepository) ListCopyOperationsEndedBefore(ctx context.Context, driveId int, state models.CopyOperationState, before time.Time) ([]models.CopyOperation, error) {
+	stmt := "SELECT " + copyOperationColumns + ` FROM copy_operation
+		WHERE drive_id=@driveId AND state=@state AND ended_at<@before ORDER BY id`
+	return r.listCopyOperations(ctx, stmt,
+		sql.Named("driveId", driveId),
+		sql.Named("state", state),
+		sql.Named("before", formatSqliteTime(before)))
+}
+
+// DeleteCopyOperation deletes the copy operation with identifier `id`.
+func (r *SqliteRepository) DeleteCopyOperation(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM copy_operation WHERE id=@id", sql.Named("id", id))
+	if err != nil {
+		return fmt.Errorf("delete failed: %w", err)
+	}
+
+	return sqliteCheckAffected(result, ErrCopyOperationNotFound)
+}
+
+// listCopyOperations gets the copy operations selected by query `stmt`.
+func (r *SqliteRepository) listCopyOperations(ctx context.Context, stmt string, args ...any) ([]models.CopyOperation, error) {
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	ops := make([]models.CopyOperation, 0)
+	for rows.Next() {
+		op, err := scanSqliteCopyOperation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		ops = append(ops, op)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return ops, nil
+}
+
+// sqliteCopyOperationArgs returns the named arguments for `op`. The warnings
+// and read errors are stored as JSON since SQLite doesn't have array types.
+func sqliteCopyOperationArgs(op models.CopyOperation) ([]any, error) {
+	warnings := op.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	warningsJson, err := json.Marshal(warnings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode warnings: %w", err)
+	}
+
+	readErrors := op.ReadErrors
+	if readErrors == nil {
+		readErrors = []models.ReadError{}
+	}
+
+	readErrorsJson, err := json.Marshal(readErrors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode read errors: %w", err)
+	}
+
+	return []any{
+		sql.Named("id", op.Id),
+		sql.Named("driveId", op.DriveId),
+		sql.Named("discLabel", op.DiscLabel),
+		sql.Named("outputDir", op.OutputDir),
+		sql.Named("state", string(op.State)),
+		sql.Named("startedAt", formatSqliteTime(op.StartedAt)),
+		sql.Named("endedAt", formatSqliteNullTime(op.EndedAt)),
+		sql.Named("warnings", string(warningsJson)),
+		sql.Named("failureReason", op.FailureReason),
+		sql.Named("damaged", op.Damaged),
+		sql.Named("readErrorCount", op.ReadErrorCount),
+		sql.Named("readErrors", string(readErrorsJson)),
+		sql.Named("discId", sql.NullInt64{Int64: int64(op.DiscId), Valid: op.DiscId != 0}),
+		sql.Named("manifest", sql.NullString{String: string(op.Manifest), Valid: op.Manifest != nil}),
+	}, nil
+}
+
+func scanSqliteCopyOperation(row interface{ Scan(dest ...any) error }) (models.CopyOperation, error) {
+	var op models.CopyOperation
+	var state string
+	var startedAt, endedAt sqliteTime
+	var warnings, readErrors string
+	var discId sql.NullInt64
+	var manifest sql.NullString
+	err := row.Scan(
+		&op.Id,
+		&op.DriveId,
+		&op.DiscLabel,
+		&op.OutputDir,
+		&state,
+		&startedAt,
+		&endedAt,
+		&warnings,
+		&op.FailureReason,
+		&op.Damaged,
+		&op.ReadErrorCount,
+		&readErrors,
+		&discId,
+		&manifest,
+	)
+	if err != nil {
+		return op, err
+	}
+
+	op.State = models.CopyOperationState(state)
+	op.StartedAt = time.Time(startedAt)
+	op.EndedAt = time.Time(endedAt)
+	op.DiscId = int(discId.Int64)
+	if manifest.Valid {
+		op.Manifest = json.RawMessage(manifest.String)
+	}
+
+	if err := json.Unmarshal([]byte(warnings), &op.Warnings); err != nil {
+		return op, fmt.Errorf("failed to decode warnings: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(readErrors), &op.ReadErrors); err != nil {
+		return op, fmt.Errorf("failed to decode read errors: %w", err)
+	}
+
+	return op, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// AddCopyProgressSample adds progress sample `sample` to its copy operation.
+func (r *SqliteRepository) AddCopyProgressSample(ctx context.Context, sample models.CopyProgressSample) error {
+	if _, err := r.db.ExecContext(ctx, insertCopyProgressSampleStmt, sqliteCopyProgressSampleArgs(sample)...); err != nil {
+		return fmt.Errorf("insert failed: %w", err)
+	}
+
+	return nil
+}
+
+// ListCopyProgressSamples gets the progress samples of the copy operation with
+// identifier `id` ordered from oldest to newest.
+func (r *SqliteRepository) ListCopyProgressSamples(ctx context.Context, id int) ([]models.CopyProgressSample, error) {
+	stmt := "SELECT " + copyProgressSampleColumns + ` FROM copy_progress_sample
+		WHERE copy_operation_id=@id ORDER BY sampled_at`
+	rows, err := r.db.QueryContext(ctx, stmt, sql.Named("id", id))
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	samples := make([]models.CopyProgressSample, 0)
+	for rows.Next() {
+		var s models.CopyProgressSample
+		var sampledAt sqliteTime
+		if err := rows.Scan(&s.CopyOperationId, &sampledAt, &s.Title, &s.Bytes, &s.Percent); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		s.SampledAt = time.Time(sampledAt)
+		samples = append(samples, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return samples, nil
+}
+
+// ReplaceCopyProgressSamples replaces the progress samples of the copy
+// operation with identifier `id` with `samples`.
+func (r *SqliteRepository) ReplaceCopyProgressSamples(ctx context.Context, id int, samples []models.CopyProgressSample) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt := "DELETE FROM copy_progress_sample WHERE copy_operation_id=@id"
+	if _, err := tx.ExecContext(ctx, stmt, sql.Named("id", id)); err != nil {
+		return fmt.Errorf("delete failed: %w", err)
+	}
+
+	for _, sample := range samples {
+		sample.CopyOperationId = id
+		if _, err := tx.ExecContext(ctx, insertCopyProgressSampleStmt, sqliteCopyProgressSampleArgs(sample)...); err != nil {
+			return fmt.Errorf("insert failed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+
+	return nil
+}
+
+func sqliteCopyProgressSampleArgs(sample models.CopyProgressSample) []any {
+	return []any{
+		sql.Named("id", sample.CopyOperationId),
+		sql.Named("sampledAt", formatSqliteTime(sample.SampledAt)),
+		sql.Named("title", sample.Title),
+		sql.Named("bytes", sample.Bytes),
+		sql.Named("percent", sample.Percent),
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// CreateDriveCommand adds the pending command `cmd` to the database and updates
+// its Id with the identifier assigned by the database.
+func (r *SqliteRepository) CreateDriveCommand(ctx context.Context, cmd *models.DriveCommand) error {
+	stmt := `INSERT INTO drive_command (drive_id, command, args, status, created_at)
+		VALUES (@driveId, @command, @args, @status, @createdAt)
+		RETURNING id`
+	args := []any{
+		sql.Named("driveId", cmd.DriveId),
+		sql.Named("command", string(cmd.Command)),
+		sql.Named("args", sql.NullString{String: string(cmd.Args), Valid: cmd.Args != nil}),
+		sql.Named("status", string(models.CommandStatusPending)),
+		sql.Named("createdAt", formatSqliteTime(cmd.CreatedAt)),
+	}
+	if err := r.db.QueryRowContext(ctx, stmt, args...).Scan(&cmd.Id); err != nil {
+		return fmt.Errorf("insert failed: %w", err)
+	}
+
+	cmd.Status = models.CommandStatusPending
+	return nil
+}
+
+// GetDriveCommand gets the command with identifier `id`. If the command doesn't
+// exist, ErrCommandNotFound is returned.
+func (r *SqliteRepository) GetDriveCommand(ctx context.Context, id int) (models.DriveCommand, error) {
+	stmt := "SELECT " + driveCommandColumns + " FROM drive_command WHERE id=@id"
+	cmd, err := scanSqliteDriveCommand(r.db.QueryRowContext(ctx, stmt, sql.Named("id", id)))
+	if err == sql.ErrNoRows {
+		return cmd, ErrCommandNotFound
+	} else if err != nil {
+		return cmd, fmt.Errorf("query row failed: %w", err)
+	}
+
+	return cmd, nil
+}
+
+// ListPendingDriveCommands gets the pending commands for the drive with
+// identifier `driveId` ordered from oldest to newest.
+func (r *SqliteRepository) ListPendingDriveCommands(ctx context.Context, driveId int) ([]models.DriveCommand, error) {
+	stmt := "SELECT " + driveCommandColumns + " FROM drive_command WHERE drive_id=@driveId AND status=@status ORDER BY id"
+	rows, err := r.db.QueryContext(ctx, stmt,
+		sql.Named("driveId", driveId),
+		sql.Named("status", string(models.CommandStatusPending)))
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	cmds := make([]models.DriveCommand, 0)
+	for rows.Next() {
+		cmd, err := scanSqliteDriveCommand(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return cmds, nil
+}
+
+// AcknowledgeDriveCommand marks the pending command with identifier `id` as
+// acknowledged at time `at`.
+func (r *SqliteRepository) AcknowledgeDriveCommand(ctx context.Context, id int, at time.Time) error {
+	stmt := "UPDATE drive_command SET status=@acknowledged, acknowledged_at=@at WHERE id=@id AND status=@pending"
+	result, err := r.db.ExecContext(ctx, stmt,
+		sql.Named("id", id),
+		sql.Named("at", formatSqliteTime(at)),
+		sql.Named("acknowledged", string(models.CommandStatusAcknowledged)),
+		sql.Named("pending", string(models.CommandStatusPending)))
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	return sqliteCheckAffected(result, ErrCommandNotPending)
+}
+
+// CompleteDriveCommand sets the status and result of the command with
+// identifier `id` which completed at time `at`.
+func (r *SqliteRepository) CompleteDriveCommand(ctx context.Context, id int, status models.DriveCommandStatus, result string, at time.Time) error {
+	stmt := "UPDATE drive_command SET status=@status, result=@result, completed_at=@at WHERE id=@id"
+	res, err := r.db.ExecContext(ctx, stmt,
+		sql.Named("id", id),
+		sql.Named("status", string(status)),
+		sql.Named("result", result),
+		sql.Named("at", formatSqliteTime(at)))
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	return sqliteCheckAffected(res, ErrCommandNotFound)
+}
+
+func scanSqliteDriveCommand(row interface{ Scan(dest ...any) error }) (models.DriveCommand, error) {
+	var cmd models.DriveCommand
+	var command, status string
+	var args sql.NullString
+	var createdAt, acknowledgedAt, completedAt sqliteTime
+	err := row.Scan(&cmd.Id, &cmd.DriveId, &command, &args, &status, &cmd.Result, &createdAt, &acknowledgedAt, &completedAt)
+	if err != nil {
+		return cmd, err
+	}
+
+	cmd.Command = models.DriveCommandType(command)
+	cmd.Status = models.DriveCommandStatus(status)
+	if args.Valid {
+		cmd.Args = json.RawMessage(args.String)
+	}
+	cmd.CreatedAt = time.Time(createdAt)
+	cmd.AcknowledgedAt = time.Time(acknowledgedAt)
+	cmd.CompletedAt = time.Time(completedAt)
+
+	return cmd, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// AcquireDriveLease takes or renews the lease on the drive with identifier
+// `driveId` for `holder` until `expiresAt`. If another holder has an unexpired
+// lease as of `now`, their lease is returned with an error wrapping
+// ErrDriveLeaseHeld.
+func (r *SqliteRepository) AcquireDriveLease(ctx context.Context, driveId int, holder string, now, expiresAt time.Time) (models.DriveLease, error) {
+	// The acquired time is kept when the holder renews its lease.
+	stmt := `INSERT INTO drive_lease (drive_id, holder, acquired_at, expires_at)
+		VALUES (@driveId, @holder, @now, @expiresAt)
+		ON CONFLICT (drive_id) DO UPDATE SET
+		holder=excluded.holder, expires_at=excluded.expires_at,
+		acquired_at=CASE WHEN drive_lease.holder=excluded.holder
+			THEN drive_lease.acquired_at ELSE excluded.acquired_at END
+		WHERE drive_lease.holder=excluded.holder OR drive_lease.expires_at<@now
+		RETURNING holder, acquired_at, expires_at`
+	args := []any{
+		sql.Named("driveId", driveId),
+		sql.Named("holder", holder),
+		sql.Named("now", formatSqliteTime(now)),
+		sql.Named("expiresAt", formatSqliteTime(expiresAt)),
+	}
+
+	lease, err := scanSqliteDriveLease(driveId, r.db.QueryRowContext(ctx, stmt, args...))
+	if err == nil {
+		return lease, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return lease, fmt.Errorf("upsert failed: %w", err)
+	}
+
+	// No row is returned when the lease is held by someone else.
+	stmt = "SELECT holder, acquired_at, expires_at FROM drive_lease WHERE drive_id=@driveId"
+	lease, err = scanSqliteDriveLease(driveId, r.db.QueryRowContext(ctx, stmt, sql.Named("driveId", driveId)))
+	if err != nil {
+		return lease, fmt.Errorf("query row failed: %w", err)
+	}
+
+	return lease, driveLeaseHeldError(lease)
+}
+
+// ReleaseDriveLease releases the lease on the drive with identifier `driveId`
+// if it is held by `holder`.
+func (r *SqliteRepository) ReleaseDriveLease(ctx context.Context, driveId int, holder string) error {
+	stmt := "DELETE FROM drive_lease WHERE drive_id=@driveId AND holder=@holder"
+	if _, err := r.db.ExecContext(ctx, stmt, sql.Named("driveId", driveId), sql.Named("holder", holder)); err != nil {
+		return fmt.Errorf("delete failed: %w", err)
+	}
+
+	return nil
+}
+
+func scanSqliteDriveLease(driveId int, row *sql.Row) (models.DriveLease, error) {
+	lease := models.DriveLease{DriveId: driveId}
+	var acquiredAt, expiresAt sqliteTime
+	err := row.Scan(&lease.Holder, &acquiredAt, &expiresAt)
+	lease.AcquiredAt = time.Time(acquiredAt)
+	lease.ExpiresAt = time.Time(expiresAt)
+	return lease, err
+}
@@ -186,3 +186,76 @@ func GetAttributeId(v int) (AttributeId, bool) {
 	id, ok := attributeTable[v]
 	return id, ok
 }
+
+// MessageSeverity is the severity of a general (MSG) message reported by
+// MakeMKV.
+type MessageSeverity string
+
+const (
+	// MS_INFO messages are purely informational and require no action.
+	MS_INFO MessageSeverity = "info"
+
+	// MS_WARNING messages indicate a problem that doesn't prevent the operation
+	// from completing, but may affect the quality of the output.
+	MS_WARNING MessageSeverity = "warning"
+
+	// MS_FATAL messages indicate a problem that causes the operation to fail.
+	MS_FATAL MessageSeverity = "fatal"
+)
+
+// MessageCategory is the general area a general (MSG) message reported by
+// MakeMKV relates to.
+type MessageCategory string
+
+const (
+	MC_GENERAL    MessageCategory = "general"
+	MC_READ_ERROR MessageCategory = "read_error"
+	MC_PROTECTION MessageCategory = "protection"
+	MC_LICENSE    MessageCategory = "license"
+	MC_TITLE      MessageCategory = "title"
+	MC_COMPLETION MessageCategory = "completion"
+)
+
+// MessageCode describes a known MSG code reported by MakeMKV.
+type MessageCode struct {
+	Code        int
+	Severity    MessageSeverity
+	Category    MessageCategory
+	Description string
+}
+
+// messageTable maps the MSG codes reported by MakeMKV to their classification.
+// MakeMKV doesn't document these codes, so the table was built from output
+// observed from MakeMKV v1.17.7. Codes not in the table are treated as
+// informational.
+var messageTable = map[int]MessageCode{
+	1005: {1005, MS_INFO, MC_GENERAL, "MakeMKV started"},
+	1011: {1011, MS_INFO, MC_GENERAL, "using LibreDrive mode"},
+	2003: {2003, MS_WARNING, MC_READ_ERROR, "error occurred while reading the disc"},
+	3007: {3007, MS_INFO, MC_GENERAL, "using direct disc access mode"},
+	3025: {3025, MS_INFO, MC_TITLE, "title skipped because it is shorter than the minimum length"},
+	3307: {3307, MS_INFO, MC_TITLE, "title added"},
+	3309: {3309, MS_INFO, MC_TITLE, "title skipped because it is a duplicate"},
+	5003: {5003, MS_FATAL, MC_TITLE, "failed to save title"},
+	5010: {5010, MS_FATAL, MC_GENERAL, "failed to open disc"},
+	5011: {5011, MS_INFO, MC_COMPLETION, "operation successfully completed"},
+	5014: {5014, MS_INFO, MC_GENERAL, "saving titles"},
+	5021: {5021, MS_FATAL, MC_LICENSE, "evaluation period expired or application version is too old"},
+	5036: {5036, MS_INFO, MC_COMPLETION, "copy complete"},
+	5037: {5037, MS_FATAL, MC_COMPLETION, "copy complete, but one or more titles failed"},
+	5052: {5052, MS_INFO, MC_LICENSE, "evaluation version"},
+	5055: {5055, MS_FATAL, MC_LICENSE, "evaluation period expired"},
+	5069: {5069, MS_FATAL, MC_PROTECTION, "AACS decryption failed"},
+	5070: {5070, MS_FATAL, MC_PROTECTION, "BD+ processing failed"},
+}
+
+// GetMessageCode returns the classification for MSG code `v` reported by
+// MakeMKV. If the code is unknown, an informational classification is returned
+// along with false.
+func GetMessageCode(v int) (MessageCode, bool) {
+	if mc, ok := messageTable[v]; ok {
+		return mc, true
+	} else {
+		return MessageCode{v, MS_INFO, MC_GENERAL, "unknown message"}, false
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"testing"
+)
+
+func TestGetMessageCode(t *testing.T) {
+	mc, ok := GetMessageCode(2003)
+	if !ok {
+		t.Error("GetMessageCode did not find code 2003")
+	}
+
+	if mc.Severity != MS_WARNING {
+		t.Errorf("Severity = %s, expected %s", mc.Severity, MS_WARNING)
+	}
+
+	if mc.Category != MC_READ_ERROR {
+		t.Errorf("Category = %s, expected %s", mc.Category, MC_READ_ERROR)
+	}
+
+	mc, ok = GetMessageCode(5021)
+	if !ok {
+		t.Error("GetMessageCode did not find code 5021")
+	}
+
+	if mc.Severity != MS_FATAL {
+		t.Errorf("Severity = %s, expected %s", mc.Severity, MS_FATAL)
+	}
+
+	if mc.Category != MC_LICENSE {
+		t.Errorf("Category = %s, expected %s", mc.Category, MC_LICENSE)
+	}
+
+	mc, ok = GetMessageCode(-1)
+	if ok {
+		t.Error("GetMessageCode found unknown code -1")
+	}
+
+	if mc.Code != -1 {
+		t.Errorf("Code = %d, expected -1", mc.Code)
+	}
+
+	if mc.Severity != MS_INFO {
+		t.Errorf("Severity = %s, expected %s", mc.Severity, MS_INFO)
+	}
+}
+
+func TestMessageTableCodes(t *testing.T) {
+	for code, mc := range messageTable {
+		if mc.Code != code {
+			t.Errorf("messageTable[%d].Code = %d", code, mc.Code)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// fingerprintVersion is included in the fingerprint so that changing what it
+// is computed from doesn't produce fingerprints matching the old ones.
+const fingerprintVersion = "v1"
+
+// Fingerprint returns a SHA-256 hex digest identifying the content of the disc.
+// It is computed from the volume name of the disc and the duration, size, and
+// segment map of each title, so every copy of the same release has the same
+// fingerprint. An empty string is returned if MakeMKV didn't report a volume
+// name or didn't report any titles since either alone isn't enough to tell
+// discs apart.
+func (d *DiscInfo) Fingerprint() string {
+	volumeName := d.Attributes[AI_VOLUME_NAME]
+	if volumeName == "" || len(d.Titles) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n%s\n", fingerprintVersion, volumeName)
+	for i, title := range d.Titles {
+		fmt.Fprintf(&sb, "%d|%s|%s|%s\n", i,
+			title.Attributes[AI_DURATION],
+			title.Attributes[AI_DISK_SIZE_BYTES],
+			title.Attributes[AI_SEGMENTS_MAP])
+	}
+
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
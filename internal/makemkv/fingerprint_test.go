@@ -0,0 +1,87 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"testing"
+)
+
+func newFingerprintDisc(volumeName string, sizes ...string) DiscInfo {
+	disc := DiscInfo{}
+	disc.AddAttribute(Attribute{Id: AI_VOLUME_NAME, Value: volumeName})
+	disc.AddAttribute(Attribute{Id: AI_NAME, Value: "Lost"})
+	for i, size := range sizes {
+		disc.AddTitleAttribute(i, Attribute{Id: AI_DURATION, Value: "0:43:00"})
+		disc.AddTitleAttribute(i, Attribute{Id: AI_DISK_SIZE_BYTES, Value: size})
+		disc.AddTitleAttribute(i, Attribute{Id: AI_SEGMENTS_MAP, Value: "800"})
+	}
+	return disc
+}
+
+func TestFingerprint(t *testing.T) {
+	disc := newFingerprintDisc("LOST_S1_D1", "7516192768", "7600000000")
+
+	fp := disc.Fingerprint()
+	if len(fp) != 64 {
+		t.Errorf("Fingerprint = %s, expected a SHA-256 hex digest", fp)
+	}
+
+	same := newFingerprintDisc("LOST_S1_D1", "7516192768", "7600000000")
+	same.Attributes[AI_NAME] = "Lost: Season 1"
+	if same.Fingerprint() != fp {
+		t.Error("Fingerprint changed when an attribute not used for it changed")
+	}
+
+	different := []DiscInfo{
+		newFingerprintDisc("LOST_S1_D2", "7516192768", "7600000000"),
+		newFingerprintDisc("LOST_S1_D1", "7516192768", "7600000001"),
+		newFingerprintDisc("LOST_S1_D1", "7516192768"),
+	}
+
+	for _, d := range different {
+		if d.Fingerprint() == fp {
+			t.Errorf("Fingerprint of %+v matched a different disc", d)
+		}
+	}
+
+	empty := DiscInfo{}
+	if empty.Fingerprint() != "" {
+		t.Error("Fingerprint of a disc without information was not empty")
+	}
+
+	noVolumeName := newFingerprintDisc("", "7516192768")
+	if noVolumeName.Fingerprint() != "" {
+		t.Error("Fingerprint of a disc without a volume name was not empty")
+	}
+
+	noTitles := newFingerprintDisc("LOST_S1_D1")
+	if noTitles.Fingerprint() != "" {
+		t.Error("Fingerprint of a disc without titles was not empty")
+	}
+}
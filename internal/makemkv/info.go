@@ -30,6 +30,7 @@ package makemkv
 
 import (
 	"errors"
+	"strconv"
 )
 
 const (
@@ -160,6 +161,34 @@ func (d *DiscInfo) AddStreamAttribute(stream, title int, attr Attribute) error {
 	}
 }
 
+// AddMessage adds the information in the disc, title, or stream information
+// message `msg` to the disc. Other messages are ignored.
+func (d *DiscInfo) AddMessage(msg any) error {
+	switch m := msg.(type) {
+	case TitleCountMessage:
+		d.TitleCount = m.Count
+	case DiscInfoMessage:
+		return d.AddAttribute(m.Attribute)
+	case TitleInfoMessage:
+		return d.AddTitleAttribute(m.Index, m.Attribute)
+	case StreamInfoMessage:
+		return d.AddStreamAttribute(m.Index, m.TitleIndex, m.Attribute)
+	}
+	return nil
+}
+
+// Size returns the number of bytes the titles of the disc take up as reported
+// by MakeMKV. Titles whose size wasn't reported are skipped.
+func (d *DiscInfo) Size() int64 {
+	var size int64
+	for _, t := range d.Titles {
+		if n, err := strconv.ParseInt(t.Attributes[AI_DISK_SIZE_BYTES], 10, 64); err == nil && n > 0 {
+			size += n
+		}
+	}
+	return size
+}
+
 func (d *DiscInfo) ensureTitleExists(title int) error {
 	if title < 0 {
 		return errors.New("title index cannot be negative")
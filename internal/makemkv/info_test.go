@@ -201,3 +201,14 @@ func TestAddStreamAttributeToDiscInfo(t *testing.T) {
 		t.Error("AddStreamAttribute did not return an error for out of bounds index")
 	}
 }
+
+func TestDiscInfoSize(t *testing.T) {
+	disc := DiscInfo{}
+	disc.AddTitleAttribute(0, Attribute{AI_DISK_SIZE_BYTES, "7516192768"})
+	disc.AddTitleAttribute(1, Attribute{AI_DISK_SIZE, "26.4 GB"})
+	disc.AddTitleAttribute(2, Attribute{AI_DISK_SIZE_BYTES, "1073741824"})
+
+	if size := disc.Size(); size != 8589934592 {
+		t.Errorf("Size() = %d, expected 8589934592", size)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// MSG codes that contain version or license information.
+const (
+	msgVersion           = 1005
+	msgVersionTooOld     = 5021
+	msgEvaluation        = 5052
+	msgEvaluationExpired = 5055
+)
+
+// LicenseState is the state of MakeMKV's license (registration or beta key).
+type LicenseState string
+
+const (
+	// LS_UNKNOWN is the state before MakeMKV has been run.
+	LS_UNKNOWN LicenseState = "unknown"
+
+	// LS_VALID is the state when MakeMKV started without reporting any
+	// license problems.
+	LS_VALID LicenseState = "valid"
+
+	// LS_EVALUATION is the state when MakeMKV reports that it is running in
+	// its evaluation period.
+	LS_EVALUATION LicenseState = "evaluation"
+
+	// LS_EXPIRED is the state when MakeMKV reports that the evaluation period
+	// or beta key has expired. MakeMKV will not copy Blu-ray discs in this
+	// state.
+	LS_EXPIRED LicenseState = "expired"
+)
+
+var (
+	versionRegex = regexp.MustCompile(`MakeMKV v(\S+)`)
+	daysRegex    = regexp.MustCompile(`(\d+) day`)
+	dateRegex    = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+)
+
+// License is the version and license information reported by MakeMKV when it
+// starts.
+type License struct {
+	// Version is the MakeMKV version (e.g. 1.17.7).
+	Version string
+
+	// State is the state of the license.
+	State LicenseState
+
+	// ExpiresAt is when the license expires. It will be the zero time if
+	// MakeMKV didn't report an expiration date.
+	ExpiresAt time.Time
+}
+
+// Update updates the license information using MakeMKV message `msg`. Returns
+// true if `msg` contained version or license information.
+func (l *License) Update(msg GeneralMessage) bool {
+	switch msg.Code {
+	case msgVersion:
+		m := versionRegex.FindStringSubmatch(msg.Message)
+		if m == nil {
+			return false
+		}
+		l.Version = m[1]
+		if l.State == "" || l.State == LS_UNKNOWN {
+			l.State = LS_VALID
+		}
+		return true
+	case msgVersionTooOld, msgEvaluationExpired:
+		l.State = LS_EXPIRED
+		return true
+	case msgEvaluation:
+		l.State = LS_EVALUATION
+		if m := daysRegex.FindStringSubmatch(msg.Message); m != nil {
+			if days, err := strconv.Atoi(m[1]); err == nil {
+				l.ExpiresAt = time.Now().AddDate(0, 0, days)
+			}
+		}
+		return true
+	}
+
+	if mc, _ := GetMessageCode(msg.Code); mc.Category != MC_LICENSE {
+		return false
+	}
+
+	// Some license messages (e.g. for beta keys) include the expiration date
+	// instead of the number of days remaining.
+	if date := dateRegex.FindString(msg.Message); date != "" {
+		if t, err := time.Parse(time.DateOnly, date); err == nil {
+			l.ExpiresAt = t
+			return true
+		}
+	}
+
+	return false
+}
+
+// ExpiresWithin returns true if the license has a known expiration date that is
+// within duration `d` from now.
+func (l *License) ExpiresWithin(d time.Duration) bool {
+	if l.ExpiresAt.IsZero() {
+		return false
+	}
+
+	return time.Until(l.ExpiresAt) < d
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLicenseVersion(t *testing.T) {
+	license := License{State: LS_UNKNOWN}
+
+	if !license.Update(GeneralMessage{Code: 1005, Message: "MakeMKV v1.17.7 linux(x64-release) started"}) {
+		t.Error("Update did not handle the version message")
+	}
+
+	if license.Version != "1.17.7" {
+		t.Errorf("Version = %s, expected 1.17.7", license.Version)
+	}
+
+	if license.State != LS_VALID {
+		t.Errorf("State = %s, expected %s", license.State, LS_VALID)
+	}
+
+	if license.Update(GeneralMessage{Code: 3007, Message: "Using direct disc access mode"}) {
+		t.Error("Update handled a message without license information")
+	}
+}
+
+func TestLicenseExpired(t *testing.T) {
+	license := License{State: LS_UNKNOWN}
+	license.Update(GeneralMessage{Code: 1005, Message: "MakeMKV v1.17.7 linux(x64-release) started"})
+
+	if !license.Update(GeneralMessage{Code: 5021, Message: "This application version is too old."}) {
+		t.Error("Update did not handle the expired message")
+	}
+
+	if license.State != LS_EXPIRED {
+		t.Errorf("State = %s, expected %s", license.State, LS_EXPIRED)
+	}
+}
+
+func TestLicenseEvaluation(t *testing.T) {
+	license := License{State: LS_UNKNOWN}
+
+	if !license.Update(GeneralMessage{Code: 5052, Message: "Evaluation version, 3 day(s) out of 30 remaining"}) {
+		t.Error("Update did not handle the evaluation message")
+	}
+
+	if license.State != LS_EVALUATION {
+		t.Errorf("State = %s, expected %s", license.State, LS_EVALUATION)
+	}
+
+	if !license.ExpiresWithin(4 * 24 * time.Hour) {
+		t.Errorf("ExpiresAt = %s, expected within 4 days", license.ExpiresAt)
+	}
+
+	if license.ExpiresWithin(2 * 24 * time.Hour) {
+		t.Errorf("ExpiresAt = %s, expected after 2 days", license.ExpiresAt)
+	}
+}
+
+func TestLicenseExpiresWithinUnknownDate(t *testing.T) {
+	license := License{State: LS_VALID}
+
+	if license.ExpiresWithin(time.Hour) {
+		t.Error("ExpiresWithin returned true without an expiration date")
+	}
+}
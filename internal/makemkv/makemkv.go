@@ -29,3 +29,197 @@
 // Package makemkv provides the ability to run makemkv, which is the external
 // program used for copying DVD and Blue-ray discs.
 package makemkv
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxLineLength is the maximum length of a line of output from MakeMKV.
+const maxLineLength = 1024 * 1024
+
+// waitDelay is how long to wait for MakeMKV's output to close after the
+// process was killed before giving up on it.
+const waitDelay = 5 * time.Second
+
+// Handler is called for every message parsed from MakeMKV's output.
+type Handler func(msg any)
+
+// Runner runs the MakeMKV command line program.
+type Runner struct {
+	// Exe is the path to the MakeMKV command line program.
+	Exe string
+
+	// OutputTimeout is how long MakeMKV can go without outputting anything
+	// before it is considered stalled and killed. Zero disables the check.
+	OutputTimeout time.Duration
+
+	// ProgressTimeout is how long MakeMKV can go without its progress
+	// changing before it is considered stalled and killed. Zero disables the
+	// check.
+	ProgressTimeout time.Duration
+
+	// Transcript receives the raw output (stdout and stderr) of every run if
+	// not nil. Each run is preceded by a header line with the command.
+	Transcript io.Writer
+
+	// Options are passed to MakeMKV on its command line for every run.
+	Options Options
+
+	// Home is the home directory MakeMKV is run with if not empty. Settings
+	// are written to its settings file before every run so that MakeMKV
+	// doesn't depend on the settings of the user running the service.
+	Home string
+
+	// Settings are the MakeMKV settings written when Home is set.
+	Settings map[string]string
+}
+
+// Run runs MakeMKV in robot mode with arguments `args` calling `handler` for
+// every message in its output. Run blocks until the process exits. The process
+// is killed if `ctx` is cancelled or if the watchdog determines it has stalled
+// in which case the returned error will wrap ErrStalled.
+func (r *Runner) Run(ctx context.Context, args []string, handler Handler) error {
+	args = append(append([]string{"-r", "--progress=-same"}, r.Options.args()...), args...)
+
+	cmd := exec.CommandContext(ctx, r.Exe, args...)
+	if r.Home != "" {
+		if err := WriteSettings(r.Home, r.Settings); err != nil {
+			return err
+		}
+		cmd.Env = append(os.Environ(), "HOME="+r.Home)
+	}
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = waitDelay
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+
+	if r.Transcript != nil {
+		cmd.Stderr = r.Transcript
+		fmt.Fprintf(r.Transcript, "=== %s %s %s\n", time.Now().Format(time.RFC3339), r.Exe, strings.Join(args, " "))
+	}
+
+	slog.Debug("Starting MakeMKV.", "exe", r.Exe, "args", args)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start makemkv: %w", err)
+	}
+
+	wd := newWatchdog(r.OutputTimeout, r.ProgressTimeout)
+	done := make(chan struct{})
+	defer close(done)
+	go wd.watch(done, func() {
+		if err := killProcessGroup(cmd); err != nil {
+			slog.Error("Failed to kill stalled MakeMKV.", "error", err)
+		}
+	})
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		line := scanner.Text()
+		wd.output()
+		if r.Transcript != nil {
+			io.WriteString(r.Transcript, line+"\n")
+		}
+		msg, err := ParseMessage(line)
+		if err != nil {
+			slog.Debug("Ignoring MakeMKV output.", "line", line, "error", err)
+			continue
+		}
+		if m, ok := msg.(ProgressValueMessage); ok {
+			wd.progress(m)
+		}
+		if handler != nil {
+			handler(msg)
+		}
+	}
+
+	// Read errors are only logged since the exit status of the process is
+	// the better indicator of whether the run was successful.
+	if err := scanner.Err(); err != nil {
+		slog.Warn("Failed to read MakeMKV output.", "error", err)
+	}
+
+	err = cmd.Wait()
+	if r.Transcript != nil {
+		fmt.Fprintf(r.Transcript, "=== %s exited: %v\n", time.Now().Format(time.RFC3339), cmd.ProcessState)
+	}
+	if reason := wd.stalled(); reason != "" {
+		return fmt.Errorf("%w: %s", ErrStalled, reason)
+	}
+	if err != nil {
+		return fmt.Errorf("makemkv failed: %w", err)
+	}
+
+	return nil
+}
+
+// Info runs MakeMKV's info command for the disc in the drive at `device` (e.g.
+// /dev/sr0) and returns the disc information it reports. If `handler` is not
+// nil, it is called for every message in MakeMKV's output.
+func (r *Runner) Info(ctx context.Context, device string, handler Handler) (DiscInfo, error) {
+	var disc DiscInfo
+
+	err := r.Run(ctx, []string{"info", "dev:" + device}, func(msg any) {
+		if err := disc.AddMessage(msg); err != nil {
+			slog.Debug("Failed to add disc information.", "msg", msg, "error", err)
+		}
+		if handler != nil {
+			handler(msg)
+		}
+	})
+
+	return disc, err
+}
+
+// Mkv runs MakeMKV's mkv command which copies all titles from the disc in the
+// drive at `device` (e.g. /dev/sr0) into the directory `outDir`. If `handler`
+// is not nil, it is called for every message in MakeMKV's output.
+func (r *Runner) Mkv(ctx context.Context, device, outDir string, handler Handler) error {
+	return r.Run(ctx, []string{"mkv", "dev:" + device, "all", outDir}, handler)
+}
+
+// Backup runs MakeMKV's backup command to copy the whole disc in the drive at
+// `device` to the directory `outDir`. The backup is only decrypted if the
+// Decrypt option is set. If `handler` is not nil, it is called for every
+// message in MakeMKV's output.
+func (r *Runner) Backup(ctx context.Context, device, outDir string, handler Handler) error {
+	return r.Run(ctx, []string{"backup", "dev:" + device, outDir}, handler)
+}
+
+// Probe runs MakeMKV without accessing a disc to get the version and license
+// information it reports when it starts.
+func (r *Runner) Probe(ctx context.Context) (License, error) {
+	license := License{State: LS_UNKNOWN}
+
+	// disc:9999 is used since it won't match any drive which makes MakeMKV
+	// exit right after reporting its version and license information.
+	err := r.Run(ctx, []string{"info", "disc:9999"}, func(msg any) {
+		if m, ok := msg.(GeneralMessage); ok {
+			license.Update(m)
+		}
+	})
+
+	// MakeMKV exits with an error since the disc doesn't exist, so the error
+	// is only returned if MakeMKV didn't report its version.
+	if license.Version == "" {
+		if err == nil {
+			err = errors.New("makemkv did not report its version")
+		}
+		return license, err
+	}
+
+	return license, nil
+}
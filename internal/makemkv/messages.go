@@ -59,10 +59,12 @@ type DriveMessage struct {
 }
 
 // GeneralMessage represents a 'MSG' message from MakeMKV which is a general
-// information message.
+// information message. Params are the values substituted into the message's
+// format string (e.g. the file and offset of a read error).
 type GeneralMessage struct {
 	Code    int
 	Message string
+	Params  []string
 }
 
 // ProgressTitleMessage represents either a 'PRGT' or 'PRGC' message from
@@ -116,7 +118,7 @@ func ParseMessage(line string) (any, error) {
 		return nil, errors.New("failed to get message id and data")
 	}
 
-	data := strings.Split(parts[1], ",")
+	data := splitData(parts[1])
 	dataLength := len(data)
 
 	// NOTE: Some data is ignored here because it seems to be data only
@@ -174,7 +176,11 @@ func ParseMessage(line string) (any, error) {
 			return nil, errors.New("[MSG] failed to parse code")
 		}
 		message := strings.Trim(data[3], "\"")
-		return GeneralMessage{int(code), message}, nil
+		var params []string
+		for _, p := range data[min(dataLength, 5):] {
+			params = append(params, strings.Trim(p, "\""))
+		}
+		return GeneralMessage{int(code), message, params}, nil
 	case "PRGT":
 		if dataLength < 3 {
 			return nil, errors.New("[PRGT] too few data items")
@@ -273,3 +279,23 @@ func ParseMessage(line string) (any, error) {
 		return nil, errors.New("unrecognized message received")
 	}
 }
+
+// splitData splits the comma separated data of a message. Commas within quoted
+// strings are not treated as separators.
+func splitData(s string) []string {
+	data := make([]string, 0)
+	quoted := false
+	start := 0
+	for i, c := range s {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				data = append(data, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(data, s[start:])
+}
@@ -108,6 +108,32 @@ func TestParseGeneralMessage(t *testing.T) {
 	}
 }
 
+func TestParseGeneralMessageParams(t *testing.T) {
+	message, err := ParseMessage("MSG:2003,0,3,\"Error 'Scsi error - MEDIUM ERROR' occurred while reading '/BDMV/STREAM/00800.m2ts' at offset '1234', retrying\",\"Error '%1' occurred while reading '%2' at offset '%3'\",\"Scsi error - MEDIUM ERROR\",\"/BDMV/STREAM/00800.m2ts\",\"1234\"")
+	if err != nil {
+		t.Error("ParseMessage returned an error")
+	}
+
+	msg, ok := message.(GeneralMessage)
+	if !ok {
+		t.Error("ParseMessage returned an incorrect type")
+	}
+
+	expected := "Error 'Scsi error - MEDIUM ERROR' occurred while reading '/BDMV/STREAM/00800.m2ts' at offset '1234', retrying"
+	if msg.Message != expected {
+		t.Errorf("Message = %s, expected \"%s\"", msg.Message, expected)
+	}
+
+	if len(msg.Params) != 3 {
+		t.Errorf("len(Params) = %d, expected 3", len(msg.Params))
+		return
+	}
+
+	if msg.Params[1] != "/BDMV/STREAM/00800.m2ts" {
+		t.Errorf("Params[1] = %s, expected \"/BDMV/STREAM/00800.m2ts\"", msg.Params[1])
+	}
+}
+
 func TestParseProgressTitleMessage(t *testing.T) {
 	msg, err := ParseMessage("PRGC:3400,7,\"Processing AV clips\"")
 	if err != nil {
@@ -0,0 +1,170 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options are the options passed to MakeMKV on its command line. The zero
+// value of each option leaves it at MakeMKV's default.
+type Options struct {
+	// MinLength is the minimum length in seconds of the titles MakeMKV
+	// includes.
+	MinLength int
+
+	// CacheSize is the size of MakeMKV's read cache in megabytes.
+	CacheSize int
+
+	// DirectIO enables or disables direct disc access if not nil.
+	DirectIO *bool
+
+	// Profile is the path to the profile file MakeMKV uses for conversion.
+	Profile string
+
+	// Decrypt specifies whether a backup is decrypted.
+	Decrypt bool
+}
+
+// args returns the command line arguments for the options.
+func (o *Options) args() []string {
+	var args []string
+	if o.MinLength > 0 {
+		args = append(args, "--minlength="+strconv.Itoa(o.MinLength))
+	}
+	if o.CacheSize > 0 {
+		args = append(args, "--cache="+strconv.Itoa(o.CacheSize))
+	}
+	if o.DirectIO != nil {
+		args = append(args, "--directio="+strconv.FormatBool(*o.DirectIO))
+	}
+	if o.Profile != "" {
+		args = append(args, "--profile="+o.Profile)
+	}
+	if o.Decrypt {
+		args = append(args, "--decrypt")
+	}
+	return args
+}
+
+// settingsFile is the path of MakeMKV's settings file relative to its home
+// directory.
+var settingsFile = filepath.Join(".MakeMKV", "settings.conf")
+
+// SettingsPath returns the path of the settings file MakeMKV reads when its
+// home directory is `home`.
+func SettingsPath(home string) string {
+	return filepath.Join(home, settingsFile)
+}
+
+// WriteSettings replaces the settings file within the home directory `home`
+// with `settings`. The home directory is created if it doesn't exist and is
+// only accessible by the service since the settings can include the license
+// key.
+func WriteSettings(home string, settings map[string]string) error {
+	dir := filepath.Dir(SettingsPath(home))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create makemkv home directory: %w", err)
+	}
+	if err := os.Chmod(home, 0700); err != nil {
+		return fmt.Errorf("failed to restrict makemkv home directory: %w", err)
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("# Generated by artie-copy before each run of MakeMKV. Changes are overwritten.\n")
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "%s = %s\n", key, strconv.Quote(settings[key]))
+	}
+
+	// The file is replaced atomically since drives can start MakeMKV at the
+	// same time.
+	f, err := os.CreateTemp(dir, "settings.conf.*")
+	if err != nil {
+		return fmt.Errorf("failed to create makemkv settings: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(sb.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write makemkv settings: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write makemkv settings: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), SettingsPath(home)); err != nil {
+		return fmt.Errorf("failed to replace makemkv settings: %w", err)
+	}
+
+	return nil
+}
+
+// ReadSettings reads the MakeMKV settings file at `path`. Lines that aren't
+// settings are ignored.
+func ReadSettings(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		settings[strings.TrimSpace(key)] = value
+	}
+
+	return settings, scanner.Err()
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOptionsArgs(t *testing.T) {
+	directIO := false
+	options := Options{
+		MinLength: 300,
+		CacheSize: 1024,
+		DirectIO:  &directIO,
+		Profile:   "/etc/artie/default.mmcp.xml",
+		Decrypt:   true,
+	}
+
+	expected := []string{
+		"--minlength=300",
+		"--cache=1024",
+		"--directio=false",
+		"--profile=/etc/artie/default.mmcp.xml",
+		"--decrypt",
+	}
+	if args := options.args(); !slices.Equal(args, expected) {
+		t.Errorf("args() = %q, expected %q", args, expected)
+	}
+
+	if args := (&Options{}).args(); len(args) != 0 {
+		t.Errorf("args() = %q, expected no arguments", args)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "home")
+	settings := map[string]string{
+		"app_Key":                "T-abc123",
+		"dvd_MinimumTitleLength": "300",
+	}
+
+	if err := WriteSettings(home, settings); err != nil {
+		t.Fatal("WriteSettings returned an error:", err)
+	}
+
+	info, err := os.Stat(home)
+	if err != nil {
+		t.Fatal("Failed to stat home directory:", err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("home directory mode = %v, expected 0700", info.Mode().Perm())
+	}
+
+	read, err := ReadSettings(SettingsPath(home))
+	if err != nil {
+		t.Fatal("ReadSettings returned an error:", err)
+	}
+
+	if len(read) != len(settings) {
+		t.Errorf("ReadSettings() = %v, expected %v", read, settings)
+	}
+	for key, value := range settings {
+		if read[key] != value {
+			t.Errorf("ReadSettings()[%s] = '%s', expected '%s'", key, read[key], value)
+		}
+	}
+
+	if err := WriteSettings(home, map[string]string{"app_Key": "T-def456"}); err != nil {
+		t.Fatal("WriteSettings returned an error:", err)
+	}
+
+	read, err = ReadSettings(SettingsPath(home))
+	if err != nil {
+		t.Fatal("ReadSettings returned an error:", err)
+	}
+	if len(read) != 1 || read["app_Key"] != "T-def456" {
+		t.Errorf("ReadSettings() = %v, expected the settings to be replaced", read)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ReadErrorKind classifies a read error reported by MakeMKV based on the error
+// reported by the drive.
+type ReadErrorKind string
+
+const (
+	// REK_MEDIUM is an error caused by the disc itself, such as a scratch or
+	// other damage, that the drive couldn't correct.
+	REK_MEDIUM ReadErrorKind = "medium"
+
+	// REK_HARDWARE is an error reported by the drive's hardware.
+	REK_HARDWARE ReadErrorKind = "hardware"
+
+	// REK_TIMEOUT is an error caused by the drive not responding in time.
+	REK_TIMEOUT ReadErrorKind = "timeout"
+
+	// REK_OTHER is any other read error.
+	REK_OTHER ReadErrorKind = "other"
+)
+
+// ReadError is a read error reported by MakeMKV while reading a disc.
+type ReadError struct {
+	Kind ReadErrorKind
+
+	// Error is the error reported by the drive.
+	Error string
+
+	// Source is the file on the disc being read when the error occurred.
+	Source string
+
+	// Offset is the offset in bytes within Source where the error occurred.
+	// It will be -1 if MakeMKV didn't report the offset.
+	Offset int64
+}
+
+// readErrorRegex matches the message text of a read error for when MakeMKV
+// doesn't provide the message parameters.
+var readErrorRegex = regexp.MustCompile(`Error '(.*)' occurred while reading '(.*?)'(?: at offset '(\d+)')?`)
+
+// ParseReadError returns the read error reported by MakeMKV message `msg`.
+// Returns false if `msg` isn't a read error.
+func ParseReadError(msg GeneralMessage) (ReadError, bool) {
+	if mc, _ := GetMessageCode(msg.Code); mc.Category != MC_READ_ERROR {
+		return ReadError{}, false
+	}
+
+	params := msg.Params
+	if len(params) < 2 {
+		if m := readErrorRegex.FindStringSubmatch(msg.Message); m != nil {
+			params = m[1:]
+		}
+	}
+
+	re := ReadError{Kind: REK_OTHER, Offset: -1}
+	if len(params) > 0 {
+		re.Error = params[0]
+		re.Kind = classifyReadError(params[0])
+	}
+	if len(params) > 1 {
+		re.Source = params[1]
+	}
+	if len(params) > 2 {
+		if offset, err := strconv.ParseInt(params[2], 10, 64); err == nil {
+			re.Offset = offset
+		}
+	}
+
+	return re, true
+}
+
+func classifyReadError(err string) ReadErrorKind {
+	err = strings.ToUpper(err)
+	switch {
+	case strings.Contains(err, "MEDIUM ERROR"):
+		return REK_MEDIUM
+	case strings.Contains(err, "HARDWARE ERROR"):
+		return REK_HARDWARE
+	case strings.Contains(err, "TIMEOUT"):
+		return REK_TIMEOUT
+	default:
+		return REK_OTHER
+	}
+}
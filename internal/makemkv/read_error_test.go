@@ -0,0 +1,82 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"testing"
+)
+
+func TestParseReadError(t *testing.T) {
+	msg := GeneralMessage{
+		Code:    2003,
+		Message: "Error 'Scsi error - HARDWARE ERROR' occurred while reading '/VIDEO_TS/VTS_01_1.VOB' at offset '2048'",
+	}
+
+	re, ok := ParseReadError(msg)
+	if !ok {
+		t.Error("ParseReadError did not recognize the read error")
+		return
+	}
+
+	if re.Kind != REK_HARDWARE {
+		t.Errorf("Kind = %s, expected %s", re.Kind, REK_HARDWARE)
+	}
+
+	if re.Error != "Scsi error - HARDWARE ERROR" {
+		t.Errorf("Error = %s, expected \"Scsi error - HARDWARE ERROR\"", re.Error)
+	}
+
+	if re.Source != "/VIDEO_TS/VTS_01_1.VOB" {
+		t.Errorf("Source = %s, expected \"/VIDEO_TS/VTS_01_1.VOB\"", re.Source)
+	}
+
+	if re.Offset != 2048 {
+		t.Errorf("Offset = %d, expected 2048", re.Offset)
+	}
+
+	msg.Params = []string{"Scsi error - MEDIUM ERROR", "/BDMV/STREAM/00001.m2ts"}
+
+	re, ok = ParseReadError(msg)
+	if !ok {
+		t.Error("ParseReadError did not recognize the read error")
+		return
+	}
+
+	if re.Kind != REK_MEDIUM {
+		t.Errorf("Kind = %s, expected %s", re.Kind, REK_MEDIUM)
+	}
+
+	if re.Offset != -1 {
+		t.Errorf("Offset = %d, expected -1", re.Offset)
+	}
+
+	if _, ok := ParseReadError(GeneralMessage{Code: 3007, Message: "Using direct disc access mode"}); ok {
+		t.Error("ParseReadError recognized a message that isn't a read error")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrStalled = errors.New("makemkv stalled")
+)
+
+// watchdogInterval is how often the watchdog checks whether MakeMKV stalled.
+const watchdogInterval = time.Second
+
+// watchdog detects when MakeMKV has stalled by tracking the time since it last
+// output anything and the time since its progress last changed.
+type watchdog struct {
+	outputTimeout   time.Duration
+	progressTimeout time.Duration
+
+	mu           sync.Mutex
+	lastOutput   time.Time
+	lastProgress time.Time
+	value        ProgressValueMessage
+	reason       string
+}
+
+func newWatchdog(outputTimeout, progressTimeout time.Duration) *watchdog {
+	now := time.Now()
+	return &watchdog{
+		outputTimeout:   outputTimeout,
+		progressTimeout: progressTimeout,
+		lastOutput:      now,
+		lastProgress:    now,
+	}
+}
+
+// output records that MakeMKV output a line.
+func (w *watchdog) output() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastOutput = time.Now()
+}
+
+// progress records a progress value reported by MakeMKV. MakeMKV can keep
+// reporting the same progress value while it is stuck retrying reads, so only
+// a change in value resets the progress timer.
+func (w *watchdog) progress(msg ProgressValueMessage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if msg != w.value {
+		w.value = msg
+		w.lastProgress = time.Now()
+	}
+}
+
+// stalled returns the reason MakeMKV was considered stalled or an empty string
+// if it wasn't.
+func (w *watchdog) stalled() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.reason
+}
+
+// check checks whether MakeMKV has stalled as of time `now` and records the
+// reason if it has. Returns true if MakeMKV has stalled.
+func (w *watchdog) check(now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.reason != "" {
+		return true
+	}
+
+	if w.outputTimeout > 0 && now.Sub(w.lastOutput) > w.outputTimeout {
+		w.reason = fmt.Sprintf("no output for %s", w.outputTimeout)
+	} else if w.progressTimeout > 0 && now.Sub(w.lastProgress) > w.progressTimeout {
+		w.reason = fmt.Sprintf("no progress for %s", w.progressTimeout)
+	}
+
+	return w.reason != ""
+}
+
+// watch periodically checks whether MakeMKV has stalled until `done` is closed.
+// `kill` is called once if it has.
+func (w *watchdog) watch(done <-chan struct{}, kill func()) {
+	if w.outputTimeout <= 0 && w.progressTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			if w.check(now) {
+				kill()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package makemkv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdogOutputTimeout(t *testing.T) {
+	wd := newWatchdog(time.Minute, 0)
+	start := wd.lastOutput
+
+	if wd.check(start.Add(30 * time.Second)) {
+		t.Error("check reported a stall before the output timeout")
+	}
+
+	wd.output()
+	if wd.check(wd.lastOutput.Add(30 * time.Second)) {
+		t.Error("check reported a stall after output was received")
+	}
+
+	if !wd.check(wd.lastOutput.Add(2 * time.Minute)) {
+		t.Error("check did not report a stall after the output timeout")
+	}
+
+	if wd.stalled() == "" {
+		t.Error("stalled did not return a reason")
+	}
+}
+
+func TestWatchdogProgressTimeout(t *testing.T) {
+	wd := newWatchdog(0, time.Minute)
+
+	wd.progress(ProgressValueMessage{Current: 1, Total: 1, Max: 65536})
+	changed := wd.lastProgress
+
+	wd.progress(ProgressValueMessage{Current: 1, Total: 1, Max: 65536})
+	if wd.lastProgress != changed {
+		t.Error("progress reset the timer without the progress changing")
+	}
+
+	if wd.check(changed.Add(30 * time.Second)) {
+		t.Error("check reported a stall before the progress timeout")
+	}
+
+	if !wd.check(changed.Add(2 * time.Minute)) {
+		t.Error("check did not report a stall after the progress timeout")
+	}
+}
+
+func TestWatchdogDisabled(t *testing.T) {
+	wd := newWatchdog(0, 0)
+
+	if wd.check(time.Now().Add(24 * time.Hour)) {
+		t.Error("check reported a stall with the watchdog disabled")
+	}
+}
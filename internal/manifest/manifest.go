@@ -0,0 +1,246 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package manifest defines the manifest written for every successful copy
+// operation. The manifest describes the files that were handed off to the
+// next stage of the pipeline so that it can consume them without inspecting
+// them itself.
+//
+// The manifest is JSON. Its Version field is incremented whenever a change is
+// made that a consumer written for an earlier version couldn't handle, such as
+// removing a field or changing its meaning. Fields may be added without
+// changing the version so consumers should ignore fields they don't know.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version is the version of the manifest schema written by this service.
+const Version = 1
+
+// Manifest describes the files produced by a successful copy operation.
+type Manifest struct {
+	// Version is the version of the schema the manifest follows.
+	Version int `json:"version"`
+
+	// OperationId is the identifier of the copy operation.
+	OperationId int `json:"operation_id"`
+
+	// Drive is the drive the disc was copied from.
+	Drive Drive `json:"drive"`
+
+	// Disc is the disc that was copied.
+	Disc Disc `json:"disc"`
+
+	// StartedAt is the time the copy started.
+	StartedAt time.Time `json:"started_at"`
+
+	// CompletedAt is the time the files were handed off.
+	CompletedAt time.Time `json:"completed_at"`
+
+	// ReadErrorCount is the number of read errors MakeMKV reported while
+	// copying the disc. It can be more than the sum of the files' counts
+	// since not every error can be attributed to a title.
+	ReadErrorCount int `json:"read_error_count"`
+
+	// Files are the MKV files that were produced in title order.
+	Files []File `json:"files"`
+
+	// Rejected are the MKV files MakeMKV produced that failed verification.
+	// They weren't handed off.
+	Rejected []RejectedFile `json:"rejected"`
+}
+
+// Drive identifies the drive a disc was copied from.
+type Drive struct {
+	// Name is the configured name of the drive.
+	Name string `json:"name"`
+
+	// Serial is the serial number of the drive.
+	Serial string `json:"serial"`
+
+	// Host is the host name of the machine the drive is attached to.
+	Host string `json:"host"`
+}
+
+// Disc identifies the disc that was copied.
+type Disc struct {
+	// Label is the label of the disc as reported by the system.
+	Label string `json:"label"`
+
+	// Name is the name of the disc as reported by MakeMKV.
+	Name string `json:"name"`
+
+	// VolumeName is the volume name of the disc as reported by MakeMKV.
+	VolumeName string `json:"volume_name"`
+
+	// Fingerprint identifies the disc in the disc catalog. It is empty if
+	// MakeMKV didn't report enough information to compute it.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// File describes an MKV file produced by a copy.
+type File struct {
+	// Name is the name of the file within the directory of the manifest.
+	Name string `json:"name"`
+
+	// Title is the index of the title on the disc the file was copied from
+	// or -1 if not known.
+	Title int `json:"title"`
+
+	// Duration is the duration of the title in seconds as reported by
+	// MakeMKV.
+	Duration int `json:"duration"`
+
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+
+	// SHA256 is the hex encoded SHA-256 digest of the file.
+	SHA256 string `json:"sha256"`
+
+	// ReadErrorCount is the number of read errors reported while reading
+	// the parts of the disc the title is made of.
+	ReadErrorCount int `json:"read_error_count"`
+
+	// Streams are the video, audio, and subtitle streams of the title.
+	Streams []Stream `json:"streams"`
+}
+
+// RejectedFile describes an MKV file that failed verification.
+type RejectedFile struct {
+	// Name is the name MakeMKV gave the file.
+	Name string `json:"name"`
+
+	// Title is the index of the title on the disc the file was copied from
+	// or -1 if it couldn't be determined.
+	Title int `json:"title"`
+
+	// Reason is why the file failed verification.
+	Reason string `json:"reason"`
+}
+
+// Stream describes a stream of a title.
+type Stream struct {
+	// Index is the index of the stream within the title.
+	Index int `json:"index"`
+
+	// Type is the kind of stream (e.g. Video, Audio, Subtitles).
+	Type string `json:"type"`
+
+	// Codec is the short name of the stream's codec (e.g. DTS-HD MA).
+	Codec string `json:"codec"`
+
+	// Language is the ISO 639-2 code of the stream's language or empty if it
+	// doesn't have one.
+	Language string `json:"language"`
+
+	// LanguageName is the name of the stream's language.
+	LanguageName string `json:"language_name"`
+
+	// Name is the name of the stream as reported by MakeMKV.
+	Name string `json:"name"`
+}
+
+// FileName returns the name of the manifest file of the copy operation with
+// identifier `id`. The identifier is part of the name since copies of
+// different discs can share a directory.
+func FileName(id int) string {
+	return fmt.Sprintf("manifest-%d.json", id)
+}
+
+// Write writes `m` to its manifest file in the directory `dir` and returns the
+// path of the file. It is written to a temporary file first so that it never
+// appears partially written.
+func Write(dir string, m Manifest) (string, error) {
+	bs, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	path := filepath.Join(dir, FileName(m.OperationId))
+
+	tmp, err := os.CreateTemp(dir, "."+FileName(m.OperationId)+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(bs, '\n')); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return path, nil
+}
+
+// Parse decodes the manifest in `bs`. An error is returned if the manifest's
+// version is newer than the version this package understands.
+func Parse(bs []byte) (Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return m, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if m.Version < 1 || m.Version > Version {
+		return m, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+
+	return m, nil
+}
+
+// Read reads and decodes the manifest file at `path`.
+func Read(path string) (Manifest, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return Parse(bs)
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+	dir := t.TempDir()
+
+	m := Manifest{
+		Version:     Version,
+		OperationId: 42,
+		Drive:       Drive{Name: "Drive A", Serial: "4-8-15-16-23-42", Host: "artie-01"},
+		Disc:        Disc{Label: "LOST_S1_D1", Name: "Lost", VolumeName: "LOST_S1_D1", Fingerprint: "abc123"},
+		StartedAt:   time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		CompletedAt: time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC),
+		Files: []File{{
+			Name:     "title_t00.mkv",
+			Title:    0,
+			Duration: 2580,
+			Size:     1024,
+			SHA256:   "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			Streams:  []Stream{{Index: 0, Type: "Video", Codec: "Mpeg4"}, {Index: 1, Type: "Audio", Codec: "DTS", Language: "eng", LanguageName: "English"}},
+		}},
+		Rejected: []RejectedFile{{Name: "title_t01.mkv", Title: 1, Reason: "is truncated"}},
+	}
+
+	path, err := Write(dir, m)
+	if err != nil {
+		t.Fatal("Write returned an error:", err)
+	}
+
+	if path != filepath.Join(dir, "manifest-42.json") {
+		t.Errorf("Write returned %s, expected manifest-42.json in %s", path, dir)
+	}
+
+	read, err := Read(path)
+	if err != nil {
+		t.Fatal("Read returned an error:", err)
+	}
+
+	if !reflect.DeepEqual(read, m) {
+		t.Errorf("Read returned %+v, expected %+v", read, m)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the manifest in %s, found %d files", dir, len(entries))
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		json     string
+		expected string
+	}{
+		{`{"version": 2}`, "unsupported manifest version 2"},
+		{`{}`, "unsupported manifest version 0"},
+		{`{"version": `, "failed to decode manifest"},
+	}
+
+	for _, test := range tests {
+		if _, err := Parse([]byte(test.json)); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Parse(%s) = %v, expected an error containing %q", test.json, err, test.expected)
+		}
+	}
+
+	if _, err := Parse([]byte(`{"version": 1, "added_later": true}`)); err != nil {
+		t.Errorf("Parse returned %v, expected unknown fields to be ignored", err)
+	}
+}
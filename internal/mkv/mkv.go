@@ -0,0 +1,414 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package mkv checks that Matroska files are well-formed by walking their EBML
+// structure. Only the element headers and the few elements needed to describe
+// the file are read so that large files can be checked quickly.
+package mkv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// ErrMalformed is wrapped by the errors returned when a file isn't a
+// well-formed Matroska file.
+var ErrMalformed = errors.New("malformed matroska file")
+
+// Element IDs used when walking a file. See the Matroska specification for
+// the full list.
+const (
+	idEBML           = 0x1A45DFA3
+	idDocType        = 0x4282
+	idSegment        = 0x18538067
+	idInfo           = 0x1549A966
+	idTimestampScale = 0x2AD7B1
+	idDuration       = 0x4489
+	idTracks         = 0x1654AE6B
+	idTrackEntry     = 0xAE
+	idCluster        = 0x1F43B675
+	idVoid           = 0xEC
+)
+
+// clusterChildren are the IDs of the elements that can be in a cluster. They
+// are used to find the end of clusters whose size isn't known.
+var clusterChildren = map[uint32]bool{
+	0xE7:   true, // Timestamp
+	0x5854: true, // SilentTracks
+	0xA7:   true, // Position
+	0xAB:   true, // PrevSize
+	0xA3:   true, // SimpleBlock
+	0xA0:   true, // BlockGroup
+	0xAF:   true, // EncryptedBlock
+	0xBF:   true, // CRC-32
+	idVoid: true,
+}
+
+// defaultTimestampScale is the number of nanoseconds in a timestamp tick if
+// the file doesn't specify it.
+const defaultTimestampScale = 1000000
+
+// unknownSize is the size of an element whose size isn't known.
+const unknownSize = -1
+
+// Info describes a Matroska file.
+type Info struct {
+	// DocType is the type of document, either matroska or webm.
+	DocType string
+
+	// TrackCount is the number of tracks in the file.
+	TrackCount int
+
+	// ClusterCount is the number of clusters, which hold the media data, in
+	// the file.
+	ClusterCount int
+
+	// Duration is the duration of the file or zero if the file doesn't
+	// specify it.
+	Duration time.Duration
+}
+
+// element is the header of an EBML element.
+type element struct {
+	id uint32
+
+	// offset is the offset of the element's data in the file.
+	offset int64
+
+	// size is the size of the element's data or unknownSize.
+	size int64
+}
+
+// end returns the offset of the end of the element's data.
+func (e element) end() int64 {
+	return e.offset + e.size
+}
+
+// reader reads EBML elements from a file.
+type reader struct {
+	r    io.ReaderAt
+	size int64
+}
+
+// Inspect checks that the file at `path` is a well-formed Matroska file and
+// describes it. The returned error wraps ErrMalformed if the file isn't
+// well-formed, for example because it was truncated.
+func Inspect(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+
+	return inspect(f, stat.Size())
+}
+
+// inspect describes the Matroska file of `size` bytes read from `r`.
+func inspect(r io.ReaderAt, size int64) (Info, error) {
+	rd := reader{r: r, size: size}
+	var info Info
+
+	// The ID is checked first so that files that aren't Matroska files at
+	// all are reported as such rather than by how they failed to parse.
+	header, err := rd.element(0, size)
+	if header.id != idEBML {
+		return info, fmt.Errorf("%w: missing EBML header", ErrMalformed)
+	} else if err != nil {
+		return info, err
+	} else if header.size == unknownSize {
+		return info, fmt.Errorf("%w: EBML header has an unknown size", ErrMalformed)
+	}
+
+	if err := rd.children(header, func(e element) error {
+		if e.id == idDocType {
+			bs, err := rd.data(e, 64)
+			info.DocType = string(trimNull(bs))
+			return err
+		}
+		return nil
+	}); err != nil {
+		return info, err
+	}
+
+	if info.DocType != "matroska" && info.DocType != "webm" {
+		return info, fmt.Errorf("%w: unexpected document type %q", ErrMalformed, info.DocType)
+	}
+
+	segment, err := rd.nextSegment(header.end())
+	if err != nil {
+		return info, err
+	}
+
+	var scale uint64 = defaultTimestampScale
+	var duration float64
+	hasInfo, hasTracks := false, false
+
+	err = rd.children(segment, func(e element) error {
+		switch e.id {
+		case idInfo:
+			hasInfo = true
+			return rd.children(e, func(e element) error {
+				switch e.id {
+				case idTimestampScale:
+					bs, err := rd.data(e, 8)
+					if err != nil {
+						return err
+					}
+					scale = readUint(bs)
+				case idDuration:
+					bs, err := rd.data(e, 8)
+					if err != nil {
+						return err
+					}
+					if duration, err = readFloat(bs); err != nil {
+						return fmt.Errorf("%w: %s", ErrMalformed, err)
+					}
+				}
+				return nil
+			})
+		case idTracks:
+			hasTracks = true
+			return rd.children(e, func(e element) error {
+				if e.id == idTrackEntry {
+					info.TrackCount++
+				}
+				return nil
+			})
+		case idCluster:
+			info.ClusterCount++
+		}
+		return nil
+	})
+	if err != nil {
+		return info, err
+	}
+
+	switch {
+	case !hasInfo:
+		return info, fmt.Errorf("%w: missing segment information", ErrMalformed)
+	case !hasTracks || info.TrackCount == 0:
+		return info, fmt.Errorf("%w: no tracks", ErrMalformed)
+	case info.ClusterCount == 0:
+		return info, fmt.Errorf("%w: no clusters", ErrMalformed)
+	}
+
+	info.Duration = time.Duration(duration * float64(scale))
+	return info, nil
+}
+
+// nextSegment returns the segment that follows the EBML header ending at
+// `offset`. Void elements between them are skipped.
+func (rd *reader) nextSegment(offset int64) (element, error) {
+	for {
+		e, err := rd.element(offset, rd.size)
+		if err != nil {
+			return e, err
+		}
+
+		switch {
+		case e.id == idSegment:
+			if e.size == unknownSize {
+				e.size = rd.size - e.offset
+			}
+			return e, nil
+		case e.id == idVoid && e.size != unknownSize:
+			offset = e.end()
+		default:
+			return e, fmt.Errorf("%w: missing segment", ErrMalformed)
+		}
+	}
+}
+
+// children calls `fn` for each child of the element `parent`. The walk stops
+// at the first error returned by `fn`. Clusters whose size isn't known are
+// sized by finding the first element that can't be in a cluster.
+func (rd *reader) children(parent element, fn func(element) error) error {
+	end := parent.end()
+	for offset := parent.offset; offset < end; {
+		e, err := rd.element(offset, end)
+		if err != nil {
+			return err
+		}
+
+		if e.size == unknownSize {
+			if e.id != idCluster {
+				return fmt.Errorf("%w: element %X at offset %d has an unknown size", ErrMalformed, e.id, offset)
+			}
+			if e.size, err = rd.clusterSize(e, end); err != nil {
+				return err
+			}
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+
+		offset = e.end()
+	}
+
+	return nil
+}
+
+// clusterSize returns the size of the cluster `cluster` whose size isn't
+// known. The cluster ends at the first element that can't be in a cluster or
+// at `end`, the end of its parent.
+func (rd *reader) clusterSize(cluster element, end int64) (int64, error) {
+	offset := cluster.offset
+	for offset < end {
+		e, err := rd.element(offset, end)
+		if err != nil {
+			return 0, err
+		}
+
+		if !clusterChildren[e.id] {
+			break
+		}
+
+		if e.size == unknownSize {
+			return 0, fmt.Errorf("%w: element %X at offset %d has an unknown size", ErrMalformed, e.id, offset)
+		}
+
+		offset = e.end()
+	}
+
+	return offset - cluster.offset, nil
+}
+
+// element reads the header of the element at `offset`. The element must end
+// before `end`, the end of its parent, unless its size isn't known.
+func (rd *reader) element(offset, end int64) (element, error) {
+	var buf [12]byte
+	n, err := rd.r.ReadAt(buf[:], offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return element{}, err
+	}
+	bs := buf[:n]
+
+	idLen := vintLength(bs)
+	if idLen == 0 || idLen > 4 || idLen > len(bs) {
+		return element{}, fmt.Errorf("%w: invalid element ID at offset %d", ErrMalformed, offset)
+	}
+
+	var id uint32
+	for _, b := range bs[:idLen] {
+		id = id<<8 | uint32(b)
+	}
+
+	bs = bs[idLen:]
+	sizeLen := vintLength(bs)
+	if sizeLen == 0 || sizeLen > len(bs) {
+		return element{id: id}, fmt.Errorf("%w: invalid size of element %X at offset %d", ErrMalformed, id, offset)
+	}
+
+	size := uint64(bs[0] & (0xFF >> sizeLen))
+	allOnes := size == uint64(0xFF>>sizeLen)
+	for _, b := range bs[1:sizeLen] {
+		size = size<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+
+	e := element{id: id, offset: offset + int64(idLen+sizeLen)}
+	switch {
+	case allOnes:
+		e.size = unknownSize
+	case size > math.MaxInt64 || e.offset+int64(size) > end || e.offset+int64(size) < e.offset:
+		return e, fmt.Errorf("%w: element %X at offset %d extends past the end of its parent, the file may be truncated", ErrMalformed, id, offset)
+	default:
+		e.size = int64(size)
+	}
+
+	return e, nil
+}
+
+// data reads the data of the element `e` which must be at most `max` bytes.
+func (rd *reader) data(e element, max int64) ([]byte, error) {
+	if e.size > max {
+		return nil, fmt.Errorf("%w: element %X is %d bytes, expected at most %d", ErrMalformed, e.id, e.size, max)
+	}
+
+	bs := make([]byte, e.size)
+	if _, err := rd.r.ReadAt(bs, e.offset); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// vintLength returns the length of the variable length integer that starts
+// with the first byte of `bs` or zero if it isn't valid.
+func vintLength(bs []byte) int {
+	if len(bs) == 0 || bs[0] == 0 {
+		return 0
+	}
+
+	length := 1
+	for mask := byte(0x80); bs[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	return length
+}
+
+// readUint decodes the big-endian unsigned integer `bs`.
+func readUint(bs []byte) uint64 {
+	var v uint64
+	for _, b := range bs {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// readFloat decodes the big-endian float `bs` which must be 0, 4, or 8 bytes.
+func readFloat(bs []byte) (float64, error) {
+	switch len(bs) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(bs))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(bs)), nil
+	default:
+		return 0, fmt.Errorf("invalid float size %d", len(bs))
+	}
+}
+
+// trimNull removes the null bytes strings can be padded with.
+func trimNull(bs []byte) []byte {
+	for len(bs) > 0 && bs[len(bs)-1] == 0 {
+		bs = bs[:len(bs)-1]
+	}
+	return bs
+}
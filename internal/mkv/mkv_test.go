@@ -0,0 +1,152 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package mkv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ebml encodes an element with ID `id` and data `data` using an 8 byte size.
+func ebml(id uint32, data ...[]byte) []byte {
+	var idBytes []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(idBytes) > 0 {
+			idBytes = append(idBytes, b)
+		}
+	}
+
+	body := bytes.Join(data, nil)
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+
+	return append(append(idBytes, size...), body...)
+}
+
+// unsized encodes an element with ID `id` and data `data` whose size isn't
+// known.
+func unsized(id uint32, data ...[]byte) []byte {
+	e := ebml(id, data...)
+	idLen := len(e) - 8 - len(bytes.Join(data, nil))
+	copy(e[idLen:], []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	return e
+}
+
+func float(v float64) []byte {
+	return binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+}
+
+// testFile returns a Matroska file with `tracks` tracks and a duration of
+// 2580 seconds whose segment is made of `segment` elements in addition to
+// its information and tracks.
+func testFile(tracks int, segment ...[]byte) []byte {
+	header := ebml(idEBML, ebml(idDocType, []byte("matroska")))
+
+	var entries [][]byte
+	for i := 0; i < tracks; i++ {
+		entries = append(entries, ebml(idTrackEntry, ebml(0xD7, []byte{byte(i + 1)})))
+	}
+
+	info := ebml(idInfo, ebml(idTimestampScale, []byte{0x0F, 0x42, 0x40}), ebml(idDuration, float(2580000)))
+	children := append([][]byte{info, ebml(idTracks, entries...)}, segment...)
+
+	return append(header, ebml(idSegment, children...)...)
+}
+
+func cluster() []byte {
+	return ebml(idCluster, ebml(0xE7, []byte{0}), ebml(0xA3, make([]byte, 64)))
+}
+
+func TestInspect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "title_t00.mkv")
+	if err := os.WriteFile(path, testFile(2, cluster(), ebml(idVoid, make([]byte, 16)), cluster()), 0644); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+
+	info, err := Inspect(path)
+	if err != nil {
+		t.Fatal("Inspect returned an error:", err)
+	}
+
+	expected := Info{DocType: "matroska", TrackCount: 2, ClusterCount: 2, Duration: 2580 * time.Second}
+	if info != expected {
+		t.Errorf("Inspect returned %+v, expected %+v", info, expected)
+	}
+}
+
+func TestInspectUnknownSizes(t *testing.T) {
+	header := ebml(idEBML, ebml(idDocType, []byte("matroska")))
+	info := ebml(idInfo, ebml(idDuration, float(1000)))
+	tracks := ebml(idTracks, ebml(idTrackEntry))
+	clusters := append(unsized(idCluster, ebml(0xA3, make([]byte, 8))), unsized(idCluster, ebml(0xA3, make([]byte, 8)))...)
+	file := append(header, unsized(idSegment, info, tracks, clusters)...)
+
+	got, err := inspect(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal("inspect returned an error:", err)
+	}
+
+	if got.ClusterCount != 2 || got.TrackCount != 1 || got.Duration != time.Second {
+		t.Errorf("inspect returned %+v, expected 2 clusters, 1 track, and a duration of 1s", got)
+	}
+}
+
+func TestInspectMalformed(t *testing.T) {
+	valid := testFile(2, cluster())
+
+	tests := []struct {
+		name     string
+		file     []byte
+		expected string
+	}{
+		{"truncated", valid[:len(valid)-10], "truncated"},
+		{"truncated header", valid[:6], "invalid size"},
+		{"empty", nil, "missing EBML header"},
+		{"not ebml", []byte("RIFF\x00\x00\x00\x00WAVE"), "missing EBML header"},
+		{"wrong doc type", append(ebml(idEBML, ebml(idDocType, []byte("avi"))), ebml(idSegment)...), "unexpected document type"},
+		{"no segment", ebml(idEBML, ebml(idDocType, []byte("matroska"))), "invalid element ID"},
+		{"no tracks", testFile(0, cluster()), "no tracks"},
+		{"no clusters", testFile(1), "no clusters"},
+	}
+
+	for _, test := range tests {
+		_, err := inspect(bytes.NewReader(test.file), int64(len(test.file)))
+		if !errors.Is(err, ErrMalformed) || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: inspect returned %v, expected an error containing %q", test.name, err, test.expected)
+		}
+	}
+}
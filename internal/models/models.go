@@ -30,6 +30,11 @@
 // in the database and as data transfer objects between services.
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // TODO: Most of these models will be moved to a common or core project so that
 //       they can be used across multiple projects. So there may be some data
 //       fields that aren't required for the copy service.
@@ -80,4 +85,309 @@ type OpticalDrive struct {
 	// DiscLabel is the label of the disc reported by the system. If a disc,
 	// is not inserted into the drive, it will be an empty string.
 	DiscLabel string
+
+	// LastSeen is the last time the copy service managing the drive reported
+	// that it was running.
+	LastSeen time.Time
+
+	// Online indicates whether the copy service managing the drive is
+	// running. It is cleared when the service shuts down cleanly, so a stale
+	// LastSeen is the only indication of a service that crashed.
+	Online bool
+}
+
+// CopyOperationState specifies the different states of a copy operation.
+type CopyOperationState string
+
+const (
+	// CopyStateRunning is the state of a copy operation while MakeMKV is
+	// copying the disc.
+	CopyStateRunning CopyOperationState = "running"
+
+	// CopyStateSucceeded is the state of a copy operation that completed
+	// without any fatal errors.
+	CopyStateSucceeded CopyOperationState = "succeeded"
+
+	// CopyStateFailed is the state of a copy operation that failed. The
+	// reason will be stored in the operation's FailureReason field.
+	CopyStateFailed CopyOperationState = "failed"
+
+	// CopyStateCancelled is the state of a copy operation that was cancelled
+	// before it completed.
+	CopyStateCancelled CopyOperationState = "cancelled"
+)
+
+// CopyOperation represents a single run of MakeMKV copying the titles from a
+// disc into MKV files.
+type CopyOperation struct {
+	// Id is the unique identifier associated with the operation.
+	Id int
+
+	// DriveId is the identifier of the optical drive the disc was copied
+	// from.
+	DriveId int
+
+	// DiscLabel is the label of the disc that was copied as reported by the
+	// system.
+	DiscLabel string
+
+	// DiscId is the identifier of the disc in the catalog that was copied or
+	// zero if the disc wasn't identified.
+	DiscId int
+
+	// OutputDir is the directory the MKV files were written to.
+	OutputDir string
+
+	// State is the current state of the operation.
+	State CopyOperationState
+
+	// StartedAt is the time the operation started.
+	StartedAt time.Time
+
+	// EndedAt is the time the operation ended. It will be the zero time
+	// while the operation is running.
+	EndedAt time.Time
+
+	// Warnings are the human readable descriptions of the non-fatal problems
+	// MakeMKV reported while copying the disc.
+	Warnings []string
+
+	// FailureReason is the human readable description of why the operation
+	// failed. It will be an empty string unless State is CopyStateFailed.
+	FailureReason string
+
+	// Damaged is true if MakeMKV reported any read errors while copying the
+	// disc.
+	Damaged bool
+
+	// ReadErrorCount is the number of read errors MakeMKV reported while
+	// copying the disc.
+	ReadErrorCount int
+
+	// ReadErrors are the details of the read errors MakeMKV reported while
+	// copying the disc. Only the first MaxReadErrorDetails errors are kept,
+	// but all are included in ReadErrorCount.
+	ReadErrors []ReadError
+
+	// Manifest is the JSON manifest describing the files handed off by the
+	// operation as defined by the manifest package. It is nil unless the
+	// operation succeeded.
+	Manifest json.RawMessage `json:",omitempty"`
+}
+
+// MaxReadErrorDetails is the maximum number of read errors whose details are
+// kept for a copy operation. This protects against a badly damaged disc
+// producing an unreasonably large record.
+const MaxReadErrorDetails = 100
+
+// ReadError is a read error reported while copying a disc.
+type ReadError struct {
+	// Kind classifies the error (e.g. medium, hardware, timeout).
+	Kind string
+
+	// Error is the error reported by the drive.
+	Error string
+
+	// Source is the file on the disc being read when the error occurred.
+	Source string
+
+	// Title is the index of the title made of Source or -1 if it couldn't be
+	// determined. Errors recorded before titles were tracked have title 0.
+	Title int
+
+	// Offset is the offset in bytes within Source where the error occurred or
+	// -1 if not known.
+	Offset int64
+
+	// Progress is the approximate position in the copy, as a percentage of
+	// the total, when the error occurred.
+	Progress float64
+}
+
+// CopyProgressSample is the progress of a copy operation at a point in time.
+// Samples are taken periodically while copying so that the throughput of a
+// copy can be charted.
+type CopyProgressSample struct {
+	// CopyOperationId is the identifier of the sampled copy operation.
+	CopyOperationId int
+
+	// SampledAt is the time the sample was taken.
+	SampledAt time.Time
+
+	// Title is the index of the title being written in the order the titles
+	// are written.
+	Title int
+
+	// Bytes is the number of bytes written to the output directory.
+	Bytes int64
+
+	// Percent is the overall progress of the copy as a percentage.
+	Percent float64
+}
+
+// Disc represents a DVD or Blu-ray disc that has been inserted into one of the
+// optical drives.
+type Disc struct {
+	// Id is the unique identifier associated with the disc.
+	Id int
+
+	// Label is the label of the disc reported by the system.
+	Label string
+
+	// Name is the name of the disc reported by MakeMKV.
+	Name string
+
+	// VolumeName is the volume name of the disc reported by MakeMKV.
+	VolumeName string
+
+	// Fingerprint identifies the disc's content. It is used to detect discs
+	// that have already been copied.
+	Fingerprint string
+
+	// CreatedAt is the time the disc was first added.
+	CreatedAt time.Time
+
+	// Titles are the titles on the disc reported by MakeMKV. They are only
+	// included when getting a single disc.
+	Titles []DiscTitle
+}
+
+// DiscTitle is a title on a disc reported by MakeMKV.
+type DiscTitle struct {
+	// Index is the index MakeMKV assigned to the title.
+	Index int
+
+	// Name is the name of the title reported by MakeMKV.
+	Name string
+
+	// Duration is the length of the title in seconds.
+	Duration int
+
+	// Size is the size of the title in bytes.
+	Size int64
+
+	// ChapterCount is the number of chapters in the title.
+	ChapterCount int
+
+	// SegmentsMap lists the segments (e.g. playlist items) making up the
+	// title.
+	SegmentsMap string
+
+	// SourceFile is the file on the disc the title is read from.
+	SourceFile string
+
+	// Streams are the video, audio, and subtitle streams in the title.
+	Streams []DiscStream
+}
+
+// DiscStream is a video, audio, or subtitle stream in a title on a disc.
+type DiscStream struct {
+	// Index is the index MakeMKV assigned to the stream within its title.
+	Index int
+
+	// Type is the type of stream (e.g. Video, Audio, Subtitles).
+	Type string
+
+	// Codec is the short name of the stream's codec.
+	Codec string
+
+	// LangCode is the language code of the stream if it has one.
+	LangCode string
+
+	// Name is the name of the stream reported by MakeMKV.
+	Name string
+}
+
+// DriveLease records which copy service manages an optical drive. It
+// prevents two services configured with the same drive from both using it.
+type DriveLease struct {
+	// DriveId is the identifier of the leased drive.
+	DriveId int
+
+	// Holder identifies the service holding the lease by its host and process
+	// id.
+	Holder string
+
+	// AcquiredAt is the time the holder first took the lease.
+	AcquiredAt time.Time
+
+	// ExpiresAt is the time the lease expires unless it is renewed.
+	ExpiresAt time.Time
+}
+
+// DriveCommandType specifies the commands that can be sent to a drive through
+// the database.
+type DriveCommandType string
+
+const (
+	// DriveCommandStart starts copying the disc in the drive. A disc that was
+	// already copied is copied anyway if the `force` argument is true.
+	DriveCommandStart DriveCommandType = "start"
+
+	// DriveCommandCancel cancels the copy in progress.
+	DriveCommandCancel DriveCommandType = "cancel"
+
+	// DriveCommandEject ejects the disc in the drive.
+	DriveCommandEject DriveCommandType = "eject"
+
+	// DriveCommandRescan reloads the information about the disc in the
+	// drive.
+	DriveCommandRescan DriveCommandType = "rescan"
+)
+
+// DriveCommandStatus specifies the different states of a drive command.
+type DriveCommandStatus string
+
+const (
+	// CommandStatusPending is the status of a command that hasn't been seen
+	// by the copy service managing the drive.
+	CommandStatusPending DriveCommandStatus = "pending"
+
+	// CommandStatusAcknowledged is the status of a command that the copy
+	// service has started handling.
+	CommandStatusAcknowledged DriveCommandStatus = "acknowledged"
+
+	// CommandStatusSucceeded is the status of a command that was handled
+	// successfully.
+	CommandStatusSucceeded DriveCommandStatus = "succeeded"
+
+	// CommandStatusFailed is the status of a command that couldn't be
+	// handled. The reason is stored in the command's Result field.
+	CommandStatusFailed DriveCommandStatus = "failed"
+)
+
+// DriveCommand is a command sent to the copy service managing a drive through
+// the database as an alternative to its HTTP API.
+type DriveCommand struct {
+	// Id is the unique identifier associated with the command.
+	Id int
+
+	// DriveId is the identifier of the drive the command is for.
+	DriveId int
+
+	// Command is the command to run.
+	Command DriveCommandType
+
+	// Args is a JSON object with the command's arguments, such as
+	// `{"force": true}` to start a copy of a disc that was already copied. It
+	// is nil if the command has no arguments.
+	Args json.RawMessage `json:",omitempty"`
+
+	// Status is the current status of the command.
+	Status DriveCommandStatus
+
+	// Result describes the outcome of the command, such as the identifier of
+	// the copy operation started or the reason the command failed.
+	Result string
+
+	// CreatedAt is the time the command was issued.
+	CreatedAt time.Time
+
+	// AcknowledgedAt is the time the copy service started handling the
+	// command.
+	AcknowledgedAt time.Time
+
+	// CompletedAt is the time the copy service finished handling the
+	// command.
+	CompletedAt time.Time
 }
@@ -0,0 +1,228 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package naming names the files copied from a disc using templates so that
+// they are useful without knowing how MakeMKV names its output.
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// maxNameLength is the maximum length in bytes of a file or directory name.
+// Most filesystems don't allow names longer than this.
+const maxNameLength = 255
+
+// Data is the information about a copied title available to the templates.
+type Data struct {
+	// DiscLabel is the label of the disc as reported by the system.
+	DiscLabel string
+
+	// VolumeName is the volume name of the disc as reported by MakeMKV.
+	VolumeName string
+
+	// Title is the index of the title on the disc.
+	Title int
+
+	// Duration is the length of the title as reported by MakeMKV, e.g.
+	// 1:23:45.
+	Duration string
+
+	// OperationId is the identifier of the copy operation.
+	OperationId int
+
+	// DriveName is the name of the drive the disc was copied from.
+	DriveName string
+
+	// Date is when the copy started.
+	Date time.Time
+}
+
+// sample is used to check that templates can be executed.
+var sample = Data{
+	DiscLabel:   "LOST_S1_D1",
+	VolumeName:  "LOST_S1_D1",
+	Title:       1,
+	Duration:    "0:42:17",
+	OperationId: 1,
+	DriveName:   "Drive A",
+	Date:        time.Date(2004, 9, 22, 20, 0, 0, 0, time.UTC),
+}
+
+// Templates are the templates the names of the copied files and the
+// directory they are placed in are created from.
+type Templates struct {
+	dir  *template.Template
+	file *template.Template
+}
+
+// Parse parses the template of the directory, `dir`, and the template of the
+// file names, `file`. The templates are executed with sample data to check
+// that they only use the fields of Data.
+func Parse(dir, file string) (*Templates, error) {
+	var t Templates
+	var err error
+
+	if dir != "" {
+		if t.dir, err = parse("directory", dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if file != "" {
+		if t.file, err = parse("file", file); err != nil {
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
+
+// parse parses the template `text` named `name` and executes it with sample
+// data.
+func parse(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, sample); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Dir returns the directory within the output directory the files for `data`
+// are placed in. Each element of the directory, which is separated by a
+// slash, is sanitized. An empty string is returned if there isn't a directory
+// template.
+func (t *Templates) Dir(data Data) (string, error) {
+	if t.dir == nil {
+		return "", nil
+	}
+
+	text, err := execute(t.dir, data)
+	if err != nil {
+		return "", err
+	}
+
+	var elems []string
+	for _, elem := range strings.Split(text, "/") {
+		if elem = Sanitize(elem); elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+
+	return filepath.Join(elems...), nil
+}
+
+// File returns the sanitized name for the file of the title in `data` with
+// extension `ext`. An empty string is returned if there isn't a file template.
+func (t *Templates) File(data Data, ext string) (string, error) {
+	if t.file == nil {
+		return "", nil
+	}
+
+	text, err := execute(t.file, data)
+	if err != nil {
+		return "", err
+	}
+
+	name := Sanitize(text)
+	if name == "" {
+		return "", errors.New("file template produced an empty name")
+	}
+
+	return truncate(name, maxNameLength-len(ext)) + ext, nil
+}
+
+// execute executes the template `t` with `data`.
+func execute(t *template.Template, data Data) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to execute %s template: %w", t.Name(), err)
+	}
+	return sb.String(), nil
+}
+
+// Sanitize replaces the characters in `name` that aren't allowed in file
+// names on common filesystems with underscores and removes the leading and
+// trailing spaces and dots. An empty string is returned if nothing is left.
+func Sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	name = strings.Trim(name, " .")
+	return truncate(name, maxNameLength)
+}
+
+// truncate shortens `s` to at most `n` bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Claim calls `create` to create something at `path`. If something already
+// exists there, a number is added to the file name before its extension,
+// starting at 2, and `create` is called again until it succeeds. `create`
+// must fail with an error matching fs.ErrExist, without replacing what is
+// there, so that two copies can't claim the same path. Returns the path that
+// was created.
+func Claim(path string, create func(path string) error) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	candidate := path
+	for i := 2; ; i++ {
+		if err := create(candidate); err == nil {
+			return candidate, nil
+		} else if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package naming
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("{{.DiscLabel}}/{{.Date.Format \"2006\"}}", "{{.VolumeName}}_{{.Title}}"); err != nil {
+		t.Error("Parse returned an error:", err)
+	}
+
+	invalid := [][2]string{
+		{"{{.DiscLabel", ""},
+		{"", "{{.Season}}"},
+		{"{{.Title.Name}}", ""},
+	}
+
+	for _, templates := range invalid {
+		if _, err := Parse(templates[0], templates[1]); err == nil {
+			t.Errorf("Expected invalid templates: %q", templates)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	templates, err := Parse("{{.DriveName}}/{{.DiscLabel}}/../{{.Date.Format \"2006-01-02\"}}", `{{.DiscLabel}}: t{{printf "%02d" .Title}} [{{.OperationId}}]`)
+	if err != nil {
+		t.Fatal("Parse returned an error:", err)
+	}
+
+	data := Data{
+		DiscLabel:   "LOST/S1",
+		Title:       3,
+		OperationId: 42,
+		DriveName:   "Drive A",
+		Date:        time.Date(2004, 9, 22, 20, 0, 0, 0, time.UTC),
+	}
+
+	dir, err := templates.Dir(data)
+	if err != nil {
+		t.Fatal("Dir returned an error:", err)
+	}
+
+	// The label's slash separates directories but the dots can't be used to
+	// leave the output directory.
+	if expected := filepath.Join("Drive A", "LOST", "S1", "2004-09-22"); dir != expected {
+		t.Errorf("Dir() = '%s', expected '%s'", dir, expected)
+	}
+
+	file, err := templates.File(data, ".mkv")
+	if err != nil {
+		t.Fatal("File returned an error:", err)
+	}
+
+	if expected := "LOST_S1_ t03 [42].mkv"; file != expected {
+		t.Errorf("File() = '%s', expected '%s'", file, expected)
+	}
+
+	empty, err := Parse("", "")
+	if err != nil {
+		t.Fatal("Parse returned an error:", err)
+	}
+
+	if dir, _ := empty.Dir(data); dir != "" {
+		t.Errorf("Dir() = '%s', expected no directory", dir)
+	}
+
+	if file, _ := empty.File(data, ".mkv"); file != "" {
+		t.Errorf("File() = '%s', expected no name", file)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Lost Season 1", "Lost Season 1"},
+		{`a/b\c:d*e?f"g<h>i|j`, "a_b_c_d_e_f_g_h_i_j"},
+		{"tab\there", "tab_here"},
+		{" .hidden. ", "hidden"},
+		{"..", ""},
+		{"Amélie", "Amélie"},
+		{strings.Repeat("é", 200), strings.Repeat("é", 127)},
+	}
+
+	for _, test := range tests {
+		if actual := Sanitize(test.name); actual != test.expected {
+			t.Errorf("Sanitize(%q) = %q, expected %q", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestClaim(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Lost.mkv")
+
+	create := func(path string) error {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	if claimed, err := Claim(path, create); err != nil || claimed != path {
+		t.Errorf("Claim() = '%s', %v, expected '%s'", claimed, err, path)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Lost (2).mkv"), nil, 0644); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+
+	expected := filepath.Join(dir, "Lost (3).mkv")
+	if claimed, err := Claim(path, create); err != nil || claimed != expected {
+		t.Errorf("Claim() = '%s', %v, expected '%s'", claimed, err, expected)
+	}
+
+	failed := errors.New("failed")
+	if _, err := Claim(path, func(string) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("Claim() error = %v, expected %v", err, failed)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package preflight checks that the environment the service runs in is usable
+// before the service starts so that problems are found before a copy is
+// attempted.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+)
+
+// probeTimeout is how long MakeMKV is given to report its version.
+const probeTimeout = 30 * time.Second
+
+// Run checks that the output, state, staging, and MakeMKV home directories
+// configured by `conf` and MakeMKV, run with `runner`, are usable. All of the
+// problems that were found are returned instead of stopping at the first one
+// so that they can be fixed at once.
+func Run(ctx context.Context, conf cfg.MakeMkvConfig, runner *makemkv.Runner) []error {
+	var problems []error
+
+	if err := CheckMakeMkv(ctx, runner); err != nil {
+		problems = append(problems, err)
+	}
+
+	problems = append(problems, CheckOutputDir(conf.OutDir, conf.MinFreeSpace)...)
+	problems = append(problems, CheckStateDir(conf.StateDir)...)
+	problems = append(problems, CheckStagingDir(conf.StagingDir, conf.MinFreeSpace)...)
+	problems = append(problems, CheckHomeDir(conf.HomeDir)...)
+
+	return problems
+}
+
+// CheckMakeMkv checks that the MakeMKV executable of `runner`, which is
+// resolved using PATH if it isn't a path, exists, is executable, and reports
+// its version when run.
+func CheckMakeMkv(ctx context.Context, runner *makemkv.Runner) error {
+	exe := runner.Exe
+	path, err := exec.LookPath(exe)
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("makemkv_exe %s was not found on PATH", exe)
+	} else if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("makemkv_exe %s is not executable", exe)
+	} else if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("makemkv_exe %s does not exist", exe)
+	} else if err != nil {
+		return fmt.Errorf("makemkv_exe %s: %w", exe, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	probe := *runner
+	probe.Exe = path
+	if _, err := probe.Probe(ctx); err != nil {
+		return fmt.Errorf("makemkv_exe %s failed to report its version: %w", path, err)
+	}
+
+	return nil
+}
+
+// CheckOutputDir checks that the output directory `dir` is a writable
+// directory with at least `minFree` bytes of free space.
+func CheckOutputDir(dir string, minFree int64) []error {
+	return checkDir("output_directory", dir, minFree)
+}
+
+// CheckStateDir checks that the state directory `dir` is a writable
+// directory. Like the staging directory, it is created if it doesn't exist
+// since the service owns it.
+func CheckStateDir(dir string) []error {
+	if err := createDir("state_directory", dir, 0755); err != nil {
+		return []error{err}
+	}
+	return checkDir("state_directory", dir, 0)
+}
+
+// CheckStagingDir checks that the staging directory `dir` is a writable
+// directory with at least `minFree` bytes of free space. Unlike the output
+// directory, it is created if it doesn't exist since the service owns it.
+func CheckStagingDir(dir string, minFree int64) []error {
+	if err := createDir("staging_directory", dir, 0755); err != nil {
+		return []error{err}
+	}
+	return checkDir("staging_directory", dir, minFree)
+}
+
+// CheckHomeDir checks that MakeMKV's home directory `dir` is a writable
+// directory. It is created if it doesn't exist and, since it holds MakeMKV's
+// settings, is only accessible by the service's user.
+func CheckHomeDir(dir string) []error {
+	if err := createDir("home_directory", dir, 0700); err != nil {
+		return []error{err}
+	}
+	return checkDir("home_directory", dir, 0)
+}
+
+// createDir creates the directory `dir`, set by the option `option`, with the
+// permissions `perm` if it doesn't exist. Its parent isn't created so that a mistyped path is still
+// reported.
+func createDir(option, dir string, perm fs.FileMode) error {
+	err := os.Mkdir(dir, perm)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("parent of %s %s does not exist", option, dir)
+	} else if err != nil && !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("failed to create %s %s: %w", option, dir, err)
+	}
+	return nil
+}
+
+// checkDir checks that the directory `dir`, set by the option `option`, is a
+// writable directory with at least `minFree` bytes of free space.
+func checkDir(option, dir string, minFree int64) []error {
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []error{fmt.Errorf("%s %s does not exist", option, dir)}
+	} else if err != nil {
+		return []error{fmt.Errorf("%s %s: %w", option, dir, err)}
+	} else if !info.IsDir() {
+		return []error{fmt.Errorf("%s %s is not a directory", option, dir)}
+	}
+
+	var problems []error
+
+	if f, err := os.CreateTemp(dir, ".artie-preflight-*"); err != nil {
+		problems = append(problems, fmt.Errorf("%s %s is not writable: %w", option, dir, err))
+	} else {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	free, err := FreeSpace(dir)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("failed to get free space of %s %s: %w", option, dir, err))
+	} else if free < uint64(minFree) {
+		problems = append(problems, fmt.Errorf("%s %s has %d bytes free, expected at least %d", option, dir, free, minFree))
+	}
+
+	return problems
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package preflight
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+)
+
+// fakeMakeMkv is a script that outputs the message MakeMKV reports its
+// version with.
+const fakeMakeMkv = `#!/bin/sh
+echo 'MSG:1005,0,1,"MakeMKV v1.17.7 linux(x64-release) started","%1 started","MakeMKV v1.17.7 linux(x64-release)"'
+exit 1
+`
+
+// writeScript writes `text` to an executable file named `name` in `dir` and
+// returns its path.
+func writeScript(t *testing.T, dir, name, text string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(text), 0755); err != nil {
+		t.Fatal("Failed to write script:", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir, state := t.TempDir(), t.TempDir()
+	conf := cfg.MakeMkvConfig{
+		OutDir:     dir,
+		StateDir:   state,
+		StagingDir: filepath.Join(state, "staging"),
+		HomeDir:    filepath.Join(state, "makemkv"),
+		MakeMKV:    writeScript(t, dir, "makemkvcon", fakeMakeMkv),
+	}
+
+	if problems := Run(context.Background(), conf, &makemkv.Runner{Exe: conf.MakeMKV}); len(problems) != 0 {
+		t.Errorf("Run returned %q, expected no problems", problems)
+	}
+
+	t.Setenv("PATH", dir)
+	conf.MakeMKV = "makemkvcon"
+	if problems := Run(context.Background(), conf, &makemkv.Runner{Exe: conf.MakeMKV}); len(problems) != 0 {
+		t.Errorf("Run returned %q, expected makemkvcon to be found on PATH", problems)
+	}
+}
+
+func TestRunReportsAllProblems(t *testing.T) {
+	dir, state := t.TempDir(), t.TempDir()
+	t.Setenv("PATH", dir)
+
+	conf := cfg.MakeMkvConfig{
+		OutDir:       dir,
+		StateDir:     state,
+		StagingDir:   filepath.Join(state, "staging"),
+		HomeDir:      filepath.Join(state, "makemkv"),
+		MakeMKV:      "makemkvcon",
+		MinFreeSpace: 1 << 62,
+	}
+
+	problems := Run(context.Background(), conf, &makemkv.Runner{Exe: conf.MakeMKV})
+	if len(problems) != 3 {
+		t.Fatalf("Run returned %q, expected 3 problems", problems)
+	}
+
+	if !strings.Contains(problems[0].Error(), "not found on PATH") {
+		t.Errorf("problems[0] = %q, expected makemkv_exe to not be found", problems[0])
+	}
+
+	if !strings.Contains(problems[1].Error(), "bytes free") {
+		t.Errorf("problems[1] = %q, expected too little free space", problems[1])
+	}
+
+	if !strings.Contains(problems[2].Error(), "staging_directory") {
+		t.Errorf("problems[2] = %q, expected too little free space in the staging directory", problems[2])
+	}
+}
+
+func TestCheckMakeMkv(t *testing.T) {
+	dir := t.TempDir()
+
+	notExecutable := filepath.Join(dir, "not-executable")
+	if err := os.WriteFile(notExecutable, []byte(fakeMakeMkv), 0644); err != nil {
+		t.Fatal("Failed to write script:", err)
+	}
+
+	tests := []struct {
+		exe      string
+		expected string
+	}{
+		{filepath.Join(dir, "missing"), "does not exist"},
+		{notExecutable, "is not executable"},
+		{writeScript(t, dir, "silent", "#!/bin/sh\nexit 1\n"), "failed to report its version"},
+	}
+
+	for _, test := range tests {
+		err := CheckMakeMkv(context.Background(), &makemkv.Runner{Exe: test.exe})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("CheckMakeMkv(%s) = %v, expected an error containing %q", test.exe, err, test.expected)
+		}
+	}
+}
+
+func TestCheckOutputDir(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+
+	tests := []struct {
+		dir      string
+		expected string
+	}{
+		{filepath.Join(dir, "missing"), "does not exist"},
+		{file, "is not a directory"},
+	}
+
+	for _, test := range tests {
+		problems := CheckOutputDir(test.dir, 0)
+		if len(problems) != 1 || !strings.Contains(problems[0].Error(), test.expected) {
+			t.Errorf("CheckOutputDir(%s) = %q, expected a problem containing %q", test.dir, problems, test.expected)
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		readOnly := filepath.Join(dir, "read-only")
+		if err := os.Mkdir(readOnly, 0555); err != nil {
+			t.Fatal("Failed to create directory:", err)
+		}
+
+		problems := CheckOutputDir(readOnly, 0)
+		if len(problems) != 1 || !strings.Contains(problems[0].Error(), "not writable") {
+			t.Errorf("CheckOutputDir(%s) = %q, expected it to not be writable", readOnly, problems)
+		}
+	}
+
+	if problems := CheckOutputDir(dir, 1024); len(problems) != 0 {
+		t.Errorf("CheckOutputDir(%s) = %q, expected no problems", dir, problems)
+	}
+}
+
+func TestCheckStateDir(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing", "state")
+	problems := CheckStateDir(missing)
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), "parent of state_directory") {
+		t.Errorf("CheckStateDir(%s) = %q, expected its parent to not exist", missing, problems)
+	}
+
+	state := filepath.Join(dir, "state")
+	if problems := CheckStateDir(state); len(problems) != 0 {
+		t.Errorf("CheckStateDir(%s) = %q, expected no problems", state, problems)
+	}
+
+	if info, err := os.Stat(state); err != nil || !info.IsDir() {
+		t.Errorf("CheckStateDir(%s) didn't create the directory: %v", state, err)
+	}
+}
+
+func TestCheckHomeDir(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing", "makemkv")
+	problems := CheckHomeDir(missing)
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), "parent of home_directory") {
+		t.Errorf("CheckHomeDir(%s) = %q, expected its parent to not exist", missing, problems)
+	}
+
+	home := filepath.Join(dir, "makemkv")
+	if problems := CheckHomeDir(home); len(problems) != 0 {
+		t.Errorf("CheckHomeDir(%s) = %q, expected no problems", home, problems)
+	}
+
+	if info, err := os.Stat(home); err != nil || !info.IsDir() {
+		t.Errorf("CheckHomeDir(%s) didn't create the directory: %v", home, err)
+	} else if info.Mode().Perm() != 0700 {
+		t.Errorf("CheckHomeDir(%s) created it with %v, expected -rwx------", home, info.Mode().Perm())
+	}
+}
+
+func TestCheckStagingDir(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing", "staging")
+	problems := CheckStagingDir(missing, 0)
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), "parent of staging_directory") {
+		t.Errorf("CheckStagingDir(%s) = %q, expected its parent to not exist", missing, problems)
+	}
+
+	staging := filepath.Join(dir, ".staging")
+	if problems := CheckStagingDir(staging, 0); len(problems) != 0 {
+		t.Errorf("CheckStagingDir(%s) = %q, expected no problems", staging, problems)
+	}
+
+	if info, err := os.Stat(staging); err != nil || !info.IsDir() {
+		t.Errorf("CheckStagingDir(%s) didn't create the directory: %v", staging, err)
+	}
+
+	if problems := CheckStagingDir(staging, 0); len(problems) != 0 {
+		t.Errorf("CheckStagingDir(%s) = %q, expected no problems when it exists", staging, problems)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package retention removes the history of copy operations once it is past
+// the configured retention period so that the database and transcripts don't
+// grow forever.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/transcript"
+)
+
+var (
+	ErrCopyRunning = errors.New("copy operation is still running")
+)
+
+// Expired gets the copy operations for the drive with identifier `driveId`
+// that are past their retention period as of time `now` ordered from oldest to
+// newest within each state. Running operations never expire and neither does
+// the latest successful copy of each disc since it's what identifies the disc
+// as already copied.
+func Expired(ctx context.Context, repo db.CopyOperationRepository, driveId int, now time.Time) ([]models.CopyOperation, error) {
+	conf := cfg.Current().Retention
+	retention := map[models.CopyOperationState]int{
+		models.CopyStateSucceeded: conf.SucceededDays,
+		models.CopyStateFailed:    conf.FailedDays,
+		models.CopyStateCancelled: conf.CancelledDays,
+	}
+
+	expired := make([]models.CopyOperation, 0)
+	for _, state := range []models.CopyOperationState{models.CopyStateSucceeded, models.CopyStateFailed, models.CopyStateCancelled} {
+		days := retention[state]
+		if days == 0 {
+			continue
+		}
+
+		ops, err := repo.ListCopyOperationsEndedBefore(ctx, driveId, state, now.AddDate(0, 0, -days))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s copy operations: %w", state, err)
+		}
+
+		if state == models.CopyStateSucceeded {
+			if ops, err = withoutLatestCopies(ctx, repo, ops); err != nil {
+				return nil, err
+			}
+		}
+		expired = append(expired, ops...)
+	}
+
+	return expired, nil
+}
+
+// withoutLatestCopies returns the successful copy operations `ops` without the
+// ones that are the latest successful copy of their disc.
+func withoutLatestCopies(ctx context.Context, repo db.CopyOperationRepository, ops []models.CopyOperation) ([]models.CopyOperation, error) {
+	latest := make(map[int]int)
+	kept := make([]models.CopyOperation, 0, len(ops))
+	for _, op := range ops {
+		if op.DiscId == 0 {
+			kept = append(kept, op)
+			continue
+		}
+
+		if _, ok := latest[op.DiscId]; !ok {
+			discOps, err := repo.ListCopyOperationsForDisc(ctx, op.DiscId)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list copy operations for disc: %w", err)
+			}
+
+			// The operations are ordered from newest to oldest.
+			for _, discOp := range discOps {
+				if discOp.State == models.CopyStateSucceeded {
+					latest[op.DiscId] = discOp.Id
+					break
+				}
+			}
+		}
+
+		if latest[op.DiscId] != op.Id {
+			kept = append(kept, op)
+		}
+	}
+
+	return kept, nil
+}
+
+// Purge deletes the copy operations for the drive with identifier `driveId`
+// that are past their retention period as of time `now` along with their
+// transcripts. Returns the operations that were deleted.
+func Purge(ctx context.Context, repo db.CopyOperationRepository, driveId int, now time.Time) ([]models.CopyOperation, error) {
+	expired, err := Expired(ctx, repo, driveId, now)
+	if err != nil {
+		return nil, err
+	}
+
+	purged := make([]models.CopyOperation, 0, len(expired))
+	for _, op := range expired {
+		if err := Delete(ctx, repo, op.Id); err != nil && !errors.Is(err, db.ErrCopyOperationNotFound) {
+			return purged, err
+		}
+		purged = append(purged, op)
+	}
+
+	return purged, nil
+}
+
+// Delete deletes the copy operation with identifier `id` and its transcript.
+// ErrCopyRunning is returned if the operation is still running.
+func Delete(ctx context.Context, repo db.CopyOperationRepository, id int) error {
+	op, err := repo.GetCopyOperation(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if op.State == models.CopyStateRunning {
+		return ErrCopyRunning
+	}
+
+	if err := repo.DeleteCopyOperation(ctx, id); err != nil {
+		return err
+	}
+
+	if err := transcript.Remove(id); err != nil {
+		slog.Warn("Failed to remove transcript.", "id", id, "error", err)
+	}
+
+	return nil
+}
+
+// Run purges the expired copy operations for the drive with identifier
+// `driveId` and the expired transcripts every purge interval until `ctx` is
+// cancelled.
+func Run(ctx context.Context, repo db.CopyOperationRepository, driveId int) {
+	for {
+		// The interval is read before each wait so that a reload of the
+		// configuration takes effect after the next purge.
+		interval := time.Duration(cfg.Current().Retention.PurgeInterval) * time.Second
+
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-time.After(interval):
+			purge(ctx, repo, driveId, now)
+		}
+	}
+}
+
+// purge runs a single purge logging the result.
+func purge(ctx context.Context, repo db.CopyOperationRepository, driveId int, now time.Time) {
+	if ops, err := Purge(ctx, repo, driveId, now); err != nil {
+		slog.Warn("Failed to purge copy operations.", "error", err)
+	} else if len(ops) > 0 {
+		slog.Info("Purged expired copy operations.", "count", len(ops))
+	}
+
+	if count, err := transcript.Purge(now); err != nil {
+		slog.Warn("Failed to purge transcripts.", "error", err)
+	} else if count > 0 {
+		slog.Info("Purged old transcripts.", "count", count)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/transcript"
+)
+
+func TestPurge(t *testing.T) {
+	cfg.Publish(cfg.Reloadable{Retention: cfg.RetentionConfig{SucceededDays: 180}})
+	cfg.Transcript = cfg.TranscriptConfig{Dir: t.TempDir()}
+	t.Cleanup(func() { cfg.Publish(cfg.Reloadable{}) })
+
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	now := time.Now()
+
+	ops := []models.CopyOperation{
+		{DriveId: 1, State: models.CopyStateSucceeded, EndedAt: now.AddDate(0, 0, -200)},
+		{DriveId: 1, State: models.CopyStateSucceeded, EndedAt: now.AddDate(0, 0, -10)},
+		{DriveId: 1, State: models.CopyStateFailed, EndedAt: now.AddDate(0, 0, -400)},
+		{DriveId: 2, State: models.CopyStateSucceeded, EndedAt: now.AddDate(0, 0, -200)},
+		{DriveId: 1, State: models.CopyStateRunning},
+	}
+	for i := range ops {
+		if err := repo.CreateCopyOperation(ctx, &ops[i]); err != nil {
+			t.Fatal("CreateCopyOperation returned an error:", err)
+		}
+	}
+
+	tw, err := transcript.Create(ops[0].Id)
+	if err != nil {
+		t.Fatal("Create returned an error:", err)
+	}
+	tw.Close()
+
+	expired, err := Expired(ctx, repo, 1, now)
+	if err != nil {
+		t.Fatal("Expired returned an error:", err)
+	}
+
+	if len(expired) != 1 || expired[0].Id != ops[0].Id {
+		t.Fatalf("Expired returned %+v, expected operation %d", expired, ops[0].Id)
+	}
+
+	if _, err := repo.GetCopyOperation(ctx, ops[0].Id); err != nil {
+		t.Error("Expired deleted the operation:", err)
+	}
+
+	purged, err := Purge(ctx, repo, 1, now)
+	if err != nil {
+		t.Fatal("Purge returned an error:", err)
+	}
+
+	if len(purged) != 1 || purged[0].Id != ops[0].Id {
+		t.Errorf("Purge returned %+v, expected operation %d", purged, ops[0].Id)
+	}
+
+	if _, err := repo.GetCopyOperation(ctx, ops[0].Id); err != db.ErrCopyOperationNotFound {
+		t.Error("Purge did not delete the expired operation")
+	}
+
+	if _, _, err := transcript.Read(ops[0].Id, 0); err != transcript.ErrTranscriptNotFound {
+		t.Error("Purge did not remove the transcript:", err)
+	}
+
+	for _, op := range ops[1:] {
+		if _, err := repo.GetCopyOperation(ctx, op.Id); err != nil {
+			t.Errorf("Purge deleted operation %d: %s", op.Id, err)
+		}
+	}
+}
+
+func TestExpiredKeepsLatestCopyOfDisc(t *testing.T) {
+	cfg.Publish(cfg.Reloadable{Retention: cfg.RetentionConfig{SucceededDays: 180}})
+	t.Cleanup(func() { cfg.Publish(cfg.Reloadable{}) })
+
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	now := time.Now()
+
+	ops := []models.CopyOperation{
+		{DriveId: 1, DiscId: 4, State: models.CopyStateSucceeded, EndedAt: now.AddDate(0, 0, -300)},
+		{DriveId: 1, DiscId: 4, State: models.CopyStateSucceeded, EndedAt: now.AddDate(0, 0, -200)},
+		{DriveId: 1, DiscId: 4, State: models.CopyStateFailed, EndedAt: now.AddDate(0, 0, -190)},
+		{DriveId: 1, DiscId: 8, State: models.CopyStateSucceeded, EndedAt: now.AddDate(0, 0, -200)},
+	}
+	for i := range ops {
+		if err := repo.CreateCopyOperation(ctx, &ops[i]); err != nil {
+			t.Fatal("CreateCopyOperation returned an error:", err)
+		}
+	}
+
+	expired, err := Expired(ctx, repo, 1, now)
+	if err != nil {
+		t.Fatal("Expired returned an error:", err)
+	}
+
+	if len(expired) != 1 || expired[0].Id != ops[0].Id {
+		t.Errorf("Expired returned %+v, expected only the older copy of disc 4, operation %d", expired, ops[0].Id)
+	}
+}
+
+func TestDeleteRunning(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+
+	op := models.CopyOperation{DriveId: 1, State: models.CopyStateRunning}
+	if err := repo.CreateCopyOperation(ctx, &op); err != nil {
+		t.Fatal("CreateCopyOperation returned an error:", err)
+	}
+
+	if err := Delete(ctx, repo, op.Id); err != ErrCopyRunning {
+		t.Errorf("Delete returned %v, expected ErrCopyRunning", err)
+	}
+
+	if err := Delete(ctx, repo, op.Id+1); err != db.ErrCopyOperationNotFound {
+		t.Errorf("Delete returned %v, expected ErrCopyOperationNotFound", err)
+	}
+}
@@ -30,18 +30,75 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/retention"
 	"github.com/kfisher/artie-copy-service/internal/store"
+	"github.com/kfisher/artie-copy-service/internal/transcript"
+	"github.com/kfisher/artie-copy-service/internal/worker"
 )
 
-// Run configures the routes and starts the HTTP server.
-func Run() error {
+// server handles the HTTP requests for the service.
+type server struct {
+	repo    db.Repository
+	workers map[string]*worker.Worker
+
+	// only is the worker of the only drive when a single drive is configured
+	// which is used by the routes without a serial number.
+	only *worker.Worker
+}
+
+// shutdownTimeout is how long in-flight requests are given to finish when the
+// server is shut down.
+const shutdownTimeout = 5 * time.Second
+
+// Run configures the routes and starts the HTTP server. Stored data is read
+// from `repo` and copies are performed by `workers`, one for each drive. The
+// server runs until `ctx` is cancelled at which point it is shut down
+// gracefully.
+func Run(ctx context.Context, repo db.Repository, workers []*worker.Worker) error {
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Server.Address, cfg.Server.Port),
+		Handler: NewHandler(repo, workers),
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
+
+// NewHandler creates the HTTP handler with all of the service's routes. The
+// routes of each drive are under /drives/{serial}. If there is only one drive,
+// its routes are also available without the prefix.
+func NewHandler(repo db.Repository, workers []*worker.Worker) http.Handler {
+	s := &server{repo: repo, workers: make(map[string]*worker.Worker)}
+	for _, w := range workers {
+		s.workers[w.Serial()] = w
+	}
 
 	r := mux.NewRouter()
 
@@ -49,20 +106,35 @@ func Run() error {
 		fmt.Fprint(w, "welcome to the world of endless wonder\n")
 	})
 
-	r.HandleFunc("/status", getStatus).Methods("GET")
+	r.HandleFunc("/drives", s.getDriveList).Methods("GET")
+	s.addDriveRoutes(r.PathPrefix("/drives/{serial}").Subrouter())
+
+	// The routes without a serial number were the only routes before multiple
+	// drives were supported, so they are kept for existing clients.
+	if len(workers) == 1 {
+		s.only = workers[0]
+		s.addDriveRoutes(r)
+	}
+
+	r.Use(loggingMiddleware)
 
-	r.HandleFunc("/copy/start", startCopy).Methods("POST")
-	r.HandleFunc("/copy/cancel", cancelCopy).Methods("POST")
+	return r
+}
 
-	r.HandleFunc("/reset", reset).Methods("POST")
+// addDriveRoutes adds the routes for a single drive to `r`.
+func (s *server) addDriveRoutes(r *mux.Router) {
+	r.HandleFunc("/status", s.getStatus).Methods("GET")
 
-	r.HandleFunc("/copy-operations", getCopyOperationList).Methods("GET")
-	r.HandleFunc("/copy-operations/{id}", getCopyOperation).Methods("GET")
+	r.HandleFunc("/copy/start", s.startCopy).Methods("POST")
+	r.HandleFunc("/copy/cancel", s.cancelCopy).Methods("POST")
 
-	r.Use(loggingMiddleware)
+	r.HandleFunc("/reset", s.reset).Methods("POST")
 
-	addr := fmt.Sprintf("%s:%d", cfg.Server.Address, cfg.Server.Port)
-	return http.ListenAndServe(addr, r)
+	r.HandleFunc("/copy-operations", s.getCopyOperationList).Methods("GET")
+	r.HandleFunc("/copy-operations/{id}", s.getCopyOperation).Methods("GET")
+	r.HandleFunc("/copy-operations/{id}", s.deleteCopyOperation).Methods("DELETE")
+	r.HandleFunc("/copy-operations/{id}/log", s.getCopyOperationLog).Methods("GET")
+	r.HandleFunc("/copy-operations/{id}/progress", s.getCopyOperationProgress).Methods("GET")
 }
 
 func loggingMiddleware(next http.Handler) http.Handler {
@@ -72,28 +144,269 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func getStatus(w http.ResponseWriter, r *http.Request) {
-	status := store.GetOpticalDrive()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(status)
+// driveWorker returns the worker of the drive selected by the serial number in
+// the request's path or the only drive for the routes without one. If the
+// drive doesn't exist, a not found response is written and nil is returned.
+func (s *server) driveWorker(w http.ResponseWriter, r *http.Request) *worker.Worker {
+	serial, ok := mux.Vars(r)["serial"]
+	if !ok {
+		return s.only
+	}
+
+	dw, ok := s.workers[serial]
+	if !ok {
+		http.Error(w, "drive not found", http.StatusNotFound)
+		return nil
+	}
+
+	return dw
 }
 
-func startCopy(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not implemented yet!", 500)
+// driveCopyOperation returns the copy operation selected by the identifier in
+// the request's path if it belongs to the drive of `dw`. Otherwise, an error
+// response is written and false is returned.
+func (s *server) driveCopyOperation(w http.ResponseWriter, r *http.Request, dw *worker.Worker) (models.CopyOperation, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid copy operation id", http.StatusBadRequest)
+		return models.CopyOperation{}, false
+	}
+
+	op, err := s.repo.GetCopyOperation(r.Context(), id)
+	if err != nil && !errors.Is(err, db.ErrCopyOperationNotFound) {
+		slog.Error("Failed to get copy operation.", "id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return op, false
+	}
+
+	if od, _ := store.GetOpticalDrive(dw.Serial()); err != nil || op.DriveId != od.Id {
+		http.Error(w, db.ErrCopyOperationNotFound.Error(), http.StatusNotFound)
+		return op, false
+	}
+
+	return op, true
 }
 
-func cancelCopy(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not implemented yet!", 500)
+// status is the response body for the status endpoint.
+type status struct {
+	models.OpticalDrive
+	MakeMkvVersion string               `json:"makemkv_version"`
+	LicenseState   makemkv.LicenseState `json:"license_state"`
+	LicenseExpires *time.Time           `json:"license_expires,omitempty"`
+	Database       *databaseStatus      `json:"database,omitempty"`
 }
 
-func reset(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not implemented yet!", 500)
+// databaseStatus is the health of the connection to the database. It is only
+// included in the status if the repository reports its health.
+type databaseStatus struct {
+	Healthy bool `json:"healthy"`
+	Spooled int  `json:"spooled"`
 }
 
-func getCopyOperationList(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not implemented yet!", 500)
+func (s *server) getDriveList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, store.GetOpticalDrives())
+}
+
+func (s *server) getStatus(w http.ResponseWriter, r *http.Request) {
+	dw := s.driveWorker(w, r)
+	if dw == nil {
+		return
+	}
+
+	od, _ := store.GetOpticalDrive(dw.Serial())
+	license := store.GetLicense()
+	status := status{
+		OpticalDrive:   od,
+		MakeMkvVersion: license.Version,
+		LicenseState:   license.State,
+	}
+	if !license.ExpiresAt.IsZero() {
+		status.LicenseExpires = &license.ExpiresAt
+	}
+	if h, ok := s.repo.(db.HealthReporter); ok {
+		status.Database = &databaseStatus{Healthy: h.Healthy(), Spooled: h.Spooled()}
+	}
+	writeJSON(w, status)
 }
 
-func getCopyOperation(w http.ResponseWriter, r *http.Request) {
+func (s *server) startCopy(w http.ResponseWriter, r *http.Request) {
+	dw := s.driveWorker(w, r)
+	if dw == nil {
+		return
+	}
+
+	force := false
+	if v := r.URL.Query().Get("force"); v != "" {
+		var err error
+		if force, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "invalid force value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	op, err := dw.StartCopy(r.Context(), force)
+	if errors.Is(err, worker.ErrCopyInProgress) || errors.Is(err, worker.ErrDuplicateDisc) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if errors.Is(err, worker.ErrLicenseExpired) || errors.Is(err, worker.ErrDriveLost) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if errors.Is(err, worker.ErrInsufficientSpace) {
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+		return
+	} else if err != nil {
+		slog.Error("Failed to start copy.", "serial", dw.Serial(), "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, op)
+}
+
+func (s *server) cancelCopy(w http.ResponseWriter, r *http.Request) {
+	dw := s.driveWorker(w, r)
+	if dw == nil {
+		return
+	}
+
+	if err := dw.CancelCopy(); errors.Is(err, worker.ErrNoCopyInProgress) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *server) reset(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Not implemented yet!", 500)
 }
+
+func (s *server) getCopyOperationList(w http.ResponseWriter, r *http.Request) {
+	dw := s.driveWorker(w, r)
+	if dw == nil {
+		return
+	}
+
+	od, _ := store.GetOpticalDrive(dw.Serial())
+	ops, err := s.repo.ListCopyOperations(r.Context(), od.Id)
+	if err != nil {
+		slog.Error("Failed to list copy operations.", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, ops)
+}
+
+func (s *server) getCopyOperation(w http.ResponseWriter, r *http.Request) {
+	dw := s.driveWorker(w, r)
+	if dw == nil {
+		return
+	}
+
+	if op, ok := s.driveCopyOperation(w, r, dw); ok {
+		writeJSON(w, op)
+	}
+}
+
+// deleteCopyOperation deletes a copy operation and its transcript. Running
+// operations can't be deleted.
+func (s *server) deleteCopyOperation(w http.ResponseWriter, r *http.Request) {
+	dw := s.driveWorker(w, r)
+	if dw == nil {
+		return
+	}
+
+	op, ok := s.driveCopyOperation(w, r, dw)
+	if !ok {
+		return
+	}
+
+	err := retention.Delete(r.Context(), s.repo, op.Id)
+	if errors.Is(err, db.ErrCopyOperationNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if errors.Is(err, retention.ErrCopyRunning) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		slog.Error("Failed to delete copy operation.", "id", op.Id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getCopyOperationProgress returns the progress samples of a copy operation
+// ordered from oldest to newest for charting its throughput.
+func (s *server) getCopyOperationProgress(w http.ResponseWriter, r *http.Request) {
+	dw := s.driveWorker(w, r)
+	if dw == nil {
+		return
+	}
+
+	op, ok := s.driveCopyOperation(w, r, dw)
+	if !ok {
+		return
+	}
+
+	samples, err := s.repo.ListCopyProgressSamples(r.Context(), op.Id)
+	if err != nil {
+		slog.Error("Failed to get copy progress.", "id", op.Id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, samples)
+}
+
+// getCopyOperationLog returns the raw MakeMKV output of a copy operation. The
+// optional `since` query parameter is the offset to start from which allows a
+// client to tail the output using the offset returned in the X-Log-Offset
+// header.
+func (s *server) getCopyOperationLog(w http.ResponseWriter, r *http.Request) {
+	dw := s.driveWorker(w, r)
+	if dw == nil {
+		return
+	}
+
+	op, ok := s.driveCopyOperation(w, r, dw)
+	if !ok {
+		return
+	}
+
+	var since int64
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		since, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || since < 0 {
+			http.Error(w, "invalid since offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, offset, err := transcript.Read(op.Id, since)
+	if errors.Is(err, transcript.ErrTranscriptNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		slog.Error("Failed to read transcript.", "id", op.Id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Log-Offset", strconv.FormatInt(offset, 10))
+	w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to encode response.", "error", err)
+	}
+}
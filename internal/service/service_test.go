@@ -0,0 +1,310 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/store"
+	"github.com/kfisher/artie-copy-service/internal/worker"
+)
+
+func newTestHandler(t *testing.T) (http.Handler, *db.MemoryRepository) {
+	repo := db.NewMemoryRepository()
+
+	od := models.OpticalDrive{
+		Name:         "Drive A",
+		SerialNumber: "4-8-15-16-23-42",
+		State:        models.DriveStateIdle,
+	}
+	if err := repo.UpsertOpticalDrive(context.Background(), &od); err != nil {
+		t.Fatal("UpsertOpticalDrive returned an error:", err)
+	}
+
+	store.Set(od)
+
+	return NewHandler(repo, []*worker.Worker{worker.New(repo, od.SerialNumber)}), repo
+}
+
+// testDriveId returns the identifier of the drive created by newTestHandler.
+func testDriveId() int {
+	od, _ := store.GetOpticalDrive("4-8-15-16-23-42")
+	return od.Id
+}
+
+func TestGetCopyOperation(t *testing.T) {
+	handler, repo := newTestHandler(t)
+
+	op := models.CopyOperation{
+		DriveId:   testDriveId(),
+		State:     models.CopyStateSucceeded,
+		StartedAt: time.Now(),
+	}
+	if err := repo.CreateCopyOperation(context.Background(), &op); err != nil {
+		t.Fatal("CreateCopyOperation returned an error:", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/copy-operations/1", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Status = %d, expected %d", rec.Code, http.StatusOK)
+	}
+
+	var got models.CopyOperation
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Error("Failed to decode response:", err)
+	}
+
+	if got.Id != op.Id || got.State != models.CopyStateSucceeded {
+		t.Errorf("Response = %+v, expected %+v", got, op)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/copy-operations/2", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Status = %d, expected %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/copy-operations/abc", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Status = %d, expected %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetCopyOperationList(t *testing.T) {
+	handler, repo := newTestHandler(t)
+
+	for range 2 {
+		op := models.CopyOperation{DriveId: testDriveId(), State: models.CopyStateSucceeded}
+		if err := repo.CreateCopyOperation(context.Background(), &op); err != nil {
+			t.Fatal("CreateCopyOperation returned an error:", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/copy-operations", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Status = %d, expected %d", rec.Code, http.StatusOK)
+	}
+
+	var got []models.CopyOperation
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Error("Failed to decode response:", err)
+	}
+
+	if len(got) != 2 {
+		t.Errorf("Response has %d operations, expected 2", len(got))
+	}
+}
+
+func TestCancelCopyWithoutCopy(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/copy/cancel", nil))
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Status = %d, expected %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestStartCopyInvalidForce(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/copy/start?force=maybe", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Status = %d, expected %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDeleteCopyOperation(t *testing.T) {
+	handler, repo := newTestHandler(t)
+	cfg.Transcript = cfg.TranscriptConfig{Dir: t.TempDir()}
+
+	done := models.CopyOperation{DriveId: testDriveId(), State: models.CopyStateSucceeded}
+	running := models.CopyOperation{DriveId: testDriveId(), State: models.CopyStateRunning}
+	for _, op := range []*models.CopyOperation{&done, &running} {
+		if err := repo.CreateCopyOperation(context.Background(), op); err != nil {
+			t.Fatal("CreateCopyOperation returned an error:", err)
+		}
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{fmt.Sprintf("/copy-operations/%d", done.Id), http.StatusNoContent},
+		{fmt.Sprintf("/copy-operations/%d", done.Id), http.StatusNotFound},
+		{fmt.Sprintf("/copy-operations/%d", running.Id), http.StatusConflict},
+		{"/copy-operations/abc", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("DELETE", test.path, nil))
+
+		if rec.Code != test.status {
+			t.Errorf("DELETE %s status = %d, expected %d", test.path, rec.Code, test.status)
+		}
+	}
+}
+
+func TestGetCopyOperationProgress(t *testing.T) {
+	handler, repo := newTestHandler(t)
+
+	op := models.CopyOperation{DriveId: testDriveId(), State: models.CopyStateRunning}
+	if err := repo.CreateCopyOperation(context.Background(), &op); err != nil {
+		t.Fatal("CreateCopyOperation returned an error:", err)
+	}
+
+	for i := range 2 {
+		sample := models.CopyProgressSample{CopyOperationId: op.Id, SampledAt: time.Now(), Percent: float64(i) * 50}
+		if err := repo.AddCopyProgressSample(context.Background(), sample); err != nil {
+			t.Fatal("AddCopyProgressSample returned an error:", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/copy-operations/%d/progress", op.Id), nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Status = %d, expected %d", rec.Code, http.StatusOK)
+	}
+
+	var got []models.CopyProgressSample
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Error("Failed to decode response:", err)
+	}
+
+	if len(got) != 2 || got[1].Percent != 50 {
+		t.Errorf("Response has samples %+v, expected 2", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/copy-operations/%d/progress", op.Id+1), nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Status = %d, expected %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDriveRoutes(t *testing.T) {
+	handler, repo := newTestHandler(t)
+
+	other := models.OpticalDrive{Name: "Drive B", SerialNumber: "108", State: models.DriveStateIdle}
+	if err := repo.UpsertOpticalDrive(context.Background(), &other); err != nil {
+		t.Fatal("UpsertOpticalDrive returned an error:", err)
+	}
+
+	ops := []models.CopyOperation{
+		{DriveId: testDriveId(), State: models.CopyStateSucceeded},
+		{DriveId: other.Id, State: models.CopyStateSucceeded},
+	}
+	for i := range ops {
+		if err := repo.CreateCopyOperation(context.Background(), &ops[i]); err != nil {
+			t.Fatal("CreateCopyOperation returned an error:", err)
+		}
+	}
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{"/drives/4-8-15-16-23-42/status", http.StatusOK},
+		{"/drives/4-8-15-16-23-42/copy-operations", http.StatusOK},
+		{fmt.Sprintf("/drives/4-8-15-16-23-42/copy-operations/%d", ops[0].Id), http.StatusOK},
+		{fmt.Sprintf("/drives/4-8-15-16-23-42/copy-operations/%d", ops[1].Id), http.StatusNotFound},
+		{fmt.Sprintf("/copy-operations/%d", ops[1].Id), http.StatusNotFound},
+		{"/drives/108/status", http.StatusNotFound},
+		{"/drives", http.StatusOK},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", test.url, nil))
+		if rec.Code != test.code {
+			t.Errorf("GET %s status = %d, expected %d", test.url, rec.Code, test.code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/drives/4-8-15-16-23-42/copy-operations", nil))
+	var list []models.CopyOperation
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal("failed to decode response:", err)
+	}
+	if len(list) != 1 || list[0].Id != ops[0].Id {
+		t.Errorf("copy operations = %v, expected only %d", list, ops[0].Id)
+	}
+}
+
+func TestDriveRoutesWithMultipleDrives(t *testing.T) {
+	repo := db.NewMemoryRepository()
+	var workers []*worker.Worker
+	for _, serial := range []string{"A1", "B2"} {
+		od := models.OpticalDrive{SerialNumber: serial, State: models.DriveStateIdle}
+		if err := repo.UpsertOpticalDrive(context.Background(), &od); err != nil {
+			t.Fatal("UpsertOpticalDrive returned an error:", err)
+		}
+		store.Set(od)
+		workers = append(workers, worker.New(repo, serial))
+	}
+	handler := NewHandler(repo, workers)
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{"/drives/A1/status", http.StatusOK},
+		{"/drives/B2/status", http.StatusOK},
+		{"/status", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", test.url, nil))
+		if rec.Code != test.code {
+			t.Errorf("GET %s status = %d, expected %d", test.url, rec.Code, test.code)
+		}
+	}
+}
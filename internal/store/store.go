@@ -33,47 +33,111 @@ package store
 import (
 	"sync"
 
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
 	"github.com/kfisher/artie-copy-service/internal/models"
 )
 
-var store Store
+var store = Store{drives: make(map[string]*models.OpticalDrive)}
 
+// Store holds the state of each of the optical drives managed by the service
+// keyed by serial number along with the MakeMKV license information.
 type Store struct {
-	mu sync.RWMutex
-	od models.OpticalDrive
+	mu      sync.RWMutex
+	drives  map[string]*models.OpticalDrive
+	serials []string
+	license makemkv.License
 }
 
-// GetOpticalDrive returns the entire OpticalDrive object.
-func GetOpticalDrive() models.OpticalDrive {
+// GetOpticalDrive returns the entire OpticalDrive object of the drive with
+// serial number `serial`. Returns false if the drive isn't in the store.
+func GetOpticalDrive(serial string) (models.OpticalDrive, bool) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
-	return store.od
+	od, ok := store.drives[serial]
+	if !ok {
+		return models.OpticalDrive{}, false
+	}
+
+	return *od, true
+}
+
+// GetOpticalDrives returns all of the drives in the order they were added.
+func GetOpticalDrives() []models.OpticalDrive {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	drives := make([]models.OpticalDrive, 0, len(store.serials))
+	for _, serial := range store.serials {
+		drives = append(drives, *store.drives[serial])
+	}
+
+	return drives
 }
 
-// GetState returns the current state of the optical drive.
-func GetState() models.OpticalDriveState {
+// GetState returns the current state of the optical drive with serial number
+// `serial`.
+func GetState(serial string) models.OpticalDriveState {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
-	return store.od.State
+	if od, ok := store.drives[serial]; ok {
+		return od.State
+	}
+
+	return ""
 }
 
-// Set updates the OpticalDrive object in the store. This should generally only
-// be used during initialization since most of the fields will not change. Use
-// the dedicated field setters. If a setter doesn't exist, then it probably
-// shouldn't be changed after initialization.
+// Set adds or updates the OpticalDrive object in the store. This should
+// generally only be used during initialization since most of the fields will
+// not change. Use the dedicated field setters. If a setter doesn't exist, then
+// it probably shouldn't be changed after initialization.
 func Set(od models.OpticalDrive) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	store.od = od
+	if _, ok := store.drives[od.SerialNumber]; !ok {
+		store.serials = append(store.serials, od.SerialNumber)
+	}
+
+	store.drives[od.SerialNumber] = &od
+}
+
+// SetState updates the state of the optical drive with serial number `serial`
+// in the store.
+func SetState(serial string, status models.OpticalDriveState) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if od, ok := store.drives[serial]; ok {
+		od.State = status
+	}
+}
+
+// SetDiscLabel updates the label of the disc in the optical drive with serial
+// number `serial` in the store.
+func SetDiscLabel(serial, label string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if od, ok := store.drives[serial]; ok {
+		od.DiscLabel = label
+	}
+}
+
+// GetLicense returns the MakeMKV version and license information reported the
+// last time MakeMKV was run.
+func GetLicense() makemkv.License {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.license
 }
 
-// SetState updates the state of the optical drive in the store.
-func SetState(status models.OpticalDriveState) {
+// SetLicense updates the MakeMKV version and license information in the store.
+func SetLicense(license makemkv.License) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	store.od.State = status
+	store.license = license
 }
@@ -29,6 +29,7 @@
 package store_test
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/kfisher/artie-copy-service/internal/models"
@@ -46,7 +47,10 @@ func TestState(t *testing.T) {
 		DiscLabel:    "LOST_S1",
 	})
 
-	od := store.GetOpticalDrive()
+	od, ok := store.GetOpticalDrive("4-8-15-16-23-42")
+	if !ok {
+		t.Fatal("Expected drive to be in the store")
+	}
 	if od.Id != 1337 {
 		t.Error("Expected ID to be 1337, got:", od.Id)
 	}
@@ -69,15 +73,45 @@ func TestState(t *testing.T) {
 		t.Error("Expected DiscLabel to be 'LOST_S1', got:", od.DiscLabel)
 	}
 
-	if store.GetState() != models.DriveStateIdle {
-		t.Error("Expected state to be Idle, got:", store.GetState())
+	if store.GetState(od.SerialNumber) != models.DriveStateIdle {
+		t.Error("Expected state to be Idle, got:", store.GetState(od.SerialNumber))
+	}
+
+	store.SetState(od.SerialNumber, models.DriveStateCopying)
+	if od, _ := store.GetOpticalDrive(od.SerialNumber); od.State != models.DriveStateCopying {
+		t.Error("Expected state to be Copying, got:", od.State)
+	}
+	if store.GetState(od.SerialNumber) != models.DriveStateCopying {
+		t.Error("Expected state to be Copying, got:", store.GetState(od.SerialNumber))
+	}
+}
+
+func TestMultipleDrives(t *testing.T) {
+	store.Set(models.OpticalDrive{Id: 1, SerialNumber: "drive-a", State: models.DriveStateIdle})
+	store.Set(models.OpticalDrive{Id: 2, SerialNumber: "drive-b", State: models.DriveStateIdle})
+
+	store.SetState("drive-b", models.DriveStateCopying)
+	store.SetDiscLabel("drive-b", "LOST_S2")
+
+	if store.GetState("drive-a") != models.DriveStateIdle {
+		t.Error("Expected drive-a to be Idle, got:", store.GetState("drive-a"))
+	}
+
+	if od, _ := store.GetOpticalDrive("drive-b"); od.State != models.DriveStateCopying || od.DiscLabel != "LOST_S2" {
+		t.Errorf("Expected drive-b to be copying LOST_S2, got: %+v", od)
 	}
 
-	store.SetState(models.DriveStateCopying)
-	if store.GetOpticalDrive().State != models.DriveStateCopying {
-		t.Error("Expected state to be Copying, got:", store.GetOpticalDrive().State)
+	if _, ok := store.GetOpticalDrive("drive-c"); ok {
+		t.Error("Expected drive-c not to be in the store")
 	}
-	if store.GetState() != models.DriveStateCopying {
-		t.Error("Expected state to be Copying, got:", store.GetState())
+
+	serials := []string{}
+	for _, od := range store.GetOpticalDrives() {
+		serials = append(serials, od.SerialNumber)
+	}
+
+	// Drives keep the order they were first added in.
+	if !slices.Contains(serials, "drive-a") || slices.Index(serials, "drive-a") > slices.Index(serials, "drive-b") {
+		t.Errorf("GetOpticalDrives returned %v", serials)
 	}
 }
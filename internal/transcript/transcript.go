@@ -0,0 +1,291 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package transcript stores the raw output of MakeMKV for each copy operation
+// so that failed copies can be diagnosed. Transcripts are gzip compressed and
+// named after the copy operation's identifier.
+package transcript
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+)
+
+var (
+	ErrTranscriptNotFound = errors.New("transcript not found")
+)
+
+// flushInterval is how often the compressed output is flushed to the file so
+// that a transcript can be read while MakeMKV is still running.
+const flushInterval = time.Second
+
+// truncatedMarker is written once when a transcript reaches its size limit.
+const truncatedMarker = "\n[transcript truncated: size limit reached]\n"
+
+// Writer writes a compressed transcript. It is safe to use from multiple
+// goroutines.
+type Writer struct {
+	mu        sync.Mutex
+	file      *os.File
+	gz        *gzip.Writer
+	size      int64
+	maxSize   int64
+	truncated bool
+	lastFlush time.Time
+}
+
+// Create creates the transcript for the copy operation with identifier `id`,
+// replacing any existing transcript.
+func Create(id int) (*Writer, error) {
+	if err := os.MkdirAll(cfg.Transcript.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+
+	file, err := os.Create(Path(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transcript: %w", err)
+	}
+
+	return &Writer{
+		file:      file,
+		gz:        gzip.NewWriter(file),
+		maxSize:   cfg.Transcript.MaxSize,
+		lastFlush: time.Now(),
+	}, nil
+}
+
+// Write writes `p` to the transcript. Once the transcript reaches its size
+// limit, the remaining output is discarded without returning an error so that
+// a large transcript never interrupts a copy.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	if w.truncated {
+		return n, nil
+	}
+
+	if w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize {
+		p = p[:w.maxSize-w.size]
+		w.truncated = true
+	}
+
+	written, err := w.gz.Write(p)
+	w.size += int64(written)
+	if err != nil {
+		return written, err
+	}
+
+	if w.truncated {
+		if _, err := w.gz.Write([]byte(truncatedMarker)); err != nil {
+			return n, err
+		}
+	}
+
+	if time.Since(w.lastFlush) >= flushInterval {
+		w.lastFlush = time.Now()
+		if err := w.gz.Flush(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Close flushes any buffered output and closes the transcript.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.gz.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
+
+// Read reads the transcript of the copy operation with identifier `id`
+// starting at offset `since` in the uncompressed output. Returns the output
+// and the offset to use to read any output written afterwards. A transcript
+// that is still being written can be read, but output that hasn't been
+// flushed yet won't be returned.
+func Read(id int, since int64) ([]byte, int64, error) {
+	file, err := os.Open(Path(id))
+	if os.IsNotExist(err) {
+		return nil, since, ErrTranscriptNotFound
+	} else if err != nil {
+		return nil, since, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err == io.EOF {
+		return []byte{}, since, nil
+	} else if err != nil {
+		return nil, since, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	skipped, err := io.CopyN(io.Discard, gz, since)
+	if err != nil && !isEndOfTranscript(err) {
+		return nil, since, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	if skipped < since {
+		return []byte{}, skipped, nil
+	}
+
+	data, err := io.ReadAll(gz)
+	if err != nil && !isEndOfTranscript(err) {
+		return nil, since, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	return data, since + int64(len(data)), nil
+}
+
+// Remove removes the transcript of the copy operation with identifier `id` if
+// it exists.
+func Remove(id int) error {
+	err := os.Remove(Path(id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Rename renames the transcript of the copy operation with identifier `from`
+// to be the transcript of the copy operation with identifier `to`. Does
+// nothing if the transcript doesn't exist.
+func Rename(from, to int) error {
+	err := os.Rename(Path(from), Path(to))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Purge removes the transcripts that are older than the configured retention
+// period as of time `now`. Returns the number of transcripts removed.
+func Purge(now time.Time) (int, error) {
+	ids, err := Expired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, id := range ids {
+		if err := Remove(id); err != nil {
+			slog.Warn("Failed to remove transcript.", "id", id, "error", err)
+			continue
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Expired returns the identifiers of the copy operations whose transcripts
+// are older than the configured retention period as of time `now`.
+func Expired(now time.Time) ([]int, error) {
+	if cfg.Transcript.RetentionDays == 0 {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(cfg.Transcript.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read transcript directory: %w", err)
+	}
+
+	cutoff := now.AddDate(0, 0, -cfg.Transcript.RetentionDays)
+	var ids []int
+	for _, entry := range entries {
+		id, ok := parseName(entry.Name())
+		if !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			slog.Warn("Failed to get transcript info.", "name", entry.Name(), "error", err)
+			continue
+		}
+
+		if info.ModTime().Before(cutoff) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// Exists returns true if the copy operation with identifier `id` has a
+// transcript.
+func Exists(id int) bool {
+	_, err := os.Stat(Path(id))
+	return err == nil
+}
+
+// Path returns the path of the transcript of the copy operation with
+// identifier `id` whether or not it exists.
+func Path(id int) string {
+	return filepath.Join(cfg.Transcript.Dir, fmt.Sprintf("%d.log.gz", id))
+}
+
+// parseName returns the copy operation identifier of the transcript with file
+// name `name`. Returns false if `name` isn't a transcript.
+func parseName(name string) (int, bool) {
+	s, ok := strings.CutSuffix(name, ".log.gz")
+	if !ok {
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(s)
+	return id, err == nil
+}
+
+// isEndOfTranscript returns true if `err` indicates that the end of the
+// available output was reached. A transcript that is still being written
+// doesn't have a gzip footer yet so it ends unexpectedly.
+func isEndOfTranscript(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
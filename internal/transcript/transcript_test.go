@@ -0,0 +1,215 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	cfg.Transcript = cfg.TranscriptConfig{Dir: t.TempDir()}
+
+	w, err := Create(1)
+	if err != nil {
+		t.Error("Create returned an error:", err)
+		return
+	}
+
+	w.Write([]byte("MSG:1005,0,1,\"MakeMKV v1.17.7 started\"\n"))
+	w.Write([]byte("TCOUNT:2\n"))
+
+	if err := w.Close(); err != nil {
+		t.Error("Close returned an error:", err)
+		return
+	}
+
+	data, offset, err := Read(1, 0)
+	if err != nil {
+		t.Error("Read returned an error:", err)
+		return
+	}
+
+	expected := "MSG:1005,0,1,\"MakeMKV v1.17.7 started\"\nTCOUNT:2\n"
+	if string(data) != expected {
+		t.Errorf("Read returned '%s', expected '%s'", data, expected)
+	}
+
+	if offset != int64(len(expected)) {
+		t.Errorf("offset = %d, expected %d", offset, len(expected))
+	}
+
+	data, offset, err = Read(1, 39)
+	if err != nil {
+		t.Error("Read returned an error:", err)
+		return
+	}
+
+	if string(data) != "TCOUNT:2\n" {
+		t.Errorf("Read returned '%s', expected 'TCOUNT:2\\n'", data)
+	}
+
+	data, _, err = Read(1, offset+100)
+	if err != nil {
+		t.Error("Read returned an error:", err)
+		return
+	}
+
+	if len(data) != 0 {
+		t.Errorf("Read returned '%s', expected nothing", data)
+	}
+
+	if _, _, err := Read(2, 0); err != ErrTranscriptNotFound {
+		t.Error("Read did not return ErrTranscriptNotFound for a missing transcript")
+	}
+}
+
+func TestReadWhileWriting(t *testing.T) {
+	cfg.Transcript = cfg.TranscriptConfig{Dir: t.TempDir()}
+
+	w, err := Create(1)
+	if err != nil {
+		t.Error("Create returned an error:", err)
+		return
+	}
+	defer w.Close()
+
+	w.Write([]byte("TCOUNT:2\n"))
+	w.gz.Flush()
+
+	data, _, err := Read(1, 0)
+	if err != nil {
+		t.Error("Read returned an error:", err)
+		return
+	}
+
+	if string(data) != "TCOUNT:2\n" {
+		t.Errorf("Read returned '%s', expected 'TCOUNT:2\\n'", data)
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	cfg.Transcript = cfg.TranscriptConfig{Dir: t.TempDir(), MaxSize: 4}
+
+	w, err := Create(1)
+	if err != nil {
+		t.Error("Create returned an error:", err)
+		return
+	}
+
+	if n, err := w.Write([]byte("TCOUNT:2\n")); err != nil || n != 9 {
+		t.Errorf("Write returned (%d, %v), expected (9, nil)", n, err)
+	}
+
+	w.Write([]byte("TCOUNT:3\n"))
+	w.Close()
+
+	data, _, err := Read(1, 0)
+	if err != nil {
+		t.Error("Read returned an error:", err)
+		return
+	}
+
+	if string(data) != "TCOU"+truncatedMarker {
+		t.Errorf("Read returned '%s', expected truncated transcript", data)
+	}
+}
+
+func TestRename(t *testing.T) {
+	cfg.Transcript = cfg.TranscriptConfig{Dir: t.TempDir()}
+
+	w, err := Create(-1)
+	if err != nil {
+		t.Fatal("Create returned an error:", err)
+	}
+
+	w.Write([]byte("TCOUNT:2\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal("Close returned an error:", err)
+	}
+
+	if err := Rename(-1, 7); err != nil {
+		t.Fatal("Rename returned an error:", err)
+	}
+
+	if data, _, err := Read(7, 0); err != nil || string(data) != "TCOUNT:2\n" {
+		t.Errorf("Read of renamed transcript returned '%s', %v", data, err)
+	}
+
+	if _, _, err := Read(-1, 0); err != ErrTranscriptNotFound {
+		t.Error("Transcript still exists under its old identifier")
+	}
+
+	if err := Rename(-2, 8); err != nil {
+		t.Error("Rename returned an error for a missing transcript:", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	cfg.Transcript = cfg.TranscriptConfig{Dir: t.TempDir(), RetentionDays: 30}
+
+	for _, id := range []int{1, 2} {
+		w, err := Create(id)
+		if err != nil {
+			t.Error("Create returned an error:", err)
+			return
+		}
+		w.Close()
+	}
+
+	old := time.Now().AddDate(0, 0, -31)
+	os.Chtimes(filepath.Join(cfg.Transcript.Dir, "1.log.gz"), old, old)
+	os.WriteFile(filepath.Join(cfg.Transcript.Dir, "notes.txt"), []byte{}, 0644)
+	os.Chtimes(filepath.Join(cfg.Transcript.Dir, "notes.txt"), old, old)
+
+	count, err := Purge(time.Now())
+	if err != nil {
+		t.Error("Purge returned an error:", err)
+	}
+
+	if count != 1 {
+		t.Errorf("Purge removed %d transcripts, expected 1", count)
+	}
+
+	if _, _, err := Read(1, 0); err != ErrTranscriptNotFound {
+		t.Error("Expected transcript 1 to be purged")
+	}
+
+	if _, _, err := Read(2, 0); err != nil {
+		t.Error("Expected transcript 2 to be kept")
+	}
+
+	if _, err := os.Stat(filepath.Join(cfg.Transcript.Dir, "notes.txt")); err != nil {
+		t.Error("Purge removed a file that isn't a transcript")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// catalogDisc identifies the disc with label `label` described by `info` and
+// returns it from the disc catalog, adding it to the catalog if it isn't there
+// yet. The identifier of the operation that already copied the disc
+// successfully is also returned, or zero if it hasn't been copied.
+func (w *Worker) catalogDisc(ctx context.Context, info makemkv.DiscInfo, label string) (models.Disc, int, error) {
+	fingerprint := info.Fingerprint()
+	if fingerprint == "" {
+		return models.Disc{}, 0, errors.New("makemkv did not report any disc information")
+	}
+
+	disc, err := w.repo.GetDiscByFingerprint(ctx, fingerprint)
+	if errors.Is(err, db.ErrDiscNotFound) {
+		disc = newDisc(info, label, fingerprint)
+		if err := w.repo.CreateDisc(ctx, &disc); err != nil {
+			return disc, 0, fmt.Errorf("failed to add disc to catalog: %w", err)
+		}
+		return disc, 0, nil
+	} else if err != nil {
+		return disc, 0, fmt.Errorf("failed to look up disc: %w", err)
+	}
+
+	ops, err := w.repo.ListCopyOperationsForDisc(ctx, disc.Id)
+	if err != nil {
+		return disc, 0, fmt.Errorf("failed to list copy operations for disc: %w", err)
+	}
+
+	for _, op := range ops {
+		if op.State == models.CopyStateSucceeded {
+			return disc, op.Id, nil
+		}
+	}
+
+	return disc, 0, nil
+}
+
+// newDisc creates a catalog entry with fingerprint `fingerprint` for the disc
+// with label `label` described by `info`.
+func newDisc(info makemkv.DiscInfo, label, fingerprint string) models.Disc {
+	disc := models.Disc{
+		Label:       label,
+		Name:        info.Attributes[makemkv.AI_NAME],
+		VolumeName:  info.Attributes[makemkv.AI_VOLUME_NAME],
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+	}
+
+	for i, t := range info.Titles {
+		title := models.DiscTitle{
+			Index:       i,
+			Name:        t.Attributes[makemkv.AI_NAME],
+			Duration:    parseDuration(t.Attributes[makemkv.AI_DURATION]),
+			SegmentsMap: t.Attributes[makemkv.AI_SEGMENTS_MAP],
+			SourceFile:  t.Attributes[makemkv.AI_SOURCE_FILE_NAME],
+		}
+		title.Size, _ = strconv.ParseInt(t.Attributes[makemkv.AI_DISK_SIZE_BYTES], 10, 64)
+		title.ChapterCount, _ = strconv.Atoi(t.Attributes[makemkv.AI_CHAPTER_COUNT])
+
+		for j, s := range t.Streams {
+			title.Streams = append(title.Streams, models.DiscStream{
+				Index:    j,
+				Type:     s.Attributes[makemkv.AI_TYPE],
+				Codec:    s.Attributes[makemkv.AI_CODEC_SHORT],
+				LangCode: s.Attributes[makemkv.AI_LANG_CODE],
+				Name:     s.Attributes[makemkv.AI_NAME],
+			})
+		}
+
+		disc.Titles = append(disc.Titles, title)
+	}
+
+	return disc
+}
+
+// parseDuration converts the duration `s` reported by MakeMKV in the format
+// h:mm:ss to seconds. Zero is returned if `s` isn't in that format.
+func parseDuration(s string) int {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+
+	seconds := 0
+	for _, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return 0
+		}
+		seconds = seconds*60 + v
+	}
+
+	return seconds
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// copyJob tracks the state of a copy operation while MakeMKV is running.
+type copyJob struct {
+	op      models.CopyOperation
+	license makemkv.License
+
+	// disc is the information about the disc MakeMKV reports before copying
+	// it.
+	disc makemkv.DiscInfo
+
+	// progress is the overall progress of the copy as a percentage.
+	progress float64
+
+	// maxReadErrors is the number of read errors above which the copy fails
+	// or zero if there is no limit.
+	maxReadErrors int
+
+	// readErrorSources is the number of read errors reported for each file
+	// on the disc. Unlike the operation's read error details, every error is
+	// counted.
+	readErrorSources map[string]int
+}
+
+func newCopyJob(op models.CopyOperation, maxReadErrors int) *copyJob {
+	return &copyJob{
+		op:               op,
+		license:          makemkv.License{State: makemkv.LS_UNKNOWN},
+		maxReadErrors:    maxReadErrors,
+		readErrorSources: make(map[string]int),
+	}
+}
+
+// handleMessage handles message `msg` from MakeMKV's output. Returns true if
+// the copy operation was changed in a way that should be saved right away.
+func (j *copyJob) handleMessage(msg any) bool {
+	switch m := msg.(type) {
+	case makemkv.GeneralMessage:
+		j.license.Update(m)
+		if re, ok := makemkv.ParseReadError(m); ok {
+			return j.handleReadError(re)
+		}
+		return j.handleGeneralMessage(m)
+	case makemkv.ProgressValueMessage:
+		if m.Max > 0 {
+			j.progress = float64(m.Total) / float64(m.Max) * 100
+		}
+	default:
+		if err := j.disc.AddMessage(msg); err != nil {
+			slog.Debug("Failed to add disc information.", "id", j.op.Id, "msg", msg, "error", err)
+		}
+	}
+
+	return false
+}
+
+// handleGeneralMessage classifies the MakeMKV message `msg` and records it in
+// the copy operation if it is a warning or fatal error. Returns true if the
+// copy operation was changed.
+func (j *copyJob) handleGeneralMessage(msg makemkv.GeneralMessage) bool {
+	mc, _ := makemkv.GetMessageCode(msg.Code)
+	reason := fmt.Sprintf("%s (%d): %s", mc.Description, msg.Code, msg.Message)
+
+	switch mc.Severity {
+	case makemkv.MS_WARNING:
+		slog.Warn("MakeMKV reported a warning.", "id", j.op.Id, "code", msg.Code, "category", mc.Category, "message", msg.Message)
+		j.op.Warnings = append(j.op.Warnings, reason)
+		return true
+	case makemkv.MS_FATAL:
+		slog.Error("MakeMKV reported a fatal error.", "id", j.op.Id, "code", msg.Code, "category", mc.Category, "message", msg.Message)
+		j.setFailureReason(reason)
+		return true
+	default:
+		slog.Debug("MakeMKV message.", "id", j.op.Id, "code", msg.Code, "message", msg.Message)
+		return false
+	}
+}
+
+// handleReadError records read error `re` in the copy operation. Returns true
+// if this was the first read error or the error caused the copy to fail. Other
+// read errors are saved with the copy operation when it ends to avoid a
+// database write for every error on a badly damaged disc.
+func (j *copyJob) handleReadError(re makemkv.ReadError) bool {
+	slog.Warn("MakeMKV reported a read error.", "id", j.op.Id, "kind", re.Kind, "source", re.Source, "offset", re.Offset, "error", re.Error)
+
+	first := !j.op.Damaged
+	j.op.Damaged = true
+	j.op.ReadErrorCount++
+	j.readErrorSources[re.Source]++
+
+	if len(j.op.ReadErrors) < models.MaxReadErrorDetails {
+		j.op.ReadErrors = append(j.op.ReadErrors, models.ReadError{
+			Kind:     string(re.Kind),
+			Error:    re.Error,
+			Source:   re.Source,
+			Title:    sourceTitle(j.disc, re.Source),
+			Offset:   re.Offset,
+			Progress: j.progress,
+		})
+	}
+
+	if j.maxReadErrors > 0 && j.op.ReadErrorCount == j.maxReadErrors+1 {
+		j.setFailureReason(fmt.Sprintf("too many read errors: more than %d reported", j.maxReadErrors))
+		return true
+	}
+
+	return first
+}
+
+// setFailureReason sets the reason the copy operation failed. Only the first
+// reason is kept since any that follow are usually a consequence of it. Later
+// reasons are added to the operation's warnings instead.
+func (j *copyJob) setFailureReason(reason string) {
+	if j.op.FailureReason == "" {
+		j.op.FailureReason = reason
+	} else {
+		j.op.Warnings = append(j.op.Warnings, reason)
+	}
+}
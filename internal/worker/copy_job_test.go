@@ -0,0 +1,127 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"testing"
+
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+func TestHandleGeneralMessage(t *testing.T) {
+	job := newCopyJob(models.CopyOperation{State: models.CopyStateRunning}, 0)
+
+	if job.handleMessage(makemkv.GeneralMessage{Code: 3007, Message: "Using direct disc access mode"}) {
+		t.Error("handleMessage changed the operation for an info message")
+	}
+
+	if !job.handleMessage(makemkv.GeneralMessage{Code: 5003, Message: "Failed to save title 0"}) {
+		t.Error("handleMessage did not change the operation for a fatal message")
+	}
+
+	expected := "failed to save title (5003): Failed to save title 0"
+	if job.op.FailureReason != expected {
+		t.Errorf("FailureReason = '%s', expected '%s'", job.op.FailureReason, expected)
+	}
+
+	job.handleMessage(makemkv.GeneralMessage{Code: 5037, Message: "Copy complete"})
+
+	if job.op.FailureReason != expected {
+		t.Errorf("FailureReason = '%s', expected '%s'", job.op.FailureReason, expected)
+	}
+
+	if len(job.op.Warnings) != 1 {
+		t.Errorf("Expected 1 warning, got %d", len(job.op.Warnings))
+	}
+}
+
+func TestHandleReadError(t *testing.T) {
+	job := newCopyJob(models.CopyOperation{State: models.CopyStateRunning}, 2)
+
+	readError := makemkv.GeneralMessage{
+		Code:    2003,
+		Message: "Error 'Scsi error - MEDIUM ERROR' occurred while reading '/BDMV/STREAM/00800.m2ts' at offset '1234'",
+		Params:  []string{"Scsi error - MEDIUM ERROR", "/BDMV/STREAM/00800.m2ts", "1234"},
+	}
+
+	job.handleMessage(makemkv.TitleInfoMessage{Index: 0, Attribute: makemkv.Attribute{Id: makemkv.AI_SEGMENTS_MAP, Value: "100"}})
+	job.handleMessage(makemkv.TitleInfoMessage{Index: 1, Attribute: makemkv.Attribute{Id: makemkv.AI_SEGMENTS_MAP, Value: "799-801"}})
+	job.handleMessage(makemkv.ProgressValueMessage{Current: 0, Total: 16384, Max: 65536})
+
+	if !job.handleMessage(readError) {
+		t.Error("handleMessage did not change the operation for the first read error")
+	}
+
+	if !job.op.Damaged {
+		t.Error("Expected operation to be marked as damaged")
+	}
+
+	if len(job.op.ReadErrors) != 1 {
+		t.Errorf("Expected 1 read error, got %d", len(job.op.ReadErrors))
+		return
+	}
+
+	re := job.op.ReadErrors[0]
+	if re.Kind != "medium" {
+		t.Errorf("Kind = '%s', expected 'medium'", re.Kind)
+	}
+	if re.Source != "/BDMV/STREAM/00800.m2ts" {
+		t.Errorf("Source = '%s', expected '/BDMV/STREAM/00800.m2ts'", re.Source)
+	}
+	if re.Title != 1 {
+		t.Errorf("Title = %d, expected 1", re.Title)
+	}
+	if re.Offset != 1234 {
+		t.Errorf("Offset = %d, expected 1234", re.Offset)
+	}
+	if re.Progress != 25 {
+		t.Errorf("Progress = %f, expected 25", re.Progress)
+	}
+
+	if len(job.op.Warnings) != 0 {
+		t.Errorf("Expected read errors to not be added to warnings, got %d", len(job.op.Warnings))
+	}
+
+	if job.handleMessage(readError) {
+		t.Error("handleMessage changed the operation for a read error below the threshold")
+	}
+
+	if !job.handleMessage(readError) {
+		t.Error("handleMessage did not change the operation when the threshold was exceeded")
+	}
+
+	if job.op.ReadErrorCount != 3 {
+		t.Errorf("ReadErrorCount = %d, expected 3", job.op.ReadErrorCount)
+	}
+
+	if job.op.FailureReason == "" {
+		t.Error("Expected operation to fail after exceeding the read error threshold")
+	}
+}
@@ -0,0 +1,443 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/manifest"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/naming"
+)
+
+// completeFileName returns the name of the file written to the output
+// directory of the copy operation with identifier `id` once all of its files
+// are in place. Programs watching the output directory should wait for it
+// before using the files. It is named after the operation since copies can
+// share an output directory.
+func completeFileName(id int) string {
+	return fmt.Sprintf(".complete-%d", id)
+}
+
+// completeManifest is the content of the complete file. The complete file is
+// only a signal. The files are described by the manifest it names.
+type completeManifest struct {
+	OperationId int       `json:"operation_id"`
+	DiscLabel   string    `json:"disc_label"`
+	Files       []string  `json:"files"`
+	Manifest    string    `json:"manifest"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// handoff moves the verified MKV files, `verified`, of the successful copy
+// operation `op`, tracked by `job`, from its staging directory to the output
+// directory configured by `conf`, writes the operation's manifest next to
+// them, and writes the complete file last. The files that failed
+// verification, `rejected`, are listed in the manifest and deleted with the
+// staging directory. The files are named with the naming templates using the
+// information MakeMKV reported about the disc and the drive it was copied
+// from, `od`. On success, the operation's output directory is updated to
+// where the files were moved and its manifest is set.
+func handoff(conf *cfg.Reloadable, op *models.CopyOperation, job *copyJob, od models.OpticalDrive, verified map[string]verifiedFile, rejected []rejectedFile) error {
+	templates, err := conf.Naming.Templates()
+	var renames []rename
+	var dir string
+	if err == nil {
+		renames, dir, err = planRenames(templates, conf.MakeMkv.OutDir, op, job.disc, od.Name)
+	}
+
+	// A problem with the names isn't worth failing a successful copy over so
+	// the names MakeMKV used are kept instead.
+	if err != nil {
+		slog.Warn("Failed to name output files.", "id", op.Id, "error", err)
+		op.Warnings = append(op.Warnings, fmt.Sprintf("failed to name output files: %s", err))
+		if renames, dir, err = planRenames(&naming.Templates{}, conf.MakeMkv.OutDir, op, job.disc, od.Name); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// A complete file left behind by an earlier attempt would tell programs
+	// watching the output directory that the files are ready too soon.
+	if err := os.Remove(filepath.Join(dir, completeFileName(op.Id))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove complete file: %w", err)
+	}
+
+	m := newManifest(op, job, od)
+	for _, r := range rejected {
+		m.Rejected = append(m.Rejected, manifest.RejectedFile{Name: r.name, Title: r.title, Reason: r.err.Error()})
+	}
+
+	// The files that were put in the output directory are removed if the
+	// handoff fails so that they aren't left there without a manifest. They
+	// are still in staging.
+	var placed []string
+	undo := func() {
+		for _, path := range placed {
+			os.Remove(path)
+		}
+		os.Remove(filepath.Join(dir, manifest.FileName(op.Id)))
+		os.Remove(dir)
+	}
+
+	for _, r := range renames {
+		v, ok := verified[filepath.Base(r.from)]
+		if !ok {
+			continue
+		}
+		file := describeFile(job, v, r.title)
+
+		to, err := placeFile(r.from, r.to)
+		if err != nil {
+			undo()
+			return fmt.Errorf("failed to move %s: %w", filepath.Base(r.from), err)
+		}
+		placed = append(placed, to)
+
+		file.Name = filepath.Base(to)
+		m.Files = append(m.Files, file)
+	}
+
+	if err := complete(op, dir, m); err != nil {
+		undo()
+		return err
+	}
+
+	if err := os.RemoveAll(op.OutputDir); err != nil {
+		slog.Warn("Failed to remove staging directory.", "id", op.Id, "error", err)
+	}
+	op.OutputDir = dir
+
+	return nil
+}
+
+// handoffBackup moves the backup of the successful copy operation `op`,
+// tracked by `job`, from its staging directory to the output directory
+// configured by `conf` and writes the operation's manifest and the complete
+// file into it. The backup is moved as a whole to the directory named by the
+// naming templates, or the first numbered variant of it that doesn't exist,
+// using the information MakeMKV reported about the disc and the drive it was
+// copied from, `od`. On success, the operation's output directory is updated
+// to where the backup was moved and its manifest is set.
+func handoffBackup(conf *cfg.Reloadable, op *models.CopyOperation, job *copyJob, od models.OpticalDrive) error {
+	data := templateData(op, job.disc, od.Name)
+
+	templates, err := conf.Naming.Templates()
+	var dir string
+	if err == nil {
+		dir, err = outputDir(templates, conf.MakeMkv.OutDir, data)
+	}
+
+	if err != nil {
+		slog.Warn("Failed to name output directory.", "id", op.Id, "error", err)
+		op.Warnings = append(op.Warnings, fmt.Sprintf("failed to name output directory: %s", err))
+		if dir, err = outputDir(&naming.Templates{}, conf.MakeMkv.OutDir, data); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	dir, err = placeDir(op.OutputDir, dir)
+	if err != nil {
+		return fmt.Errorf("failed to move backup: %w", err)
+	}
+
+	if err := complete(op, dir, newManifest(op, job, od)); err != nil {
+		// The backup is put back in staging if it was renamed rather than
+		// copied so that it isn't lost.
+		os.Remove(filepath.Join(dir, manifest.FileName(op.Id)))
+		if _, statErr := os.Stat(op.OutputDir); errors.Is(statErr, fs.ErrNotExist) {
+			if err := os.Rename(dir, op.OutputDir); err != nil {
+				slog.Error("Failed to put backup back in staging.", "id", op.Id, "path", dir, "error", err)
+			}
+		} else {
+			os.RemoveAll(dir)
+		}
+		return err
+	}
+
+	if err := os.RemoveAll(op.OutputDir); err != nil {
+		slog.Warn("Failed to remove staging directory.", "id", op.Id, "error", err)
+	}
+	op.OutputDir = dir
+
+	return nil
+}
+
+// complete writes the manifest `m` of the copy operation `op` to the output
+// directory `dir` followed by the complete file, and sets the operation's
+// manifest.
+func complete(op *models.CopyOperation, dir string, m manifest.Manifest) error {
+	m.CompletedAt = time.Now()
+	if _, err := manifest.Write(dir, m); err != nil {
+		return err
+	}
+
+	complete := completeManifest{
+		OperationId: op.Id,
+		DiscLabel:   op.DiscLabel,
+		Files:       make([]string, 0, len(m.Files)),
+		Manifest:    manifest.FileName(op.Id),
+		CompletedAt: m.CompletedAt,
+	}
+	for _, file := range m.Files {
+		complete.Files = append(complete.Files, file.Name)
+	}
+
+	if err := writeComplete(dir, complete); err != nil {
+		return err
+	}
+
+	bs, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	op.Manifest = bs
+
+	return nil
+}
+
+// linkFile creates a hard link. Tests replace it to simulate an output
+// filesystem that doesn't support hard links.
+var linkFile = os.Link
+
+// renameDir renames a directory, replacing an empty one. Unlike os.Rename,
+// rename(2) allows that. Tests replace it to simulate a staging directory on
+// another filesystem.
+var renameDir = syscall.Rename
+
+// placeFile puts the file at `from` at `to` or, if something already exists
+// there, at the first numbered variant of it that doesn't. Returns where the
+// file was put. The file at `from` is left in place so that a handoff that
+// fails part way can be undone without losing it. The file is linked into
+// place so that a file another copy put there at the same time is never
+// replaced. If it can't be linked, such as when the paths are on different
+// filesystems or the output filesystem doesn't support hard links, it is
+// copied to a hidden temporary file next to `to` instead, an empty file is
+// created to claim the name, and the copy is renamed over it so that the file
+// never appears partially written.
+func placeFile(from, to string) (string, error) {
+	placed, err := naming.Claim(to, func(path string) error {
+		return linkFile(from, path)
+	})
+	if err == nil {
+		return placed, nil
+	}
+
+	tmp, err := copyTemp(from, filepath.Dir(to))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	placed, err = naming.Claim(to, func(path string) error {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp, placed); err != nil {
+		os.Remove(placed)
+		return "", err
+	}
+
+	return placed, nil
+}
+
+// placeDir puts the directory at `from` at `to` or, if something already
+// exists there, at the first numbered variant of it that doesn't. Returns
+// where the directory was put. The destination is claimed by creating it so
+// that a directory another copy put there at the same time is never replaced,
+// and the directory at `from` is renamed over the empty directory. If it
+// can't be renamed, such as when the paths are on different filesystems, its
+// contents are copied to a hidden temporary directory next to `to` which is
+// renamed into place once the copy is complete, and the directory at `from`
+// is left for the caller to remove. Nothing at `from` is removed if it fails.
+func placeDir(from, to string) (string, error) {
+	placed, err := naming.Claim(to, func(path string) error {
+		return os.Mkdir(path, 0755)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := renameDir(from, placed); err == nil {
+		return placed, nil
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(to), "."+filepath.Base(placed)+".*.partial")
+	if err == nil {
+		if err = os.Chmod(tmp, 0755); err == nil {
+			err = copyDir(from, tmp)
+		}
+		if err == nil {
+			err = renameDir(tmp, placed)
+		}
+		if err != nil {
+			os.RemoveAll(tmp)
+		}
+	}
+	if err != nil {
+		os.Remove(placed)
+		return "", err
+	}
+
+	return placed, nil
+}
+
+// copyDir copies the contents of the directory `from` into the existing
+// directory `to`.
+func copyDir(from, to string) error {
+	return filepath.WalkDir(from, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == from {
+			return err
+		}
+
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(to, rel)
+
+		if entry.IsDir() {
+			return os.Mkdir(dst, 0755)
+		}
+
+		f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+
+		if err := copyFile(f, path); err != nil {
+			f.Close()
+			return err
+		}
+
+		return f.Close()
+	})
+}
+
+// copyTemp copies the file at `from` to a hidden temporary file in the
+// directory `dir`. Returns the path of the copy.
+func copyTemp(from, dir string) (string, error) {
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(from)+".*.partial")
+	if err != nil {
+		return "", err
+	}
+
+	if err := copyFile(tmp, from); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// copyFile copies the contents of the file at `from` to `dst` and syncs it.
+func copyFile(dst *os.File, from string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+
+	return dst.Sync()
+}
+
+// writeComplete writes `manifest` to the complete file of its copy operation
+// in the directory `dir`. It is written to a temporary file first so that it
+// never appears partially written.
+func writeComplete(dir string, manifest completeManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode complete file: %w", err)
+	}
+
+	name := completeFileName(manifest.OperationId)
+	tmp, err := os.CreateTemp(dir, name+".*.partial")
+	if err != nil {
+		return fmt.Errorf("failed to create complete file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write complete file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write complete file: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write complete file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("failed to write complete file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/manifest"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// newManifest returns the manifest of the copy operation `op`, tracked by
+// `job`, for the disc in the drive `od` without any files.
+func newManifest(op *models.CopyOperation, job *copyJob, od models.OpticalDrive) manifest.Manifest {
+	return manifest.Manifest{
+		Version:     manifest.Version,
+		OperationId: op.Id,
+		Drive: manifest.Drive{
+			Name:   od.Name,
+			Serial: od.SerialNumber,
+			Host:   od.Host,
+		},
+		Disc: manifest.Disc{
+			Label:       op.DiscLabel,
+			Name:        job.disc.Attributes[makemkv.AI_NAME],
+			VolumeName:  job.disc.Attributes[makemkv.AI_VOLUME_NAME],
+			Fingerprint: job.disc.Fingerprint(),
+		},
+		StartedAt:      op.StartedAt,
+		ReadErrorCount: op.ReadErrorCount,
+		Files:          make([]manifest.File, 0),
+		Rejected:       make([]manifest.RejectedFile, 0),
+	}
+}
+
+// describeFile returns the manifest entry of the verified MKV file `v` which
+// was copied from the title at index `title` of the disc tracked by `job`.
+func describeFile(job *copyJob, v verifiedFile, title int) manifest.File {
+	file := manifest.File{
+		Title:   title,
+		Size:    v.size,
+		SHA256:  v.sha256,
+		Streams: make([]manifest.Stream, 0),
+	}
+
+	if title < 0 || title >= len(job.disc.Titles) {
+		return file
+	}
+
+	info := job.disc.Titles[title]
+	file.Duration = parseDuration(info.Attributes[makemkv.AI_DURATION])
+	file.ReadErrorCount = titleReadErrors(info.Attributes[makemkv.AI_SEGMENTS_MAP], job.readErrorSources)
+
+	for i, s := range info.Streams {
+		file.Streams = append(file.Streams, manifest.Stream{
+			Index:        i,
+			Type:         s.Attributes[makemkv.AI_TYPE],
+			Codec:        s.Attributes[makemkv.AI_CODEC_SHORT],
+			Language:     s.Attributes[makemkv.AI_LANG_CODE],
+			LanguageName: s.Attributes[makemkv.AI_LANG_NAME],
+			Name:         s.Attributes[makemkv.AI_NAME],
+		})
+	}
+
+	return file
+}
+
+// titleReadErrors returns the number of read errors in `sources`, the counts
+// of errors by the disc file they occurred in, that occurred in the segments
+// of a title. `segments` is the title's segment map as reported by MakeMKV,
+// e.g. "800,801" or "1-3", which lists the numbers of the stream files the
+// title is made of. Errors in files that aren't numbered, such as DVD VOB
+// files, can't be attributed to a title and aren't counted.
+func titleReadErrors(segments string, sources map[string]int) int {
+	count := 0
+	for source, n := range sources {
+		if number, ok := segmentNumber(source); ok && inSegments(segments, number) {
+			count += n
+		}
+	}
+	return count
+}
+
+// sourceTitle returns the index of the first title in `disc` whose segments
+// include the disc file `source` or -1 if there isn't one. Files that aren't
+// numbered, such as DVD VOB files, can't be attributed to a title.
+func sourceTitle(disc makemkv.DiscInfo, source string) int {
+	number, ok := segmentNumber(source)
+	if !ok {
+		return -1
+	}
+
+	for i, title := range disc.Titles {
+		if inSegments(title.Attributes[makemkv.AI_SEGMENTS_MAP], number) {
+			return i
+		}
+	}
+	return -1
+}
+
+// segmentNumber returns the number of the stream file `source` on the disc,
+// e.g. 800 for "/BDMV/STREAM/00800.m2ts". Returns false if the file isn't
+// numbered.
+func segmentNumber(source string) (int, bool) {
+	name := path.Base(strings.ReplaceAll(source, "\\", "/"))
+	number, err := strconv.Atoi(strings.TrimSuffix(name, path.Ext(name)))
+	return number, err == nil
+}
+
+// inSegments returns true if the segment map `segments` includes the segment
+// numbered `number`.
+func inSegments(segments string, number int) bool {
+	for _, part := range strings.Split(segments, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+
+		lo, err := strconv.Atoi(first)
+		if err != nil {
+			continue
+		}
+
+		hi := lo
+		if isRange {
+			if hi, err = strconv.Atoi(last); err != nil {
+				continue
+			}
+		}
+
+		if number >= lo && number <= hi {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/naming"
+)
+
+// titlePattern matches the index of the title in the name MakeMKV gives its
+// MKV files, e.g. title_t00.mkv.
+var titlePattern = regexp.MustCompile(`_t(\d+)\.mkv$`)
+
+// rename is a file of a copy that is being moved to the output directory.
+type rename struct {
+	from string
+	to   string
+
+	// title is the index of the title the file was copied from or -1 if it
+	// isn't known.
+	title int
+}
+
+// planRenames returns where each MKV file of the copy operation `op` is moved
+// to and the directory within the output directory `outDir` they are moved
+// to. The paths are created from `templates` which can be empty to keep the
+// names MakeMKV used and to use a directory named after the operation. `disc`
+// is the information MakeMKV reported about the disc and `driveName` is the
+// name of the drive it was copied from.
+func planRenames(templates *naming.Templates, outDir string, op *models.CopyOperation, disc makemkv.DiscInfo, driveName string) ([]rename, string, error) {
+	data := templateData(op, disc, driveName)
+
+	dir, err := outputDir(templates, outDir, data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries, err := os.ReadDir(op.OutputDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var renames []rename
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".mkv") {
+			continue
+		}
+
+		data.Title = titleIndex(disc, entry.Name())
+		data.Duration = ""
+		if data.Title >= 0 && data.Title < len(disc.Titles) {
+			data.Duration = disc.Titles[data.Title].Attributes[makemkv.AI_DURATION]
+		}
+
+		name, err := templates.File(data, filepath.Ext(entry.Name()))
+		if err != nil {
+			return nil, "", err
+		} else if name == "" {
+			name = entry.Name()
+		}
+
+		renames = append(renames, rename{
+			from:  filepath.Join(op.OutputDir, entry.Name()),
+			to:    filepath.Join(dir, name),
+			title: data.Title,
+		})
+	}
+
+	return renames, dir, nil
+}
+
+// templateData returns the information about the copy operation `op` that is
+// available to the naming templates. `disc` is the information MakeMKV
+// reported about the disc and `driveName` is the name of the drive it was
+// copied from.
+func templateData(op *models.CopyOperation, disc makemkv.DiscInfo, driveName string) naming.Data {
+	return naming.Data{
+		DiscLabel:   op.DiscLabel,
+		VolumeName:  disc.Attributes[makemkv.AI_VOLUME_NAME],
+		OperationId: op.Id,
+		DriveName:   driveName,
+		Date:        op.StartedAt,
+	}
+}
+
+// outputDir returns the directory within the output directory `outDir` that
+// the files of a copy are moved to. It is created from `templates` using
+// `data` or is named after the operation if there isn't a directory template.
+func outputDir(templates *naming.Templates, outDir string, data naming.Data) (string, error) {
+	sub, err := templates.Dir(data)
+	if err != nil {
+		return "", err
+	} else if sub == "" {
+		return filepath.Join(outDir, strconv.Itoa(data.OperationId)), nil
+	}
+
+	return filepath.Join(outDir, sub), nil
+}
+
+// titleIndex returns the index of the title MakeMKV wrote to the file `name`
+// or -1 if it isn't known. The output file names reported with the disc
+// information are used if available and otherwise the index is taken from the
+// name.
+func titleIndex(disc makemkv.DiscInfo, name string) int {
+	for i, title := range disc.Titles {
+		if title.Attributes[makemkv.AI_OUTPUT_FILE_NAME] == name {
+			return i
+		}
+	}
+
+	if m := titlePattern.FindStringSubmatch(name); m != nil {
+		if i, err := strconv.Atoi(m[1]); err == nil {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/models"
+)
+
+// maxProgressSamples is the number of progress samples kept for a copy
+// operation once it ends. It is enough to chart the throughput of a copy
+// without keeping every sample of a long one.
+const maxProgressSamples = 300
+
+// progressSampler records the progress of a copy operation at a fixed
+// interval.
+type progressSampler struct {
+	repo     db.CopyOperationRepository
+	op       models.CopyOperation
+	interval time.Duration
+	last     time.Time
+}
+
+func newProgressSampler(repo db.CopyOperationRepository, op models.CopyOperation, interval time.Duration) *progressSampler {
+	return &progressSampler{repo: repo, op: op, interval: interval}
+}
+
+// sample records the progress `percent` at time `now` unless a sample was
+// recorded less than the sampling interval ago.
+func (s *progressSampler) sample(now time.Time, percent float64) {
+	if now.Sub(s.last) < s.interval {
+		return
+	}
+	s.last = now
+
+	title, bytes := outputProgress(s.op.OutputDir)
+	sample := models.CopyProgressSample{
+		CopyOperationId: s.op.Id,
+		SampledAt:       now,
+		Title:           title,
+		Bytes:           bytes,
+		Percent:         percent,
+	}
+
+	// Samples are only used for charting so a missing one isn't worth more
+	// than a debug message.
+	if err := s.repo.AddCopyProgressSample(context.Background(), sample); err != nil {
+		slog.Debug("Failed to record copy progress.", "id", s.op.Id, "error", err)
+	}
+}
+
+// finish records a final sample with progress `percent` and reduces the
+// samples to at most maxProgressSamples.
+func (s *progressSampler) finish(percent float64) {
+	s.last = time.Time{}
+	s.sample(time.Now(), percent)
+
+	ctx := context.Background()
+	samples, err := s.repo.ListCopyProgressSamples(ctx, s.op.Id)
+	if err != nil {
+		slog.Warn("Failed to get copy progress.", "id", s.op.Id, "error", err)
+		return
+	}
+
+	if len(samples) <= maxProgressSamples {
+		return
+	}
+
+	if err := s.repo.ReplaceCopyProgressSamples(ctx, s.op.Id, downsample(samples, maxProgressSamples)); err != nil {
+		slog.Warn("Failed to downsample copy progress.", "id", s.op.Id, "error", err)
+	}
+}
+
+// downsample returns `limit` samples evenly spaced across `samples` always
+// including the first and last sample.
+func downsample(samples []models.CopyProgressSample, limit int) []models.CopyProgressSample {
+	if len(samples) <= limit || limit < 2 {
+		return samples
+	}
+
+	result := make([]models.CopyProgressSample, limit)
+	for i := range limit {
+		result[i] = samples[i*(len(samples)-1)/(limit-1)]
+	}
+
+	return result
+}
+
+// outputProgress returns the index of the title being written and the number
+// of bytes written so far to the MKV files in the output directory `dir`.
+// MakeMKV writes one title at a time so the title being written is the last
+// of the files created so far.
+func outputProgress(dir string) (int, int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0
+	}
+
+	count := 0
+	var bytes int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".mkv") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		count++
+		bytes += info.Size()
+	}
+
+	return max(count-1, 0), bytes
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+)
+
+// NewRunner creates a MakeMKV runner using the configuration `conf`. MakeMKV
+// is run with the configured private home directory so that its behavior
+// doesn't depend on the settings of the user running the service.
+func NewRunner(conf cfg.MakeMkvConfig) *makemkv.Runner {
+	options := makemkv.Options{
+		MinLength: conf.MinLength,
+		CacheSize: conf.CacheSize,
+		Profile:   conf.Profile,
+		Decrypt:   conf.Mode == cfg.ModeBackup && conf.Decrypt,
+	}
+	if conf.DirectIO != "" {
+		directIO := conf.DirectIO == "true"
+		options.DirectIO = &directIO
+	}
+
+	return &makemkv.Runner{
+		Exe:             conf.MakeMKV,
+		OutputTimeout:   time.Duration(conf.OutputStallTimeout) * time.Second,
+		ProgressTimeout: time.Duration(conf.ProgressStallTimeout) * time.Second,
+		Options:         options,
+		Home:            conf.HomeDir,
+		Settings:        makemkvSettings(conf),
+	}
+}
+
+// makemkvSettings returns the settings written to MakeMKV's settings file for
+// the configuration `conf`.
+func makemkvSettings(conf cfg.MakeMkvConfig) map[string]string {
+	// Updates are checked for by whoever maintains the host rather than by
+	// every run of the service.
+	settings := map[string]string{
+		"app_UpdateEnable": "0",
+	}
+
+	key := conf.LicenseKey
+	if key == "" {
+		key = userLicenseKey()
+	}
+	if key != "" {
+		settings["app_Key"] = key
+	}
+
+	if conf.MinLength > 0 {
+		settings["dvd_MinimumTitleLength"] = strconv.Itoa(conf.MinLength)
+	}
+
+	if conf.CacheSize > 0 {
+		settings["io_RBufSizeMB"] = strconv.Itoa(conf.CacheSize)
+	}
+
+	return settings
+}
+
+// userLicenseKey returns the MakeMKV license key from the settings of the user
+// running the service or an empty string if there isn't one.
+func userLicenseKey() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	settings, err := makemkv.ReadSettings(makemkv.SettingsPath(home))
+	if err != nil {
+		return ""
+	}
+
+	return settings["app_Key"]
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/preflight"
+)
+
+// checkSpace checks that the staging and output directories configured by
+// `conf` have room for a copy of `size` bytes plus the configured margin. Both
+// are checked since the output directory can be on another filesystem, in
+// which case the files are copied to it once the copy is done. The returned
+// error wraps ErrInsufficientSpace if either doesn't have room.
+func checkSpace(conf cfg.MakeMkvConfig, size int64) error {
+	for _, dir := range []string{conf.StagingDir, conf.OutDir} {
+		free, err := preflight.FreeSpace(dir)
+		if err != nil {
+			return fmt.Errorf("failed to get free space of %s: %w", dir, err)
+		}
+
+		if err := spaceNeeded(free, size, conf.SpaceMargin); err != nil {
+			return fmt.Errorf("%w: %s %s", ErrInsufficientSpace, dir, err)
+		}
+	}
+
+	return nil
+}
+
+// spaceNeeded returns an error describing the shortfall if `free` bytes
+// aren't enough to write `remaining` more bytes and still leave `margin`
+// bytes free.
+func spaceNeeded(free uint64, remaining, margin int64) error {
+	needed := max(remaining, 0) + margin
+	if free < uint64(needed) {
+		return fmt.Errorf("has %d bytes free, %d bytes are needed for the copy and %d bytes must be left free", free, max(remaining, 0), margin)
+	}
+	return nil
+}
+
+// checkHandoffSpace checks that the output directory configured by `conf` has
+// room for the copy in the staging directory `staging` plus the configured
+// margin before the copy is moved to it. Only copies staged on another
+// filesystem are checked since the others are renamed into place. All of the
+// staged files are counted, even ones that won't be moved, so the check errs
+// on the side of refusing. The returned error wraps ErrInsufficientSpace if
+// the output directory doesn't have room.
+func checkHandoffSpace(conf cfg.MakeMkvConfig, staging string) error {
+	same, err := preflight.SameFilesystem(staging, conf.OutDir)
+	if err != nil {
+		return fmt.Errorf("failed to compare the filesystems of %s and %s: %w", staging, conf.OutDir, err)
+	}
+	if same {
+		return nil
+	}
+
+	free, err := preflight.FreeSpace(conf.OutDir)
+	if err != nil {
+		return fmt.Errorf("failed to get free space of %s: %w", conf.OutDir, err)
+	}
+
+	if err := spaceNeeded(free, dirSize(staging), conf.SpaceMargin); err != nil {
+		return fmt.Errorf("%w: %s %s", ErrInsufficientSpace, conf.OutDir, err)
+	}
+
+	return nil
+}
+
+// watchSpace checks the free space of the directory `dir` a copy is writing to
+// every `interval` until `ctx` is cancelled. If the copy is expected to write
+// `size` bytes in total and there is no longer room for the rest of it plus
+// `margin` bytes, `abort` is called with an error wrapping
+// ErrInsufficientSpace and the watch ends. This stops the copy before it fills
+// the disk instead of when it does.
+func watchSpace(ctx context.Context, dir string, size, margin int64, interval time.Duration, abort func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		free, err := preflight.FreeSpace(dir)
+		if err != nil {
+			slog.Warn("Failed to get free space.", "dir", dir, "error", err)
+			continue
+		}
+
+		if err := spaceNeeded(free, size-dirSize(dir), margin); err != nil {
+			abort(fmt.Errorf("%w: %s %s", ErrInsufficientSpace, dir, err))
+			return
+		}
+	}
+}
+
+// dirSize returns the total size of the files in the directory `dir`. Files
+// that can't be read are skipped since the size is only an estimate of the
+// progress of a copy.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
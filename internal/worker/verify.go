@@ -0,0 +1,330 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/mkv"
+)
+
+// minDurationTolerance is the smallest difference allowed between the
+// duration of an MKV file and the duration MakeMKV reported for its title. The
+// reported duration is rounded to the second.
+const minDurationTolerance = 2 * time.Second
+
+// durationTolerance is the percentage by which the duration of an MKV file may
+// differ from the duration of its title if that is more than
+// minDurationTolerance.
+const durationTolerance = 1
+
+// verifyCheckInterval is how often the output directory is checked for MKV
+// files that MakeMKV has finished writing while a copy is running.
+const verifyCheckInterval = 5 * time.Second
+
+// verifiedFile is an MKV file of a copy that passed verification.
+type verifiedFile struct {
+	// size is the size of the file in bytes.
+	size int64
+
+	// sha256 is the hex encoded SHA-256 digest of the file.
+	sha256 string
+}
+
+// rejectedFile is an MKV file of a copy that failed verification.
+type rejectedFile struct {
+	name string
+
+	// title is the index of the title the file was copied from or -1 if it
+	// couldn't be determined.
+	title int
+
+	err error
+}
+
+func (r rejectedFile) Error() string {
+	if r.title < 0 {
+		return fmt.Sprintf("%s failed verification: %s", r.name, r.err)
+	}
+	return fmt.Sprintf("title %d (%s) failed verification: %s", r.title, r.name, r.err)
+}
+
+// titleVerifier verifies the MKV files of a copy as MakeMKV finishes writing
+// them, instead of all of them once the copy ends, so that verifying a disc
+// with many titles doesn't add much to the time the copy takes. MakeMKV
+// writes one title at a time so every file except the one modified last is
+// complete. The files are verified in the background so that MakeMKV's output
+// is still read while a file is being hashed.
+type titleVerifier struct {
+	dir           string
+	sizeTolerance int
+
+	// disc is the information MakeMKV reported about the disc when it was
+	// scanned before the copy started. Files whose title can't be found in
+	// it are left until the copy ends.
+	disc makemkv.DiscInfo
+
+	lastCheck time.Time
+	queued    map[string]bool
+	queue     chan string
+	done      chan struct{}
+
+	mu       sync.Mutex
+	verified map[string]verifiedFile
+	rejected []rejectedFile
+}
+
+// newTitleVerifier starts verifying the MKV files written to the directory
+// `dir` against the disc information `disc`. The size of each file may differ
+// from its title's size by `sizeTolerance` percent.
+func newTitleVerifier(dir string, disc makemkv.DiscInfo, sizeTolerance int) *titleVerifier {
+	v := &titleVerifier{
+		dir:           dir,
+		sizeTolerance: sizeTolerance,
+		disc:          disc,
+		queued:        make(map[string]bool),
+		queue:         make(chan string, 64),
+		done:          make(chan struct{}),
+		verified:      make(map[string]verifiedFile),
+	}
+
+	go func() {
+		defer close(v.done)
+		for name := range v.queue {
+			v.verify(name, v.disc)
+		}
+	}()
+
+	return v
+}
+
+// check queues the MKV files MakeMKV has finished writing as of time `now`
+// for verification unless the output directory was checked less than
+// verifyCheckInterval ago.
+func (v *titleVerifier) check(now time.Time) {
+	if now.Sub(v.lastCheck) < verifyCheckInterval {
+		return
+	}
+	v.lastCheck = now
+
+	names, err := mkvFiles(v.dir)
+	if err != nil {
+		slog.Debug("Failed to list copied files.", "dir", v.dir, "error", err)
+		return
+	}
+
+	// The file being written is the one modified last.
+	var writing string
+	var latest time.Time
+	for _, name := range names {
+		if stat, err := os.Stat(filepath.Join(v.dir, name)); err == nil && !stat.ModTime().Before(latest) {
+			writing, latest = name, stat.ModTime()
+		}
+	}
+
+	for _, name := range names {
+		if name == writing || v.queued[name] || !hasTitle(v.disc, name) {
+			continue
+		}
+
+		select {
+		case v.queue <- name:
+			v.queued[name] = true
+		default:
+			// The file is queued on a later check instead of blocking.
+		}
+	}
+}
+
+// stop stops verifying files without verifying the ones that are left. It
+// waits for the file being verified, if any.
+func (v *titleVerifier) stop() {
+	close(v.queue)
+	<-v.done
+}
+
+// finish verifies the MKV files that weren't verified while the copy was
+// running against `disc`, the information MakeMKV reported while copying the
+// disc. Returns the files that passed by name and the files that didn't.
+func (v *titleVerifier) finish(disc makemkv.DiscInfo) (map[string]verifiedFile, []rejectedFile, error) {
+	v.stop()
+
+	names, err := mkvFiles(v.dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list copied files: %w", err)
+	}
+
+	for _, name := range names {
+		if !v.queued[name] {
+			v.verify(name, disc)
+		}
+	}
+
+	return v.verified, v.rejected, nil
+}
+
+// verify verifies the MKV file named `name` against the information about
+// its title in `disc` and records the result.
+func (v *titleVerifier) verify(name string, disc makemkv.DiscInfo) {
+	index := titleIndex(disc, name)
+
+	var vf verifiedFile
+	var err error
+	if index < 0 || index >= len(disc.Titles) {
+		err = errors.New("the title it was copied from couldn't be determined")
+	} else {
+		vf, err = verifyFile(filepath.Join(v.dir, name), &disc.Titles[index], v.sizeTolerance)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err != nil {
+		v.rejected = append(v.rejected, rejectedFile{name: name, title: index, err: err})
+	} else {
+		v.verified[name] = vf
+	}
+}
+
+// hasTitle returns true if the title the MKV file named `name` was copied
+// from is in `disc`.
+func hasTitle(disc makemkv.DiscInfo, name string) bool {
+	index := titleIndex(disc, name)
+	return index >= 0 && index < len(disc.Titles)
+}
+
+// mkvFiles returns the names of the MKV files in the directory `dir`.
+func mkvFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".mkv") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// verifyFile checks that the MKV file at `path` is a well-formed Matroska file
+// that matches `title`, the information MakeMKV reported about the title it
+// was copied from, and computes its digest. Only the structure is checked if
+// `title` is nil. The checks for which MakeMKV didn't report the information
+// needed are skipped.
+func verifyFile(path string, title *makemkv.TitleInfo, sizeTolerance int) (verifiedFile, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return verifiedFile{}, err
+	}
+
+	if title != nil {
+		expected, _ := strconv.ParseInt(title.Attributes[makemkv.AI_DISK_SIZE_BYTES], 10, 64)
+		if expected > 0 && abs(stat.Size()-expected) > expected*int64(sizeTolerance)/100 {
+			return verifiedFile{}, fmt.Errorf("size of %d bytes isn't within %d%% of the expected %d bytes", stat.Size(), sizeTolerance, expected)
+		}
+	}
+
+	info, err := mkv.Inspect(path)
+	if err != nil {
+		return verifiedFile{}, err
+	}
+
+	if title != nil {
+		// Streams can be left out by MakeMKV's selection rules so the file
+		// may have fewer tracks than the title has streams, but never more.
+		// The video and at least one audio stream are never left out.
+		if streams := len(title.Streams); streams > 0 {
+			if least := requiredTracks(title); info.TrackCount < least || info.TrackCount > streams {
+				return verifiedFile{}, fmt.Errorf("has %d tracks, expected between %d and %d", info.TrackCount, least, streams)
+			}
+		}
+
+		if expected := time.Duration(parseDuration(title.Attributes[makemkv.AI_DURATION])) * time.Second; expected > 0 {
+			tolerance := max(minDurationTolerance, expected*durationTolerance/100)
+			if info.Duration == 0 {
+				return verifiedFile{}, fmt.Errorf("doesn't specify its duration, expected %s", expected)
+			} else if abs(info.Duration-expected) > tolerance {
+				return verifiedFile{}, fmt.Errorf("duration of %s isn't within %s of the expected %s", info.Duration.Round(time.Second), tolerance, expected)
+			}
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return verifiedFile{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return verifiedFile{}, fmt.Errorf("failed to compute digest: %w", err)
+	}
+
+	return verifiedFile{size: size, sha256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// requiredTracks returns the number of tracks an MKV file copied from the
+// title `title` has at least: one for each video stream and one for audio if
+// the title has any audio streams.
+func requiredTracks(title *makemkv.TitleInfo) int {
+	video, audio := 0, 0
+	for _, stream := range title.Streams {
+		switch stream.Attributes[makemkv.AI_TYPE] {
+		case "Video":
+			video++
+		case "Audio":
+			audio = 1
+		}
+	}
+	return video + audio
+}
+
+// abs returns the absolute value of `v`.
+func abs[T int64 | time.Duration](v T) T {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
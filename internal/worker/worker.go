@@ -0,0 +1,625 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package worker provides the background workers that perform the long running
+// operations requested through the service's API.
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/blk"
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/store"
+	"github.com/kfisher/artie-copy-service/internal/transcript"
+)
+
+var (
+	ErrCopyInProgress    = errors.New("copy operation already in progress")
+	ErrNoCopyInProgress  = errors.New("no copy operation in progress")
+	ErrLicenseExpired    = errors.New("makemkv license or beta key has expired")
+	ErrDuplicateDisc     = errors.New("disc has already been copied")
+	ErrInsufficientSpace = errors.New("insufficient free space")
+	ErrDriveLost         = errors.New("drive lease was taken by another service")
+)
+
+// licenseWarningPeriod is how long before the MakeMKV license expires that a
+// warning will be logged.
+const licenseWarningPeriod = 7 * 24 * time.Hour
+
+// Worker copies the disc in an optical drive in the background. Only one copy
+// can be in progress at a time for each drive.
+type Worker struct {
+	repo   db.Repository
+	serial string
+
+	mu     sync.Mutex
+	cancel context.CancelCauseFunc
+	lost   bool
+
+	// replayed maps the provisional identifiers of copy operations created
+	// while the database was unreachable to the identifiers the database
+	// assigned once they were replayed. It has its own lock since it's
+	// updated while the repository's lock is held.
+	replayedMu sync.Mutex
+	replayed   map[int]int
+}
+
+// New creates a worker for the drive with serial number `serial` that records
+// its copy operations and the discs it copies using `repo`.
+func New(repo db.Repository, serial string) *Worker {
+	return &Worker{repo: repo, serial: serial}
+}
+
+// Serial returns the serial number of the worker's drive.
+func (w *Worker) Serial() string {
+	return w.serial
+}
+
+// StartCopy creates a new copy operation and starts copying the disc in the
+// drive in the background. ErrCopyInProgress is returned if a copy is already
+// in progress and ErrDriveLost if the drive's lease was taken by another
+// service.
+//
+// The disc is looked up in the disc catalog unless duplicate checking is
+// turned off. If it was already copied successfully, a warning is added to the
+// operation or, if configured to refuse duplicates, ErrDuplicateDisc is
+// returned unless `force` is set.
+//
+// An error wrapping ErrInsufficientSpace is returned if the staging or output
+// directory doesn't have room for the titles MakeMKV reports for the disc.
+func (w *Worker) StartCopy(ctx context.Context, force bool) (models.CopyOperation, error) {
+	// The worker is reserved, instead of locked, while the disc is scanned
+	// so that the status and cancelling a copy don't wait on MakeMKV.
+	copyCtx, err := w.reserve()
+	if err != nil {
+		return models.CopyOperation{}, err
+	}
+
+	started := false
+	defer func() {
+		if !started {
+			w.release()
+		}
+	}()
+
+	// The license is checked again when expired in case the key was updated
+	// since the last time MakeMKV was run.
+	if store.GetLicense().State == makemkv.LS_EXPIRED {
+		if err := w.ProbeMakeMkv(ctx); err != nil {
+			slog.Warn("Failed to probe MakeMKV.", "error", err)
+		}
+		if store.GetLicense().State == makemkv.LS_EXPIRED {
+			return models.CopyOperation{}, ErrLicenseExpired
+		}
+	}
+
+	// The whole copy uses the configuration as of when it started even if it
+	// is reloaded while the copy is running.
+	conf := cfg.Current()
+
+	od, _ := store.GetOpticalDrive(w.serial)
+
+	op := models.CopyOperation{
+		DriveId:   od.Id,
+		DiscLabel: od.DiscLabel,
+		State:     models.CopyStateRunning,
+		StartedAt: time.Now(),
+	}
+
+	// The operation doesn't exist yet so MakeMKV's output is kept until its
+	// transcript is created. Failing to get the disc information shouldn't
+	// prevent copying it so the checks that need it are skipped instead.
+	scan := &scanBuffer{}
+	runner := NewRunner(conf.MakeMkv)
+	runner.Transcript = scan
+	info, err := runner.Info(ctx, od.DeviceName, nil)
+	if err != nil {
+		slog.Warn("Failed to get disc information.", "error", err)
+	}
+
+	if size := info.Size(); size > 0 {
+		if err := checkSpace(conf.MakeMkv, size); errors.Is(err, ErrInsufficientSpace) {
+			return models.CopyOperation{}, err
+		} else if err != nil {
+			slog.Warn("Failed to check free space.", "error", err)
+		}
+	}
+
+	action := conf.Catalog.DuplicateAction
+	if err == nil && (action == cfg.DuplicateWarn || action == cfg.DuplicateRefuse) {
+		// Failing to identify the disc shouldn't prevent copying it.
+		disc, copiedBy, err := w.catalogDisc(ctx, info, od.DiscLabel)
+		if err != nil {
+			slog.Warn("Failed to check disc catalog.", "error", err)
+		}
+		op.DiscId = disc.Id
+
+		if copiedBy != 0 {
+			if action == cfg.DuplicateRefuse && !force {
+				return models.CopyOperation{}, fmt.Errorf("%w by copy operation %d", ErrDuplicateDisc, copiedBy)
+			}
+			slog.Warn("Disc has already been copied.", "disc", disc.Id, "operation", copiedBy)
+			op.Warnings = append(op.Warnings, fmt.Sprintf("disc was already copied by copy operation %d", copiedBy))
+		}
+	}
+
+	// The copy may have been cancelled, or the drive's lease lost, while the
+	// disc was scanned.
+	if copyCtx.Err() != nil {
+		return models.CopyOperation{}, fmt.Errorf("copy stopped before it started: %w", context.Cause(copyCtx))
+	}
+
+	if err := w.repo.CreateCopyOperation(ctx, &op); err != nil {
+		return op, fmt.Errorf("failed to create copy operation: %w", err)
+	}
+
+	op.OutputDir = filepath.Join(conf.MakeMkv.StagingDir, strconv.Itoa(op.Id))
+	if err := os.MkdirAll(op.OutputDir, 0755); err != nil {
+		w.fail(ctx, &op, fmt.Sprintf("failed to create output directory: %s", err))
+		return op, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if err := w.repo.UpdateCopyOperation(ctx, op); err != nil {
+		return op, fmt.Errorf("failed to update copy operation: %w", err)
+	}
+
+	started = true
+	store.SetState(w.serial, models.DriveStateCopying)
+
+	slog.Info("Starting copy operation.", "id", op.Id, "device", od.DeviceName, "output", op.OutputDir)
+	go w.runCopy(copyCtx, conf, op, od, info, scan.Bytes())
+
+	return op, nil
+}
+
+// reserve marks the worker as busy so that only one copy can be started at a
+// time. Returns the context of the copy which is cancelled by CancelCopy.
+// ErrCopyInProgress is returned if the worker is already busy and
+// ErrDriveLost if the drive's lease was taken by another service.
+func (w *Worker) reserve() (context.Context, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		return nil, ErrCopyInProgress
+	} else if w.lost {
+		return nil, ErrDriveLost
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	w.cancel = cancel
+	return ctx, nil
+}
+
+// release marks the worker as idle once its copy has ended or failed to
+// start.
+func (w *Worker) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.cancel(nil)
+	w.cancel = nil
+	store.SetState(w.serial, models.DriveStateIdle)
+}
+
+// ProbeMakeMkv runs MakeMKV to get its version and license information and
+// updates the store with the result.
+func (w *Worker) ProbeMakeMkv(ctx context.Context) error {
+	license, err := NewRunner(cfg.Current().MakeMkv).Probe(ctx)
+	if err != nil {
+		return err
+	}
+
+	updateLicense(license)
+	return nil
+}
+
+// Replayed records that the copy operation with provisional identifier
+// `provisional`, assigned while the database was unreachable, was added to the
+// database with identifier `id` so that a copy still running uses the new
+// identifier from then on.
+func (w *Worker) Replayed(provisional, id int) {
+	w.replayedMu.Lock()
+	defer w.replayedMu.Unlock()
+
+	if w.replayed == nil {
+		w.replayed = make(map[int]int)
+	}
+	w.replayed[provisional] = id
+}
+
+// operationId returns the identifier the database assigned to the copy
+// operation with identifier `id` if it was created with a provisional one.
+// Otherwise, `id` is returned.
+func (w *Worker) operationId(id int) int {
+	w.replayedMu.Lock()
+	defer w.replayedMu.Unlock()
+
+	if replayed, ok := w.replayed[id]; ok {
+		return replayed
+	}
+	return id
+}
+
+// CancelCopy cancels the copy operation in progress. ErrNoCopyInProgress is
+// returned if there isn't a copy in progress.
+func (w *Worker) CancelCopy() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel == nil {
+		return ErrNoCopyInProgress
+	}
+
+	w.cancel(nil)
+	return nil
+}
+
+// LeaseLost stops the worker from using its drive after the lease on it was
+// taken by another service. A copy in progress is stopped and fails, and
+// later copies are refused with ErrDriveLost.
+func (w *Worker) LeaseLost() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lost = true
+	if w.cancel != nil {
+		w.cancel(ErrDriveLost)
+	}
+
+	od, _ := store.GetOpticalDrive(w.serial)
+	od.Online = false
+	store.Set(od)
+}
+
+// Lost returns true if the drive's lease was taken by another service.
+func (w *Worker) Lost() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.lost
+}
+
+// Eject ejects the disc in the drive. ErrCopyInProgress is returned if a copy
+// is in progress and ErrDriveLost if the drive's lease was taken by another
+// service.
+func (w *Worker) Eject(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		return ErrCopyInProgress
+	} else if w.lost {
+		return ErrDriveLost
+	}
+
+	od, _ := store.GetOpticalDrive(w.serial)
+	if err := blk.Eject(od.DeviceName); err != nil {
+		return err
+	}
+
+	store.SetDiscLabel(w.serial, "")
+	return nil
+}
+
+// Rescan reloads the information about the disc in the drive and updates the
+// store. Returns the label of the disc which will be empty if there isn't a
+// disc in the drive.
+func (w *Worker) Rescan(ctx context.Context) (string, error) {
+	dev, err := blk.GetBlockDevice(w.serial)
+	if err != nil {
+		return "", err
+	}
+
+	store.SetDiscLabel(w.serial, dev.Label)
+	return dev.Label, nil
+}
+
+// runCopy runs the copy operation `op` for the disc in the drive `od` using
+// the configuration `conf`. `info` is the information MakeMKV reported when
+// the disc was scanned, which is used to stop the copy early if the free space
+// runs out and to verify titles as they are copied, and `scan` is MakeMKV's
+// output from the scan which starts the operation's transcript.
+func (w *Worker) runCopy(ctx context.Context, conf *cfg.Reloadable, op models.CopyOperation, od models.OpticalDrive, info makemkv.DiscInfo, scan []byte) {
+	device := od.DeviceName
+
+	defer w.release()
+
+	attempts := 1
+	if conf.MakeMkv.RetryOnStall {
+		attempts = 2
+	}
+
+	runner := NewRunner(conf.MakeMkv)
+
+	tw, err := transcript.Create(w.operationId(op.Id))
+	if err != nil {
+		slog.Error("Failed to create transcript.", "id", op.Id, "error", err)
+	} else {
+		if _, err := tw.Write(scan); err != nil {
+			slog.Warn("Failed to write disc scan to transcript.", "id", op.Id, "error", err)
+		}
+		runner.Transcript = tw
+		defer func() {
+			if err := tw.Close(); err != nil {
+				slog.Error("Failed to close transcript.", "id", op.Id, "error", err)
+			}
+		}()
+	}
+
+	if conf.MakeMkv.DriveSpeed > 0 {
+		if err := blk.SetSpeed(device, conf.MakeMkv.DriveSpeed); err != nil {
+			slog.Warn("Failed to set drive speed.", "id", op.Id, "error", err)
+			op.Warnings = append(op.Warnings, err.Error())
+		}
+	}
+
+	copyDisc := runner.Mkv
+	if conf.MakeMkv.Mode == cfg.ModeBackup {
+		copyDisc = runner.Backup
+	}
+
+	sampler := newProgressSampler(w.repo, op, time.Duration(conf.MakeMkv.ProgressSampleInterval)*time.Second)
+
+	// The copy is aborted with a cause, instead of cancelled, when the free
+	// space runs out or the drive's lease is lost so that it's reported as a
+	// failure.
+	copyCtx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	if interval := conf.MakeMkv.SpaceCheckInterval; interval > 0 {
+		go watchSpace(copyCtx, op.OutputDir, info.Size(), conf.MakeMkv.SpaceMargin, time.Duration(interval)*time.Second, abort)
+	}
+
+	var job *copyJob
+	var verifier *titleVerifier
+	for attempt := 1; attempt <= attempts; attempt++ {
+		job = newCopyJob(op, conf.MakeMkv.MaxReadErrors)
+		if conf.MakeMkv.Mode != cfg.ModeBackup {
+			verifier = newTitleVerifier(op.OutputDir, info, conf.MakeMkv.SizeTolerance)
+		}
+		err = copyDisc(copyCtx, device, op.OutputDir, func(msg any) {
+			if job.handleMessage(msg) {
+				w.saveCopyOperation(job.op)
+			}
+			if _, ok := msg.(makemkv.ProgressValueMessage); ok {
+				now := time.Now()
+				sampler.sample(now, job.progress)
+				if verifier != nil {
+					verifier.check(now)
+				}
+			}
+		})
+		if job.license.Version != "" {
+			updateLicense(job.license)
+		}
+
+		if !errors.Is(err, makemkv.ErrStalled) || copyCtx.Err() != nil || attempt == attempts {
+			break
+		}
+
+		slog.Warn("MakeMKV stalled. Retrying copy.", "id", op.Id, "error", err)
+		if verifier != nil {
+			verifier.stop()
+		}
+		op.Warnings = append(op.Warnings, fmt.Sprintf("%s, retried copy", err))
+		if err := resetOutputDir(op.OutputDir); err != nil {
+			slog.Error("Failed to reset output directory.", "id", op.Id, "error", err)
+		}
+	}
+
+	// The operation may have been replayed while it was running, in which
+	// case the files it writes need to name it by its new identifier.
+	op = job.op
+	op.Id = w.operationId(op.Id)
+	op.EndedAt = time.Now()
+	switch {
+	case errors.Is(context.Cause(copyCtx), ErrInsufficientSpace), errors.Is(context.Cause(copyCtx), ErrDriveLost):
+		op.State = models.CopyStateFailed
+		if op.FailureReason != "" {
+			op.Warnings = append(op.Warnings, op.FailureReason)
+		}
+		op.FailureReason = context.Cause(copyCtx).Error()
+	case ctx.Err() != nil:
+		op.State = models.CopyStateCancelled
+	case errors.Is(err, makemkv.ErrStalled):
+		// A stall is always reported as the reason since any earlier fatal
+		// error wouldn't have stopped MakeMKV on its own.
+		op.State = models.CopyStateFailed
+		if op.FailureReason != "" {
+			op.Warnings = append(op.Warnings, op.FailureReason)
+		}
+		op.FailureReason = err.Error()
+	case op.FailureReason != "":
+		op.State = models.CopyStateFailed
+	case err != nil:
+		op.State = models.CopyStateFailed
+		op.FailureReason = err.Error()
+	default:
+		op.State = models.CopyStateSucceeded
+	}
+
+	// The final sample is taken before the files are renamed so that it
+	// includes all of them.
+	sampler.finish(job.progress)
+
+	// Only complete copies are moved out of staging, and only the files of
+	// them that pass verification, so that programs watching the output
+	// directory never see partial or corrupt ones.
+	//
+	// A copy that fails to be handed off is kept in staging since the disc
+	// was copied successfully and only needs to be moved.
+	kept := false
+	if op.State != models.CopyStateSucceeded && verifier != nil {
+		verifier.stop()
+	}
+	if op.State == models.CopyStateSucceeded && conf.MakeMkv.Mode == cfg.ModeBackup {
+		if err := checkHandoffSpace(conf.MakeMkv, op.OutputDir); err != nil {
+			keepStaged(&op, err)
+			kept = true
+		} else if err := handoffBackup(conf, &op, job, od); err != nil {
+			slog.Error("Failed to move backup to the output directory.", "id", op.Id, "staging", op.OutputDir, "error", err)
+			op.State = models.CopyStateFailed
+			op.FailureReason = fmt.Sprintf("failed to move backup to the output directory, it was kept in %s: %s", op.OutputDir, err)
+			kept = true
+		}
+	} else if op.State == models.CopyStateSucceeded {
+		// A title that fails verification only fails the copy if it was the
+		// only one so that one bad title doesn't throw away the rest of the
+		// disc.
+		verified, rejected, err := verifier.finish(job.disc)
+		for i, r := range rejected {
+			slog.Error("Copied file failed verification.", "id", op.Id, "error", r)
+			if i == 0 && len(verified) == 0 {
+				op.FailureReason = r.Error()
+			} else {
+				op.Warnings = append(op.Warnings, r.Error())
+			}
+		}
+
+		if err != nil {
+			op.State = models.CopyStateFailed
+			op.FailureReason = err.Error()
+		} else if len(verified) == 0 && len(rejected) > 0 {
+			op.State = models.CopyStateFailed
+		} else if err := checkHandoffSpace(conf.MakeMkv, op.OutputDir); err != nil {
+			keepStaged(&op, err)
+			kept = true
+		} else if err := handoff(conf, &op, job, od, verified, rejected); err != nil {
+			slog.Error("Failed to move files to the output directory.", "id", op.Id, "staging", op.OutputDir, "error", err)
+			op.State = models.CopyStateFailed
+			op.FailureReason = fmt.Sprintf("failed to move files to the output directory, they were kept in %s: %s", op.OutputDir, err)
+			kept = true
+		}
+	}
+
+	// The files of copies that didn't make it to the output directory are
+	// removed to give the space back since a disc can be tens of gigabytes.
+	if op.State != models.CopyStateSucceeded && !kept {
+		if err := os.RemoveAll(op.OutputDir); err != nil {
+			slog.Error("Failed to remove partial copy.", "id", op.Id, "error", err)
+		}
+		op.OutputDir = ""
+	}
+
+	slog.Info("Copy operation ended.", "id", op.Id, "state", op.State, "reason", op.FailureReason)
+	w.saveCopyOperation(op)
+
+	if count, err := transcript.Purge(time.Now()); err != nil {
+		slog.Warn("Failed to purge transcripts.", "error", err)
+	} else if count > 0 {
+		slog.Info("Purged old transcripts.", "count", count)
+	}
+}
+
+// keepStaged fails the copy operation `op` whose files can't be moved out of
+// staging because of error `err`. The files are kept in staging since the
+// disc was copied successfully and only needs to be moved.
+func keepStaged(op *models.CopyOperation, err error) {
+	slog.Error("Copy can't be moved to the output directory.", "id", op.Id, "staging", op.OutputDir, "error", err)
+	op.State = models.CopyStateFailed
+	op.FailureReason = fmt.Sprintf("%s, the copy was kept in %s", err, op.OutputDir)
+}
+
+// resetOutputDir removes everything from the output directory `dir` so that a
+// copy can be retried.
+func resetOutputDir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+
+	return os.MkdirAll(dir, 0755)
+}
+
+// updateLicense updates the store with the MakeMKV version and license
+// information `license` and logs a warning if the license has expired or will
+// expire soon.
+func updateLicense(license makemkv.License) {
+	store.SetLicense(license)
+
+	switch {
+	case license.State == makemkv.LS_EXPIRED:
+		slog.Warn("MakeMKV license or beta key has expired. Copies will be refused until it is updated.", "version", license.Version)
+	case license.ExpiresWithin(licenseWarningPeriod):
+		slog.Warn("MakeMKV license or beta key will expire soon.", "version", license.Version, "expires", license.ExpiresAt)
+	default:
+		slog.Debug("MakeMKV license checked.", "version", license.Version, "state", license.State)
+	}
+}
+
+// fail marks the copy operation `op` as failed for `reason` and saves it.
+func (w *Worker) fail(ctx context.Context, op *models.CopyOperation, reason string) {
+	op.State = models.CopyStateFailed
+	op.FailureReason = reason
+	op.EndedAt = time.Now()
+	if err := w.repo.UpdateCopyOperation(ctx, *op); err != nil {
+		slog.Error("Failed to save copy operation.", "id", op.Id, "error", err)
+	}
+}
+
+func (w *Worker) saveCopyOperation(op models.CopyOperation) {
+	op.Id = w.operationId(op.Id)
+	if err := w.repo.UpdateCopyOperation(context.Background(), op); err != nil {
+		slog.Error("Failed to save copy operation.", "id", op.Id, "error", err)
+	}
+}
+
+// scanBuffer holds MakeMKV's output from scanning a disc until the transcript
+// of the copy operation is created. MakeMKV's output and errors are written
+// from different goroutines so writes are serialized.
+type scanBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *scanBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+// Bytes returns the output written so far.
+func (b *scanBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return bytes.Clone(b.buf.Bytes())
+}
@@ -0,0 +1,1187 @@
+// Copyright 2025 Kevin Fisher
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+// this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+// this list of conditions and the following disclaimer in the documentation
+// and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+// may be used to endorse or promote products derived from this software without
+// specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+//go:build !windows
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kfisher/artie-copy-service/internal/cfg"
+	"github.com/kfisher/artie-copy-service/internal/db"
+	"github.com/kfisher/artie-copy-service/internal/makemkv"
+	"github.com/kfisher/artie-copy-service/internal/manifest"
+	"github.com/kfisher/artie-copy-service/internal/models"
+	"github.com/kfisher/artie-copy-service/internal/preflight"
+	"github.com/kfisher/artie-copy-service/internal/store"
+	"github.com/kfisher/artie-copy-service/internal/transcript"
+)
+
+// fakeMakeMkv is a shell script that stands in for makemkvcon. It outputs the
+// messages in the file whose path is in FAKE_MAKEMKV_OUTPUT and, for the mkv
+// command, copies the MKV file whose path is in FAKE_MAKEMKV_MKV to the output
+// directory and then, if FAKE_MAKEMKV_SLEEP is set, waits that many seconds.
+// For the backup command, it writes a minimal disc structure.
+const fakeMakeMkv = `#!/bin/sh
+for arg; do last="$arg"; done
+cat "$FAKE_MAKEMKV_OUTPUT"
+for arg; do
+	if [ "$arg" = "mkv" ]; then
+		cp "$FAKE_MAKEMKV_MKV" "$last/title_t00.mkv"
+		if [ -n "$FAKE_MAKEMKV_SLEEP" ]; then
+			exec sleep "$FAKE_MAKEMKV_SLEEP"
+		fi
+	fi
+	if [ "$arg" = "backup" ]; then
+		mkdir -p "$last/BDMV" && echo index > "$last/BDMV/index.bdmv"
+	fi
+done
+`
+
+// fakeMkv is the MKV file the fake MakeMKV writes. It has one track and lasts
+// 43 minutes to match the disc information used by the tests.
+var fakeMkv = testMkv(1, 2580)
+
+// fakeTitle is the information the fake MakeMKV reports about the title of
+// fakeMkv so that the file passes verification.
+const fakeTitle = `TINFO:0,9,0,"0:43:00"
+TINFO:0,27,0,"title_t00.mkv"
+SINFO:0,0,1,6201,"Video"
+`
+
+// testMkv returns a minimal Matroska file with `tracks` tracks that lasts
+// `seconds` seconds.
+func testMkv(tracks int, seconds float64) []byte {
+	element := func(id []byte, data ...[]byte) []byte {
+		body := bytes.Join(data, nil)
+		size := binary.BigEndian.AppendUint64(nil, uint64(len(body)))
+		size[0] = 0x01
+		return append(append(id, size...), body...)
+	}
+
+	var entries [][]byte
+	for i := 0; i < tracks; i++ {
+		entries = append(entries, element([]byte{0xAE}, element([]byte{0xD7}, []byte{byte(i + 1)})))
+	}
+
+	duration := binary.BigEndian.AppendUint64(nil, math.Float64bits(seconds*1000))
+	return append(
+		element([]byte{0x1A, 0x45, 0xDF, 0xA3}, element([]byte{0x42, 0x82}, []byte("matroska"))),
+		element([]byte{0x18, 0x53, 0x80, 0x67},
+			element([]byte{0x15, 0x49, 0xA9, 0x66}, element([]byte{0x44, 0x89}, duration)),
+			element([]byte{0x16, 0x54, 0xAE, 0x6B}, entries...),
+			element([]byte{0x1F, 0x43, 0xB6, 0x75}, element([]byte{0xA3}, make([]byte, 64))))...)
+}
+
+// setupWorkerTest configures the service to use the fake MakeMKV which will
+// output `output` and returns a worker using an in-memory repository.
+func setupWorkerTest(t *testing.T, output string) (*Worker, db.Repository) {
+	dir := t.TempDir()
+
+	exe := filepath.Join(dir, "makemkvcon")
+	if err := os.WriteFile(exe, []byte(fakeMakeMkv), 0755); err != nil {
+		t.Fatal("Failed to write fake makemkvcon:", err)
+	}
+
+	outputPath := filepath.Join(dir, "output.txt")
+	if err := os.WriteFile(outputPath, []byte(output), 0644); err != nil {
+		t.Fatal("Failed to write fake makemkvcon output:", err)
+	}
+	t.Setenv("FAKE_MAKEMKV_OUTPUT", outputPath)
+
+	mkvPath := filepath.Join(dir, "fake.mkv")
+	if err := os.WriteFile(mkvPath, fakeMkv, 0644); err != nil {
+		t.Fatal("Failed to write fake MKV file:", err)
+	}
+	t.Setenv("FAKE_MAKEMKV_MKV", mkvPath)
+
+	outDir := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Join(outDir, ".staging"), 0755); err != nil {
+		t.Fatal("Failed to create output directory:", err)
+	}
+
+	cfg.Publish(cfg.Reloadable{MakeMkv: cfg.MakeMkvConfig{
+		OutDir:     outDir,
+		StagingDir: filepath.Join(outDir, ".staging"),
+		MakeMKV:    exe,
+	}})
+	cfg.Transcript = cfg.TranscriptConfig{Dir: filepath.Join(dir, "transcripts")}
+
+	repo := db.NewMemoryRepository()
+	od := models.OpticalDrive{
+		DeviceName:   "/dev/sr0",
+		SerialNumber: "4-8-15-16-23-42",
+		State:        models.DriveStateIdle,
+		DiscLabel:    "LOST_S1",
+	}
+	if err := repo.UpsertOpticalDrive(context.Background(), &od); err != nil {
+		t.Fatal("UpsertOpticalDrive returned an error:", err)
+	}
+
+	store.Set(od)
+
+	return New(repo, od.SerialNumber), repo
+}
+
+// waitForCopy waits for the copy operation with identifier `id` to end and
+// returns it.
+func waitForCopy(t *testing.T, repo db.Repository, id int) models.CopyOperation {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		op, err := repo.GetCopyOperation(context.Background(), id)
+		if err != nil {
+			t.Fatal("GetCopyOperation returned an error:", err)
+		}
+		if op.State != models.CopyStateRunning && store.GetState("4-8-15-16-23-42") == models.DriveStateIdle {
+			return op
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("Timed out waiting for the copy to end")
+	return models.CopyOperation{}
+}
+
+func TestCopySucceeded(t *testing.T) {
+	w, repo := setupWorkerTest(t, fakeTitle+`MSG:1005,0,1,"MakeMKV v1.17.7 linux(x64-release) started","%1 started","MakeMKV v1.17.7 linux(x64-release)"
+MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	op = waitForCopy(t, repo, op.Id)
+
+	if op.State != models.CopyStateSucceeded {
+		t.Errorf("State = %s, expected %s (reason: %s)", op.State, models.CopyStateSucceeded, op.FailureReason)
+	}
+
+	outDir := filepath.Join(cfg.Current().MakeMkv.OutDir, strconv.Itoa(op.Id))
+	if op.OutputDir != outDir {
+		t.Errorf("OutputDir = '%s', expected '%s'", op.OutputDir, outDir)
+	}
+
+	if _, err := os.Stat(filepath.Join(op.OutputDir, "title_t00.mkv")); err != nil {
+		t.Error("Expected MKV file in the output directory:", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(op.OutputDir, completeFileName(op.Id)))
+	if err != nil {
+		t.Fatal("Expected the complete file in the output directory:", err)
+	}
+
+	var complete completeManifest
+	if err := json.Unmarshal(data, &complete); err != nil {
+		t.Fatal("Failed to decode the complete file:", err)
+	}
+
+	if complete.OperationId != op.Id || len(complete.Files) != 1 || complete.Files[0] != "title_t00.mkv" {
+		t.Errorf("Complete file = %+v, expected operation %d with title_t00.mkv", complete, op.Id)
+	}
+
+	staging := filepath.Join(cfg.Current().MakeMkv.StagingDir, strconv.Itoa(op.Id))
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Errorf("Expected the staging directory %s to be removed", staging)
+	}
+
+	if store.GetLicense().Version != "1.17.7" {
+		t.Errorf("License version = %s, expected 1.17.7", store.GetLicense().Version)
+	}
+}
+
+func TestCopyFailed(t *testing.T) {
+	w, repo := setupWorkerTest(t, `MSG:5010,0,0,"Failed to open disc","Failed to open disc"
+`)
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	op = waitForCopy(t, repo, op.Id)
+
+	if op.State != models.CopyStateFailed {
+		t.Errorf("State = %s, expected %s", op.State, models.CopyStateFailed)
+	}
+
+	if op.FailureReason != "failed to open disc (5010): Failed to open disc" {
+		t.Errorf("FailureReason = %s", op.FailureReason)
+	}
+
+	if op.OutputDir != "" {
+		t.Errorf("OutputDir = '%s', expected none for a failed copy", op.OutputDir)
+	}
+
+	staging := filepath.Join(cfg.Current().MakeMkv.StagingDir, strconv.Itoa(op.Id))
+	if _, err := os.Stat(staging); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the staging directory %s to be removed", staging)
+	}
+}
+
+func TestCopyTranscriptIncludesScan(t *testing.T) {
+	w, repo := setupWorkerTest(t, fakeTitle+`MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+	op = waitForCopy(t, repo, op.Id)
+
+	data, _, err := transcript.Read(op.Id, 0)
+	if err != nil {
+		t.Fatal("Failed to read transcript:", err)
+	}
+
+	info := strings.Index(string(data), " info dev:/dev/sr0")
+	mkv := strings.Index(string(data), " mkv dev:/dev/sr0")
+	if info < 0 || mkv < info {
+		t.Errorf("Transcript = %q, expected the disc scan before the copy", data)
+	}
+}
+
+func TestCopyLeaseLost(t *testing.T) {
+	w, repo := setupWorkerTest(t, "")
+	t.Setenv("FAKE_MAKEMKV_SLEEP", "10")
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	w.LeaseLost()
+
+	op = waitForCopy(t, repo, op.Id)
+	if op.State != models.CopyStateFailed {
+		t.Errorf("State = %s, expected %s", op.State, models.CopyStateFailed)
+	}
+
+	if op.FailureReason != ErrDriveLost.Error() {
+		t.Errorf("FailureReason = %s, expected %s", op.FailureReason, ErrDriveLost)
+	}
+
+	if _, err := w.StartCopy(context.Background(), false); !errors.Is(err, ErrDriveLost) {
+		t.Errorf("StartCopy error = %v, expected %v", err, ErrDriveLost)
+	}
+
+	if od, _ := store.GetOpticalDrive(w.Serial()); od.Online {
+		t.Error("Drive still online after its lease was lost")
+	}
+}
+
+func TestCopyDuplicateDisc(t *testing.T) {
+	w, repo := setupWorkerTest(t, `CINFO:2,0,"Lost"
+CINFO:32,0,"LOST_S1_D1"
+TINFO:0,9,0,"0:43:00"
+TINFO:0,11,0,"`+strconv.Itoa(len(fakeMkv))+`"
+TINFO:0,26,0,"800"
+SINFO:0,0,1,6201,"Video"
+SINFO:0,0,6,0,"Mpeg4"
+MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	conf := *cfg.Current()
+	conf.Catalog = cfg.CatalogConfig{DuplicateAction: cfg.DuplicateRefuse}
+	cfg.Publish(conf)
+
+	first, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	first = waitForCopy(t, repo, first.Id)
+	if first.State != models.CopyStateSucceeded {
+		t.Fatalf("State = %s, expected %s (reason: %s)", first.State, models.CopyStateSucceeded, first.FailureReason)
+	}
+
+	disc, err := repo.GetDisc(context.Background(), first.DiscId)
+	if err != nil {
+		t.Fatal("GetDisc returned an error:", err)
+	}
+
+	if disc.VolumeName != "LOST_S1_D1" || len(disc.Titles) != 1 {
+		t.Errorf("Disc not added to the catalog: %+v", disc)
+	} else if title := disc.Titles[0]; title.Duration != 2580 || title.Size != int64(len(fakeMkv)) || len(title.Streams) != 1 {
+		t.Errorf("Title not added to the catalog: %+v", title)
+	}
+
+	if _, err := w.StartCopy(context.Background(), false); !errors.Is(err, ErrDuplicateDisc) {
+		t.Fatal("StartCopy did not return ErrDuplicateDisc:", err)
+	}
+
+	forced, err := w.StartCopy(context.Background(), true)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	forced = waitForCopy(t, repo, forced.Id)
+	if forced.DiscId != first.DiscId {
+		t.Errorf("DiscId = %d, expected %d", forced.DiscId, first.DiscId)
+	}
+
+	if len(forced.Warnings) != 1 {
+		t.Errorf("Warnings = %v, expected the duplicate warning", forced.Warnings)
+	}
+}
+
+func TestReplayed(t *testing.T) {
+	w := New(db.NewMemoryRepository(), "4-8-15-16-23-42")
+	w.Replayed(-1700000000, 42)
+
+	if id := w.operationId(-1700000000); id != 42 {
+		t.Errorf("operationId(-1700000000) = %d, expected 42", id)
+	}
+
+	if id := w.operationId(7); id != 7 {
+		t.Errorf("operationId(7) = %d, expected 7", id)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]int{
+		"0:43:00": 2580,
+		"1:02:03": 3723,
+		"":        0,
+		"43:00":   0,
+		"a:00:00": 0,
+	}
+
+	for s, expected := range tests {
+		if v := parseDuration(s); v != expected {
+			t.Errorf("parseDuration(%q) = %d, expected %d", s, v, expected)
+		}
+	}
+}
+
+func TestCopyProgressSamples(t *testing.T) {
+	w, repo := setupWorkerTest(t, fakeTitle+`PRGV:0,0,65536
+PRGV:0,32768,65536
+PRGV:0,65536,65536
+MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	op = waitForCopy(t, repo, op.Id)
+
+	// The sampling interval isn't configured so every progress message is
+	// sampled in addition to the final sample.
+	samples, err := repo.ListCopyProgressSamples(context.Background(), op.Id)
+	if err != nil {
+		t.Fatal("ListCopyProgressSamples returned an error:", err)
+	}
+
+	if len(samples) != 4 {
+		t.Fatalf("ListCopyProgressSamples returned %d samples, expected 4", len(samples))
+	}
+
+	if samples[1].Percent != 50 || samples[3].Percent != 100 {
+		t.Errorf("Samples = %+v", samples)
+	}
+
+	if last := samples[3]; last.Title != 0 || last.Bytes == 0 {
+		t.Errorf("Final sample = %+v, expected the bytes of the MKV file", last)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	samples := make([]models.CopyProgressSample, 1000)
+	for i := range samples {
+		samples[i].Percent = float64(i)
+	}
+
+	reduced := downsample(samples, 300)
+	if len(reduced) != 300 {
+		t.Fatalf("downsample returned %d samples, expected 300", len(reduced))
+	}
+
+	if reduced[0].Percent != 0 || reduced[299].Percent != 999 {
+		t.Errorf("downsample did not keep the first and last samples: %v, %v", reduced[0], reduced[299])
+	}
+
+	for i := 1; i < len(reduced); i++ {
+		if reduced[i].Percent <= reduced[i-1].Percent {
+			t.Errorf("downsample returned samples out of order at %d", i)
+			break
+		}
+	}
+
+	if len(downsample(samples[:10], 300)) != 10 {
+		t.Error("downsample changed samples that were under the limit")
+	}
+}
+
+func TestNewRunner(t *testing.T) {
+	dir := t.TempDir()
+
+	// The fake records the arguments and home directory it was run with.
+	exe := filepath.Join(dir, "makemkvcon")
+	script := `#!/bin/sh
+echo "$@" > "$HOME/args"
+echo 'MSG:1005,0,1,"MakeMKV v1.17.7 linux(x64-release) started","%1 started","MakeMKV v1.17.7 linux(x64-release)"'
+`
+	if err := os.WriteFile(exe, []byte(script), 0755); err != nil {
+		t.Fatal("Failed to write fake makemkvcon:", err)
+	}
+
+	// The license key of the user running the service is used when one
+	// isn't configured.
+	userHome := filepath.Join(dir, "user")
+	t.Setenv("HOME", userHome)
+	if err := makemkv.WriteSettings(userHome, map[string]string{"app_Key": "T-user"}); err != nil {
+		t.Fatal("WriteSettings returned an error:", err)
+	}
+
+	home := filepath.Join(dir, "home")
+	conf := cfg.MakeMkvConfig{
+		MakeMKV:   exe,
+		HomeDir:   home,
+		Mode:      cfg.ModeMkv,
+		MinLength: 300,
+		DirectIO:  "true",
+	}
+
+	if _, err := NewRunner(conf).Probe(context.Background()); err != nil {
+		t.Fatal("Probe returned an error:", err)
+	}
+
+	args, err := os.ReadFile(filepath.Join(home, "args"))
+	if err != nil {
+		t.Fatal("MakeMKV was not run with the private home directory:", err)
+	}
+	if !strings.Contains(string(args), "--minlength=300 --directio=true info") {
+		t.Errorf("args = '%s', expected the configured options", strings.TrimSpace(string(args)))
+	}
+
+	settings, err := makemkv.ReadSettings(makemkv.SettingsPath(home))
+	if err != nil {
+		t.Fatal("ReadSettings returned an error:", err)
+	}
+	if settings["app_Key"] != "T-user" || settings["dvd_MinimumTitleLength"] != "300" {
+		t.Errorf("settings = %v, expected the user's key and minimum title length", settings)
+	}
+
+	conf.LicenseKey = "T-configured"
+	if _, err := NewRunner(conf).Probe(context.Background()); err != nil {
+		t.Fatal("Probe returned an error:", err)
+	}
+
+	settings, err = makemkv.ReadSettings(makemkv.SettingsPath(home))
+	if err != nil {
+		t.Fatal("ReadSettings returned an error:", err)
+	}
+	if settings["app_Key"] != "T-configured" {
+		t.Errorf("app_Key = '%s', expected the configured key", settings["app_Key"])
+	}
+}
+
+func TestCopyNaming(t *testing.T) {
+	w, repo := setupWorkerTest(t, `CINFO:32,0,"LOST_S1_D1"
+TINFO:0,9,0,"0:43:00"
+TINFO:0,27,0,"title_t00.mkv"
+MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	conf := *cfg.Current()
+	conf.Naming = cfg.NamingConfig{
+		Directory: "{{.DiscLabel}}/{{.VolumeName}}",
+		File:      `{{.DiscLabel}} t{{printf "%02d" .Title}} {{.Duration}}`,
+	}
+	cfg.Publish(conf)
+
+	var dirs []string
+	for _, expected := range []string{"LOST_S1 t00 0_43_00.mkv", "LOST_S1 t00 0_43_00 (2).mkv"} {
+		op, err := w.StartCopy(context.Background(), false)
+		if err != nil {
+			t.Fatal("StartCopy returned an error:", err)
+		}
+
+		op = waitForCopy(t, repo, op.Id)
+		if op.State != models.CopyStateSucceeded {
+			t.Fatalf("State = %s, expected %s (reason: %s)", op.State, models.CopyStateSucceeded, op.FailureReason)
+		}
+
+		dir := filepath.Join(conf.MakeMkv.OutDir, "LOST_S1", "LOST_S1_D1")
+		if op.OutputDir != dir {
+			t.Errorf("OutputDir = '%s', expected '%s'", op.OutputDir, dir)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, expected)); err != nil {
+			t.Errorf("Expected %s in the output directory: %s", expected, err)
+		}
+
+		if len(op.Warnings) != 0 {
+			t.Errorf("Warnings = %q, expected none", op.Warnings)
+		}
+
+		dirs = append(dirs, filepath.Join(conf.MakeMkv.StagingDir, strconv.Itoa(op.Id)))
+	}
+
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("Expected the staging directory %s to be removed", dir)
+		}
+	}
+}
+
+func TestCopyBackup(t *testing.T) {
+	w, repo := setupWorkerTest(t, `CINFO:32,0,"LOST_S1_D1"
+MSG:5036,0,1,"Backup done.","Backup done."
+`)
+
+	conf := *cfg.Current()
+	conf.MakeMkv.Mode = cfg.ModeBackup
+	conf.Naming = cfg.NamingConfig{Directory: "{{.VolumeName}}"}
+	cfg.Publish(conf)
+
+	for _, name := range []string{"LOST_S1_D1", "LOST_S1_D1 (2)"} {
+		op, err := w.StartCopy(context.Background(), false)
+		if err != nil {
+			t.Fatal("StartCopy returned an error:", err)
+		}
+
+		op = waitForCopy(t, repo, op.Id)
+		if op.State != models.CopyStateSucceeded {
+			t.Fatalf("State = %s, expected %s (reason: %s)", op.State, models.CopyStateSucceeded, op.FailureReason)
+		}
+
+		dir := filepath.Join(conf.MakeMkv.OutDir, name)
+		if op.OutputDir != dir {
+			t.Errorf("OutputDir = '%s', expected '%s'", op.OutputDir, dir)
+		}
+
+		for _, file := range []string{"BDMV/index.bdmv", manifest.FileName(op.Id), completeFileName(op.Id)} {
+			if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+				t.Errorf("Expected %s in the output directory: %s", file, err)
+			}
+		}
+
+		if op.Manifest == nil {
+			t.Error("Expected the manifest to be stored with the copy operation")
+		}
+
+		staging := filepath.Join(conf.MakeMkv.StagingDir, strconv.Itoa(op.Id))
+		if _, err := os.Stat(staging); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected the staging directory %s to be removed", staging)
+		}
+	}
+}
+
+func TestPlaceFile(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "title_t00.mkv")
+	to := filepath.Join(dir, "Lost.mkv")
+
+	if err := os.WriteFile(to, []byte("other copy"), 0644); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+	if err := os.WriteFile(from, []byte("this copy"), 0644); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+
+	placed, err := placeFile(from, to)
+	if expected := filepath.Join(dir, "Lost (2).mkv"); err != nil || placed != expected {
+		t.Errorf("placeFile() = '%s', %v, expected '%s'", placed, err, expected)
+	}
+
+	if data, err := os.ReadFile(to); err != nil || string(data) != "other copy" {
+		t.Errorf("Expected the existing file to be kept, got %q (%v)", data, err)
+	}
+
+	if _, err := os.Stat(from); err != nil {
+		t.Error("Expected the file to be kept in its old location:", err)
+	}
+
+	// Filesystems without hard links, such as exFAT and many network shares,
+	// fail with an error other than EXDEV.
+	linkFile = func(string, string) error { return &os.LinkError{Op: "link", Err: syscall.EPERM} }
+	defer func() { linkFile = os.Link }()
+
+	placed, err = placeFile(from, to)
+	if expected := filepath.Join(dir, "Lost (3).mkv"); err != nil || placed != expected {
+		t.Errorf("placeFile() without links = '%s', %v, expected '%s'", placed, err, expected)
+	}
+
+	if data, err := os.ReadFile(placed); err != nil || string(data) != "this copy" {
+		t.Errorf("Expected the file to be copied, got %q (%v)", data, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".partial") {
+			t.Errorf("Expected the temporary copy %s to be removed", entry.Name())
+		}
+	}
+}
+
+func TestPlaceDir(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "staging")
+	to := filepath.Join(dir, "LOST_S1_D1")
+
+	if err := os.MkdirAll(filepath.Join(from, "BDMV"), 0755); err != nil {
+		t.Fatal("Failed to create directory:", err)
+	}
+	if err := os.Mkdir(to, 0755); err != nil {
+		t.Fatal("Failed to create directory:", err)
+	}
+
+	placed, err := placeDir(from, to)
+	if expected := filepath.Join(dir, "LOST_S1_D1 (2)"); err != nil || placed != expected {
+		t.Errorf("placeDir() = '%s', %v, expected '%s'", placed, err, expected)
+	}
+
+	if _, err := os.Stat(filepath.Join(placed, "BDMV")); err != nil {
+		t.Error("Expected the contents to be moved:", err)
+	}
+
+	// The contents are copied if the directory can't be renamed, such as
+	// when it is on another filesystem, and the copy is renamed into place.
+	renameDir = func(from, to string) error {
+		if !strings.HasSuffix(from, ".partial") {
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: syscall.EXDEV}
+		}
+		return syscall.Rename(from, to)
+	}
+	defer func() { renameDir = syscall.Rename }()
+
+	copied, err := placeDir(placed, to)
+	if expected := filepath.Join(dir, "LOST_S1_D1 (3)"); err != nil || copied != expected {
+		t.Errorf("placeDir() across filesystems = '%s', %v, expected '%s'", copied, err, expected)
+	}
+
+	for _, path := range []string{placed, copied} {
+		if _, err := os.Stat(filepath.Join(path, "BDMV")); err != nil {
+			t.Errorf("Expected the contents in %s: %v", path, err)
+		}
+	}
+
+	// A directory that can't be placed is left where it is.
+	if _, err := placeDir(placed, filepath.Join(dir, "missing", "LOST_S1_D1")); err == nil {
+		t.Error("placeDir() returned no error for a missing parent")
+	}
+
+	if _, err := os.Stat(filepath.Join(placed, "BDMV")); err != nil {
+		t.Error("Expected the contents to be kept after a failure:", err)
+	}
+}
+
+func TestCopyHandoffFailed(t *testing.T) {
+	w, repo := setupWorkerTest(t, fakeTitle+`MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	// The operation's output directory can't be created since a file is in
+	// the way.
+	if err := os.WriteFile(filepath.Join(cfg.Current().MakeMkv.OutDir, "1"), nil, 0644); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	op = waitForCopy(t, repo, op.Id)
+	if op.State != models.CopyStateFailed {
+		t.Errorf("State = %s, expected %s", op.State, models.CopyStateFailed)
+	}
+
+	staging := filepath.Join(cfg.Current().MakeMkv.StagingDir, strconv.Itoa(op.Id))
+	if op.OutputDir != staging {
+		t.Errorf("OutputDir = '%s', expected the staging directory '%s'", op.OutputDir, staging)
+	}
+
+	if _, err := os.Stat(filepath.Join(staging, "title_t00.mkv")); err != nil {
+		t.Error("Expected the copy to be kept in staging:", err)
+	}
+
+	if !strings.Contains(op.FailureReason, staging) {
+		t.Errorf("FailureReason = %s, expected it to say where the copy was kept", op.FailureReason)
+	}
+}
+
+func TestCopyHandoffRollsBack(t *testing.T) {
+	w, repo := setupWorkerTest(t, fakeTitle+`MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	// The manifest can't be written since a directory is in the way, which
+	// happens after the MKV file was put in the output directory.
+	outDir := filepath.Join(cfg.Current().MakeMkv.OutDir, "1")
+	if err := os.MkdirAll(filepath.Join(outDir, manifest.FileName(1)), 0755); err != nil {
+		t.Fatal("Failed to create directory:", err)
+	}
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	op = waitForCopy(t, repo, op.Id)
+	if op.State != models.CopyStateFailed {
+		t.Errorf("State = %s, expected %s", op.State, models.CopyStateFailed)
+	}
+
+	if _, err := os.Stat(filepath.Join(outDir, "title_t00.mkv")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected the MKV file to be removed from the output directory")
+	}
+
+	if _, err := os.Stat(filepath.Join(op.OutputDir, "title_t00.mkv")); err != nil {
+		t.Error("Expected the copy to be kept in staging:", err)
+	}
+}
+
+func TestTitleIndex(t *testing.T) {
+	var disc makemkv.DiscInfo
+	disc.AddTitleAttribute(0, makemkv.Attribute{Id: makemkv.AI_OUTPUT_FILE_NAME, Value: "Lost_t03.mkv"})
+
+	tests := []struct {
+		name     string
+		expected int
+	}{
+		{"Lost_t03.mkv", 0},
+		{"title_t07.mkv", 7},
+		{"extras.mkv", -1},
+	}
+
+	for _, test := range tests {
+		if actual := titleIndex(disc, test.name); actual != test.expected {
+			t.Errorf("titleIndex(%s) = %d, expected %d", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestCopyInsufficientSpace(t *testing.T) {
+	w, repo := setupWorkerTest(t, `TINFO:0,9,0,"0:43:00"
+TINFO:0,11,0,"7516192768"
+MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	conf := *cfg.Current()
+	conf.MakeMkv.SpaceMargin = 1 << 62
+	cfg.Publish(conf)
+
+	_, err := w.StartCopy(context.Background(), false)
+	if !errors.Is(err, ErrInsufficientSpace) {
+		t.Fatalf("StartCopy returned %v, expected %v", err, ErrInsufficientSpace)
+	}
+
+	if !strings.Contains(err.Error(), "7516192768 bytes are needed") {
+		t.Errorf("StartCopy returned %q, expected it to include the size of the disc", err)
+	}
+
+	od, _ := store.GetOpticalDrive("4-8-15-16-23-42")
+	if ops, _ := repo.ListCopyOperations(context.Background(), od.Id); len(ops) != 0 {
+		t.Errorf("Expected no copy operations to be created, got %d", len(ops))
+	}
+
+	if store.GetState("4-8-15-16-23-42") != models.DriveStateIdle {
+		t.Errorf("Drive state = %s, expected %s", store.GetState("4-8-15-16-23-42"), models.DriveStateIdle)
+	}
+}
+
+func TestSpaceNeeded(t *testing.T) {
+	tests := []struct {
+		free      uint64
+		remaining int64
+		margin    int64
+		ok        bool
+	}{
+		{100, 50, 50, true},
+		{100, 51, 50, false},
+		{100, -10, 100, true},
+		{100, 0, 101, false},
+	}
+
+	for _, test := range tests {
+		err := spaceNeeded(test.free, test.remaining, test.margin)
+		if (err == nil) != test.ok {
+			t.Errorf("spaceNeeded(%d, %d, %d) = %v, expected ok = %t", test.free, test.remaining, test.margin, err, test.ok)
+		}
+	}
+}
+
+func TestCheckHandoffSpace(t *testing.T) {
+	out := t.TempDir()
+	staging := filepath.Join(out, ".staging")
+	if err := os.Mkdir(staging, 0755); err != nil {
+		t.Fatal("Failed to create directory:", err)
+	}
+
+	// A copy on the same filesystem is renamed so it doesn't need room.
+	conf := cfg.MakeMkvConfig{OutDir: out, SpaceMargin: 1 << 62}
+	if err := checkHandoffSpace(conf, staging); err != nil {
+		t.Error("checkHandoffSpace returned an error for the same filesystem:", err)
+	}
+
+	other, err := os.MkdirTemp("/dev/shm", "artie-staging-*")
+	if err != nil {
+		t.Skip("No other filesystem to stage on:", err)
+	}
+	defer os.RemoveAll(other)
+
+	if same, err := preflight.SameFilesystem(other, out); err != nil || same {
+		t.Skip("No other filesystem to stage on")
+	}
+
+	if err := checkHandoffSpace(conf, other); !errors.Is(err, ErrInsufficientSpace) {
+		t.Errorf("checkHandoffSpace returned %v, expected %v", err, ErrInsufficientSpace)
+	}
+
+	conf.SpaceMargin = 0
+	if err := checkHandoffSpace(conf, other); err != nil {
+		t.Error("checkHandoffSpace returned an error without a margin:", err)
+	}
+}
+
+func TestWatchSpace(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "title_t00.mkv"), make([]byte, 1024), 0644); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+
+	if size := dirSize(dir); size != 1024 {
+		t.Errorf("dirSize(%s) = %d, expected 1024", dir, size)
+	}
+
+	aborted := make(chan error, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go watchSpace(ctx, dir, 1<<62, 0, 10*time.Millisecond, func(err error) { aborted <- err })
+
+	select {
+	case err := <-aborted:
+		if !errors.Is(err, ErrInsufficientSpace) {
+			t.Errorf("watchSpace aborted with %v, expected %v", err, ErrInsufficientSpace)
+		}
+	case <-ctx.Done():
+		t.Error("Timed out waiting for watchSpace to abort the copy")
+	}
+}
+
+func TestCopyManifest(t *testing.T) {
+	w, repo := setupWorkerTest(t, `CINFO:2,0,"Lost"
+CINFO:32,0,"LOST_S1_D1"
+TINFO:0,9,0,"0:43:00"
+TINFO:0,26,0,"800,801"
+TINFO:0,27,0,"title_t00.mkv"
+SINFO:0,0,1,6201,"Video"
+SINFO:0,0,6,0,"Mpeg4"
+SINFO:0,1,1,6202,"Audio"
+SINFO:0,1,3,0,"eng"
+SINFO:0,1,4,0,"English"
+SINFO:0,1,6,0,"DTS-HD MA"
+MSG:2003,0,3,"Error 'Scsi error - MEDIUM ERROR' occurred while reading '/BDMV/STREAM/00801.m2ts' at offset '1234'","Error '%1' occurred while reading '%2' at offset '%3'","Scsi error - MEDIUM ERROR","/BDMV/STREAM/00801.m2ts","1234"
+MSG:2003,0,3,"Error 'Scsi error - MEDIUM ERROR' occurred while reading '/BDMV/STREAM/00900.m2ts' at offset '1234'","Error '%1' occurred while reading '%2' at offset '%3'","Scsi error - MEDIUM ERROR","/BDMV/STREAM/00900.m2ts","1234"
+MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	// The title has a video and an audio stream so the file needs both.
+	mkv := testMkv(2, 2580)
+	mkvPath := filepath.Join(t.TempDir(), "fake.mkv")
+	if err := os.WriteFile(mkvPath, mkv, 0644); err != nil {
+		t.Fatal("Failed to write fake MKV file:", err)
+	}
+	t.Setenv("FAKE_MAKEMKV_MKV", mkvPath)
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	op = waitForCopy(t, repo, op.Id)
+	if op.State != models.CopyStateSucceeded {
+		t.Fatalf("State = %s, expected %s (reason: %s)", op.State, models.CopyStateSucceeded, op.FailureReason)
+	}
+
+	m, err := manifest.Read(filepath.Join(op.OutputDir, manifest.FileName(op.Id)))
+	if err != nil {
+		t.Fatal("Failed to read the manifest:", err)
+	}
+
+	if m.Version != manifest.Version || m.OperationId != op.Id {
+		t.Errorf("Manifest version = %d and operation = %d, expected %d and %d", m.Version, m.OperationId, manifest.Version, op.Id)
+	}
+
+	if m.Drive.Serial != "4-8-15-16-23-42" || m.Disc.Fingerprint == "" || m.Disc.Name != "Lost" {
+		t.Errorf("Manifest drive = %+v and disc = %+v, expected the test drive and an identified disc", m.Drive, m.Disc)
+	}
+
+	if m.ReadErrorCount != 2 {
+		t.Errorf("Manifest ReadErrorCount = %d, expected 2", m.ReadErrorCount)
+	}
+
+	if len(m.Files) != 1 {
+		t.Fatalf("Manifest has %d files, expected 1", len(m.Files))
+	}
+
+	if len(m.Rejected) != 0 {
+		t.Errorf("Manifest rejected = %+v, expected none", m.Rejected)
+	}
+
+	digest := sha256.Sum256(mkv)
+	expected := manifest.File{
+		Name:           "title_t00.mkv",
+		Title:          0,
+		Duration:       2580,
+		Size:           int64(len(mkv)),
+		SHA256:         hex.EncodeToString(digest[:]),
+		ReadErrorCount: 1,
+		Streams: []manifest.Stream{
+			{Index: 0, Type: "Video", Codec: "Mpeg4"},
+			{Index: 1, Type: "Audio", Codec: "DTS-HD MA", Language: "eng", LanguageName: "English"},
+		},
+	}
+	if !reflect.DeepEqual(m.Files[0], expected) {
+		t.Errorf("Manifest file = %+v, expected %+v", m.Files[0], expected)
+	}
+
+	stored, err := manifest.Parse(op.Manifest)
+	if err != nil {
+		t.Fatal("Failed to parse the stored manifest:", err)
+	}
+
+	if !reflect.DeepEqual(stored.Files, m.Files) {
+		t.Errorf("Stored manifest files = %+v, expected %+v", stored.Files, m.Files)
+	}
+
+	var complete completeManifest
+	if data, err := os.ReadFile(filepath.Join(op.OutputDir, completeFileName(op.Id))); err != nil {
+		t.Error("Expected the complete file in the output directory:", err)
+	} else if err := json.Unmarshal(data, &complete); err != nil || complete.Manifest != manifest.FileName(op.Id) {
+		t.Errorf("Complete file names manifest %q, expected %q (%v)", complete.Manifest, manifest.FileName(op.Id), err)
+	}
+}
+
+func TestTitleReadErrors(t *testing.T) {
+	sources := map[string]int{
+		"/BDMV/STREAM/00800.m2ts": 2,
+		"/BDMV/STREAM/00802.m2ts": 1,
+		"/BDMV/STREAM/00810.m2ts": 4,
+		"/VIDEO_TS/VTS_01_1.VOB":  8,
+	}
+
+	tests := []struct {
+		segments string
+		expected int
+	}{
+		{"800", 2},
+		{"800,802", 3},
+		{"800-810", 7},
+		{"1-7", 0},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		if count := titleReadErrors(test.segments, sources); count != test.expected {
+			t.Errorf("titleReadErrors(%q) = %d, expected %d", test.segments, count, test.expected)
+		}
+	}
+}
+
+func TestCopyVerificationFailed(t *testing.T) {
+	w, repo := setupWorkerTest(t, `TINFO:0,9,0,"0:43:00"
+TINFO:0,11,0,"7516192768"
+TINFO:0,27,0,"title_t00.mkv"
+MSG:5036,0,1,"Copy complete. 1 titles saved.","Copy complete. %1 titles saved.","1"
+`)
+
+	op, err := w.StartCopy(context.Background(), false)
+	if err != nil {
+		t.Fatal("StartCopy returned an error:", err)
+	}
+
+	op = waitForCopy(t, repo, op.Id)
+	if op.State != models.CopyStateFailed {
+		t.Errorf("State = %s, expected %s", op.State, models.CopyStateFailed)
+	}
+
+	if !strings.HasPrefix(op.FailureReason, "title 0 (title_t00.mkv) failed verification: size") {
+		t.Errorf("FailureReason = %s, expected the size of title 0 to fail verification", op.FailureReason)
+	}
+
+	staging := filepath.Join(cfg.Current().MakeMkv.StagingDir, strconv.Itoa(op.Id))
+	if _, err := os.Stat(staging); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the staging directory %s to be removed", staging)
+	}
+
+	if op.Manifest != nil {
+		t.Errorf("Manifest = %s, expected none for a failed copy", op.Manifest)
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal("Failed to write file:", err)
+		}
+		return path
+	}
+
+	title := func(size int, duration string, streams int) *makemkv.TitleInfo {
+		info := &makemkv.TitleInfo{}
+		info.AddAttribute(makemkv.Attribute{Id: makemkv.AI_DISK_SIZE_BYTES, Value: strconv.Itoa(size)})
+		info.AddAttribute(makemkv.Attribute{Id: makemkv.AI_DURATION, Value: duration})
+		for i := 0; i < streams; i++ {
+			info.AddStreamAttribute(i, makemkv.Attribute{Id: makemkv.AI_TYPE, Value: "Audio"})
+		}
+		return info
+	}
+
+	// withVideo makes the first stream of `info` a video stream.
+	withVideo := func(info *makemkv.TitleInfo) *makemkv.TitleInfo {
+		info.Streams[0].Attributes[makemkv.AI_TYPE] = "Video"
+		return info
+	}
+
+	valid := testMkv(2, 2580)
+	path := write("valid.mkv", valid)
+
+	v, err := verifyFile(path, title(len(valid), "0:43:00", 3), 10)
+	if err != nil {
+		t.Error("verifyFile returned an error for a valid file:", err)
+	}
+
+	digest := sha256.Sum256(valid)
+	if v.size != int64(len(valid)) || v.sha256 != hex.EncodeToString(digest[:]) {
+		t.Errorf("verifyFile returned %+v, expected the size and digest of the file", v)
+	}
+
+	if _, err := verifyFile(path, nil, 10); err != nil {
+		t.Error("verifyFile returned an error without title information:", err)
+	}
+
+	tests := []struct {
+		path     string
+		title    *makemkv.TitleInfo
+		expected string
+	}{
+		{write("truncated.mkv", valid[:len(valid)-16]), nil, "truncated"},
+		{write("garbage.mkv", []byte("not an mkv file")), nil, "missing EBML header"},
+		{path, title(len(valid)*2, "0:43:00", 3), "isn't within 10% of the expected"},
+		{path, title(len(valid), "0:43:00", 1), "has 2 tracks, expected between 1 and 1"},
+		{write("missing.mkv", testMkv(1, 2580)), withVideo(title(0, "0:43:00", 2)), "has 1 tracks, expected between 2 and 2"},
+		{path, title(len(valid), "0:45:00", 3), "duration of 43m0s isn't within"},
+	}
+
+	for _, test := range tests {
+		if _, err := verifyFile(test.path, test.title, 10); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("verifyFile(%s) = %v, expected an error containing %q", filepath.Base(test.path), err, test.expected)
+		}
+	}
+}
+
+func TestTitleVerifier(t *testing.T) {
+	dir := t.TempDir()
+
+	good, bad := testMkv(1, 2580), testMkv(1, 2700)
+	for name, data := range map[string][]byte{"title_t00.mkv": good, "title_t01.mkv": bad, "extra.mkv": good} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal("Failed to write file:", err)
+		}
+	}
+
+	var disc makemkv.DiscInfo
+	for i, name := range []string{"title_t00.mkv", "title_t01.mkv"} {
+		disc.AddTitleAttribute(i, makemkv.Attribute{Id: makemkv.AI_DURATION, Value: "0:43:00"})
+		disc.AddTitleAttribute(i, makemkv.Attribute{Id: makemkv.AI_OUTPUT_FILE_NAME, Value: name})
+		disc.AddStreamAttribute(0, i, makemkv.Attribute{Id: makemkv.AI_TYPE, Value: "Video"})
+	}
+
+	v := newTitleVerifier(dir, disc, 0)
+	v.check(time.Now())
+
+	verified, rejected, err := v.finish(disc)
+	if err != nil {
+		t.Fatal("finish returned an error:", err)
+	}
+
+	if _, ok := verified["title_t00.mkv"]; !ok || len(verified) != 1 {
+		t.Errorf("Verified = %v, expected only title_t00.mkv", verified)
+	}
+
+	reasons := make(map[string]string)
+	for _, r := range rejected {
+		reasons[r.name] = r.Error()
+	}
+
+	if !strings.Contains(reasons["title_t01.mkv"], "title 1 (title_t01.mkv) failed verification: duration") {
+		t.Errorf("Rejection of title_t01.mkv = %q, expected its duration to fail verification", reasons["title_t01.mkv"])
+	}
+
+	if !strings.Contains(reasons["extra.mkv"], "extra.mkv failed verification: the title it was copied from couldn't be determined") {
+		t.Errorf("Rejection of extra.mkv = %q, expected its title to be unknown", reasons["extra.mkv"])
+	}
+}
+
+func TestRequiredTracks(t *testing.T) {
+	tests := []struct {
+		types    []string
+		expected int
+	}{
+		{nil, 0},
+		{[]string{"Video"}, 1},
+		{[]string{"Video", "Audio", "Audio", "Subtitles"}, 2},
+		{[]string{"Video", "Video", "Audio"}, 3},
+	}
+
+	for _, test := range tests {
+		var title makemkv.TitleInfo
+		for i, typ := range test.types {
+			title.AddStreamAttribute(i, makemkv.Attribute{Id: makemkv.AI_TYPE, Value: typ})
+		}
+
+		if n := requiredTracks(&title); n != test.expected {
+			t.Errorf("requiredTracks(%v) = %d, expected %d", test.types, n, test.expected)
+		}
+	}
+}